@@ -0,0 +1,322 @@
+// Command wxpay 微信支付运维工具
+//
+//	wxpay replay -dir ./deadletters -url http://127.0.0.1:8080
+//	wxpay simulate -url http://127.0.0.1:8080/notify/paid -key <支付密钥> -out-trade-no 1001 -total-fee 100
+//
+// replay 把文件死信存储中的通知重新投递到业务系统的通知地址,
+// 由业务系统完成签名校验及回调处理, 投递成功的死信会被删除。
+//
+// simulate 生成签名正确的模拟支付或退款通知并投递到本地通知地址, 用于上线前联调。
+// 指定 -private-key 时生成 APIv3 通知, 签名使用该私钥, 业务系统需信任对应的模拟平台证书。
+//
+//	wxpay resync -appid <APPID> -mchid <商户号> -key <支付密钥> -from 2024-01-01 -to 2024-01-07 -url http://127.0.0.1:8080/notify/paid
+//
+// resync 下载指定日期范围的成功支付账单, 查询 -known 文件中没有的微信订单号,
+// 并以签名正确的支付通知投递到业务系统, 用于找回通知丢失的订单。
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/wanghuobo/weapp/payment"
+	"github.com/wanghuobo/weapp/payment/v3"
+	"github.com/wanghuobo/weapp/util"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "replay":
+		err = replay(os.Args[2:])
+	case "simulate":
+		err = simulate(os.Args[2:])
+	case "resync":
+		err = resync(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: wxpay replay -dir <dead letter dir> -url <notify base url>")
+	fmt.Fprintln(os.Stderr, "       wxpay simulate -url <notify url> -key <key> [-type paid|refund] [-private-key <file>]")
+	fmt.Fprintln(os.Stderr, "       wxpay resync -appid <appid> -mchid <mchid> -key <key> -from <date> [-to <date>] -url <notify url> [-known <file>]")
+}
+
+func replay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	dir := fs.String("dir", "", "文件死信存储目录")
+	target := fs.String("url", "", "业务系统地址, 与死信中的通知路径拼接")
+	timeout := fs.Duration("timeout", 10*time.Second, "单次投递超时时间")
+	fs.Parse(args)
+
+	if *dir == "" || *target == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	store, err := payment.NewFileDeadLetterSink(*dir)
+	if err != nil {
+		return err
+	}
+
+	h := &forwarder{
+		base:   strings.TrimSuffix(*target, "/"),
+		client: &http.Client{Timeout: *timeout},
+	}
+
+	res, err := payment.ReplayDeadLetters(store, h)
+	for id, e := range res.Failed {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", id, e)
+	}
+	fmt.Printf("succeeded: %d, failed: %d\n", res.Succeeded, len(res.Failed))
+
+	return err
+}
+
+// 把请求转发到业务系统, 并原样返回应答
+type forwarder struct {
+	base   string
+	client *http.Client
+}
+
+func (f *forwarder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	res, err := f.client.Post(f.base+req.URL.Path, req.Header.Get("Content-Type"), req.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		io.WriteString(w, err.Error())
+		return
+	}
+	defer res.Body.Close()
+
+	w.WriteHeader(res.StatusCode)
+	io.Copy(w, res.Body)
+}
+
+func simulate(args []string) error {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	target := fs.String("url", "", "业务系统通知地址")
+	typ := fs.String("type", "paid", "通知类型: paid | refund")
+	key := fs.String("key", "", "微信支付密钥, APIv3 通知时为 APIv3 密钥")
+	signType := fs.String("sign-type", payment.SignTypeMD5, "签名类型: MD5 | HMAC-SHA256")
+	appID := fs.String("appid", "", "APPID")
+	mchID := fs.String("mchid", "", "商户号")
+	outTradeNo := fs.String("out-trade-no", "", "商户订单号")
+	outRefundNo := fs.String("out-refund-no", "", "商户退款单号")
+	totalFee := fs.Int("total-fee", 1, "订单金额(分)")
+	refundFee := fs.Int("refund-fee", 0, "退款金额(分), 默认全额")
+	keyFile := fs.String("private-key", "", "模拟平台证书私钥文件, 指定时生成 APIv3 通知")
+	serial := fs.String("serial", "", "模拟平台证书序列号")
+	timeout := fs.Duration("timeout", 10*time.Second, "投递超时时间")
+	fs.Parse(args)
+
+	if *target == "" || *key == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if *refundFee == 0 {
+		*refundFee = *totalFee
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	now := time.Now()
+	transactionID := "sim" + now.Format("20060102150405")
+
+	if *keyFile != "" {
+		data, err := ioutil.ReadFile(*keyFile)
+		if err != nil {
+			return err
+		}
+		pk, err := util.ParsePrivateKey(data)
+		if err != nil {
+			return err
+		}
+
+		s := v3.Simulator{APIKey: *key, SerialNo: *serial, PrivateKey: pk}
+		switch *typ {
+		case "paid":
+			err = s.Send(ctx, *target, "TRANSACTION.SUCCESS", "transaction", map[string]interface{}{
+				"appid":          *appID,
+				"mchid":          *mchID,
+				"out_trade_no":   *outTradeNo,
+				"transaction_id": transactionID,
+				"trade_state":    "SUCCESS",
+				"success_time":   now.Format(time.RFC3339),
+				"amount":         map[string]interface{}{"total": *totalFee, "payer_total": *totalFee},
+			})
+		case "refund":
+			err = s.Send(ctx, *target, "REFUND.SUCCESS", "refund", map[string]interface{}{
+				"mchid":          *mchID,
+				"out_trade_no":   *outTradeNo,
+				"transaction_id": transactionID,
+				"out_refund_no":  *outRefundNo,
+				"refund_status":  "SUCCESS",
+				"success_time":   now.Format(time.RFC3339),
+				"amount":         map[string]interface{}{"total": *totalFee, "refund": *refundFee},
+			})
+		default:
+			return fmt.Errorf("未知的通知类型: %s", *typ)
+		}
+		if err == nil {
+			fmt.Println("ok")
+		}
+		return err
+	}
+
+	s := payment.Simulator{Key: *key, SignType: *signType}
+	var err error
+	switch *typ {
+	case "paid":
+		err = s.SendPaidNotify(ctx, *target, payment.PaidNotify{
+			AppID:         *appID,
+			MchID:         *mchID,
+			TotalFee:      *totalFee,
+			CashFee:       float64(*totalFee),
+			TradeType:     payment.TradeTypeJSAPI,
+			TransactionID: transactionID,
+			OutTradeNo:    *outTradeNo,
+			Timeend:       now.Format("20060102150405"),
+		})
+	case "refund":
+		err = s.SendRefundedNotify(ctx, *target, payment.RefundedNotify{
+			AppID:         *appID,
+			MchID:         *mchID,
+			TransactionID: transactionID,
+			OutTradeNo:    *outTradeNo,
+			RefundID:      "sim" + now.Format("20060102150405"),
+			OutRefundNo:   *outRefundNo,
+			TotalFee:      float64(*totalFee),
+			RefundFee:     float64(*refundFee),
+			RefundStatus:  "SUCCESS",
+			SuccessTime:   now.Format("2006-01-02 15:04:05"),
+		})
+	default:
+		return fmt.Errorf("未知的通知类型: %s", *typ)
+	}
+	if err == nil {
+		fmt.Println("ok")
+	}
+
+	return err
+}
+
+func resync(args []string) error {
+	fs := flag.NewFlagSet("resync", flag.ExitOnError)
+	target := fs.String("url", "", "业务系统支付通知地址")
+	appID := fs.String("appid", "", "APPID")
+	mchID := fs.String("mchid", "", "商户号")
+	key := fs.String("key", "", "微信支付密钥")
+	from := fs.String("from", "", "开始日期, 格式 2006-01-02")
+	to := fs.String("to", "", "结束日期(包含), 默认与开始日期相同")
+	knownFile := fs.String("known", "", "业务系统已记录的微信订单号文件, 每行一个")
+	dryRun := fs.Bool("dry-run", false, "只列出遗漏的订单, 不投递通知")
+	timeout := fs.Duration("timeout", 10*time.Second, "单次请求超时时间")
+	fs.Parse(args)
+
+	if *from == "" || (*target == "" && !*dryRun) {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *to == "" {
+		*to = *from
+	}
+
+	start, err := time.ParseInLocation("2006-01-02", *from, time.Local)
+	if err != nil {
+		return err
+	}
+	end, err := time.ParseInLocation("2006-01-02", *to, time.Local)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool)
+	if *knownFile != "" {
+		if known, err = readLines(*knownFile); err != nil {
+			return err
+		}
+	}
+
+	client, err := payment.NewClient(payment.Config{
+		AppID:   *appID,
+		MchID:   *mchID,
+		Key:     *key,
+		Timeout: *timeout,
+	})
+	if err != nil {
+		return err
+	}
+	defer client.Close(context.Background())
+
+	s := payment.Simulator{Key: *key}
+	b := &payment.Backfill{
+		Client: client,
+		Known: func(_, transactionID string) (bool, error) {
+			return known[transactionID], nil
+		},
+		Handler: func(ntf payment.PaidNotify) (bool, string) {
+			fmt.Println(ntf.TransactionID, ntf.OutTradeNo, ntf.TotalFee)
+			if *dryRun {
+				return true, ""
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+			defer cancel()
+			if err := s.SendPaidNotify(ctx, *target, ntf); err != nil {
+				return false, err.Error()
+			}
+			return true, ""
+		},
+	}
+
+	report, err := b.Resync(context.Background(), start, end)
+	for id, e := range report.Failed {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", id, e)
+	}
+	fmt.Printf("days: %d, rows: %d, known: %d, discovered: %d, failed: %d\n",
+		report.Days, report.Rows, report.Known, report.Discovered, len(report.Failed))
+
+	return err
+}
+
+// 读取文件中的非空行
+func readLines(name string) (map[string]bool, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := make(map[string]bool)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			lines[line] = true
+		}
+	}
+
+	return lines, sc.Err()
+}
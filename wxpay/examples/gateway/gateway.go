@@ -0,0 +1,174 @@
+//go:build example
+// +build example
+
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wanghuobo/weapp/payment"
+)
+
+// 支付网关
+//
+//	POST /orders              下单并返回前端支付参数
+//	GET  /orders/{outTradeNo} 返回本地订单状态及微信支付查询结果
+//	POST /notify/paid         支付结果通知
+type gateway struct {
+	client *payment.Client
+	store  *payment.MemoryOrderStore
+	guard  *payment.NotifyGuard
+
+	mu        sync.RWMutex
+	notifyURL string
+}
+
+type orderState struct {
+	Local      string              `json:"local"`
+	Remote     payment.QueryResult `json:"remote"`
+	Mismatches []payment.Mismatch  `json:"mismatches,omitempty"`
+}
+
+func newGateway(base string, sink payment.DeadLetterSink) (*gateway, error) {
+	store := payment.NewMemoryOrderStore()
+	client, err := payment.NewClient(payment.Config{
+		AppID:      appID,
+		MchID:      mchID,
+		Key:        key,
+		Profile:    payment.ProfileMock,
+		BaseURL:    base,
+		OrderStore: store,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &gateway{
+		client:    client,
+		store:     store,
+		guard:     payment.NewNotifyGuard(sink),
+		notifyURL: "http://127.0.0.1:8080/notify/paid",
+	}, nil
+}
+
+func (g *gateway) setNotifyURL(u string) {
+	g.mu.Lock()
+	g.notifyURL = u
+	g.mu.Unlock()
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case req.Method == http.MethodPost && req.URL.Path == "/orders":
+		g.createOrder(w, req)
+	case req.Method == http.MethodGet && strings.HasPrefix(req.URL.Path, "/orders/"):
+		g.getOrder(w, req, strings.TrimPrefix(req.URL.Path, "/orders/"))
+	case req.Method == http.MethodPost && req.URL.Path == "/notify/paid":
+		// 失败时已经应答 FAIL, 这里只记录日志
+		if err := g.guard.HandlePaidNotify(w, req, g.client.Config().Key, g.onPaid); err != nil {
+			log.Printf("支付通知处理失败: %v", err)
+		}
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (g *gateway) createOrder(w http.ResponseWriter, req *http.Request) {
+	var in struct {
+		OutTradeNo string `json:"out_trade_no"`
+		TotalFee   int    `json:"total_fee"`
+		OpenID     string `json:"openid"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&in); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	g.mu.RLock()
+	notifyURL := g.notifyURL
+	g.mu.RUnlock()
+
+	ctx, cancel := timeout()
+	defer cancel()
+
+	res, err := g.client.Unify(ctx, payment.Order{
+		OutTradeNo: in.OutTradeNo,
+		TotalFee:   in.TotalFee,
+		OpenID:     in.OpenID,
+		Body:       "示例商品",
+		IP:         "127.0.0.1",
+		NotifyURL:  notifyURL,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	params, err := payment.GetParams(appID, key, res.NonceStr, res.PrePayID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, params)
+}
+
+func (g *gateway) getOrder(w http.ResponseWriter, req *http.Request, outTradeNo string) {
+	r, err := g.store.GetOrder(outTradeNo)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if r == nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	ctx, cancel := timeout()
+	defer cancel()
+
+	remote, err := g.client.QueryOrder(ctx, outTradeNo)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	writeJSON(w, orderState{
+		Local:      r.TradeState,
+		Remote:     remote,
+		Mismatches: payment.Diff(r.Order, remote),
+	})
+}
+
+// 支付成功后更新本地订单状态
+func (g *gateway) onPaid(ntf payment.PaidNotify) (bool, string) {
+	r, err := g.store.GetOrder(ntf.OutTradeNo)
+	if err != nil {
+		return false, err.Error()
+	}
+	if r == nil {
+		return false, "订单不存在"
+	}
+
+	if ntf.TotalFee != r.Order.TotalFee {
+		return false, "金额不一致"
+	}
+
+	r.TradeState = payment.TradeStateSuccess
+	r.UpdatedAt = time.Now()
+	if err = g.store.SaveOrder(*r); err != nil {
+		return false, err.Error()
+	}
+
+	return true, ""
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
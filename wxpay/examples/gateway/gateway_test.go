@@ -0,0 +1,151 @@
+//go:build example
+// +build example
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wanghuobo/weapp/payment"
+)
+
+// 在进程内启动模拟服务和网关
+func startGateway(t *testing.T) (gw *gateway, url string, mock *mockServer, sink *payment.FileDeadLetterSink) {
+	mock = newMockServer(key)
+	api := httptest.NewServer(mock)
+	t.Cleanup(api.Close)
+
+	dir, err := ioutil.TempDir("", "gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	if sink, err = payment.NewFileDeadLetterSink(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if gw, err = newGateway(api.URL, sink); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(gw)
+	t.Cleanup(srv.Close)
+	gw.setNotifyURL(srv.URL + "/notify/paid")
+
+	return gw, srv.URL, mock, sink
+}
+
+// 下单并返回商户订单号
+func createOrder(t *testing.T, url string) string {
+	outTradeNo := "example" + time.Now().Format("20060102150405.000000")
+	outTradeNo = strings.Replace(outTradeNo, ".", "", 1)
+
+	body := strings.NewReader(`{"out_trade_no":"` + outTradeNo + `","total_fee":100,"openid":"oExample0000000000000000000"}`)
+	res, err := http.Post(url+"/orders", "application/json", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var params payment.Params
+	if err = readJSON(res, &params); err != nil {
+		t.Fatalf("下单失败: %v", err)
+	}
+	if !strings.HasPrefix(params.Package, "prepay_id=") {
+		t.Fatalf("支付参数错误: %+v", params)
+	}
+
+	return outTradeNo
+}
+
+func getOrder(t *testing.T, url, outTradeNo string) orderState {
+	res, err := http.Get(url + "/orders/" + outTradeNo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var state orderState
+	if err = readJSON(res, &state); err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+
+	return state
+}
+
+// 下单 -> 模拟用户支付并发送通知 -> 查询订单
+func TestGatewayPaid(t *testing.T) {
+	_, url, mock, _ := startGateway(t)
+
+	outTradeNo := createOrder(t, url)
+	if err := mock.pay(outTradeNo); err != nil {
+		t.Fatalf("支付通知失败: %v", err)
+	}
+
+	state := getOrder(t, url, outTradeNo)
+	if state.Local != payment.TradeStateSuccess || !state.Remote.Paid() {
+		t.Fatalf("订单状态错误: local=%s remote=%s", state.Local, state.Remote.TradeState)
+	}
+	if len(state.Mismatches) > 0 {
+		t.Fatalf("订单不一致: %+v", state.Mismatches)
+	}
+}
+
+// 签名错误的通知不会更新订单, 并写入死信
+func TestGatewayForgedNotify(t *testing.T) {
+	gw, url, _, sink := startGateway(t)
+
+	outTradeNo := createOrder(t, url)
+	body, err := payment.Simulator{Key: "forged"}.PaidNotifyBody(payment.PaidNotify{
+		AppID:         appID,
+		MchID:         mchID,
+		TotalFee:      100,
+		CashFee:       100,
+		TransactionID: "4200000000000000000000000000",
+		OutTradeNo:    outTradeNo,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := http.Post(url+"/notify/paid", "application/xml", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if state := getOrder(t, url, outTradeNo); state.Local != payment.TradeStateNotPay {
+		t.Fatalf("伪造的通知更新了订单: local=%s", state.Local)
+	}
+
+	letters, err := sink.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 1 || gw.guard.Stats().Failed != 1 {
+		t.Fatalf("死信数 = %d, 失败数 = %d, 都应为1", len(letters), gw.guard.Stats().Failed)
+	}
+}
+
+func readJSON(res *http.Response, v interface{}) error {
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d %s", res.StatusCode, data)
+	}
+
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,66 @@
+//go:build example
+// +build example
+
+// Command gateway 支付网关示例
+//
+// 把支付客户端、通知处理、订单存储和模拟微信支付服务组装在一起,
+// 演示完整的下单、支付通知、订单查询流程:
+//
+//	go run -tags example ./examples/gateway
+//
+// 接口地址由 -base 指定, 为空时启动进程内模拟服务。
+// 整个支付流程的集成测试见 gateway_test.go:
+//
+//	go test -tags example ./examples/...
+//
+// 也可以使用同目录下的 docker-compose.yml 在容器中运行测试和网关。
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	"github.com/wanghuobo/weapp/payment"
+)
+
+const (
+	appID = "wx0000000000000000"
+	mchID = "1000000000"
+	key   = "00000000000000000000000000000000"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "网关监听地址")
+	base := flag.String("base", "", "微信支付接口地址, 为空时启动进程内模拟服务")
+	dir := flag.String("deadletters", os.TempDir(), "通知死信目录")
+	flag.Parse()
+
+	if *base == "" {
+		srv := httptest.NewServer(newMockServer(key))
+		defer srv.Close()
+		*base = srv.URL
+	}
+
+	sink, err := payment.NewFileDeadLetterSink(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	gw, err := newGateway(*base, sink)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("gateway listening on %s, payment api %s", *addr, *base)
+	log.Fatal(http.ListenAndServe(*addr, gw))
+}
+
+// 调用微信支付及发送通知的超时时间
+func timeout() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 5*time.Second)
+}
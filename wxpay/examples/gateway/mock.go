@@ -0,0 +1,219 @@
+//go:build example
+// +build example
+
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/wanghuobo/weapp/payment"
+	"github.com/wanghuobo/weapp/util"
+)
+
+// 模拟微信支付服务
+// 支持统一下单和订单查询, 通过 pay 模拟用户完成支付并发送支付结果通知
+type mockServer struct {
+	key string
+
+	mu     sync.Mutex
+	orders map[string]*mockOrder
+}
+
+type mockOrder struct {
+	params        map[string]string
+	state         string
+	transactionID string
+	timeEnd       string
+}
+
+func newMockServer(key string) *mockServer {
+	return &mockServer{key: key, orders: make(map[string]*mockOrder)}
+}
+
+func (m *mockServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	params, err := readXML(req.Body)
+	if err != nil {
+		m.reply(w, map[string]string{"return_code": "FAIL", "return_msg": err.Error()})
+		return
+	}
+
+	if err = m.verify(params); err != nil {
+		m.reply(w, map[string]string{"return_code": "FAIL", "return_msg": err.Error()})
+		return
+	}
+
+	switch req.URL.Path {
+	case "/pay/unifiedorder":
+		m.unify(w, params)
+	case "/pay/orderquery":
+		m.query(w, params)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (m *mockServer) unify(w http.ResponseWriter, params map[string]string) {
+	m.mu.Lock()
+	m.orders[params["out_trade_no"]] = &mockOrder{params: params, state: payment.TradeStateNotPay}
+	m.mu.Unlock()
+
+	m.reply(w, map[string]string{
+		"return_code": "SUCCESS",
+		"result_code": "SUCCESS",
+		"appid":       params["appid"],
+		"mch_id":      params["mch_id"],
+		"trade_type":  params["trade_type"],
+		"prepay_id":   "wx" + util.RandomString(30),
+	})
+}
+
+func (m *mockServer) query(w http.ResponseWriter, params map[string]string) {
+	m.mu.Lock()
+	o, ok := m.orders[params["out_trade_no"]]
+	var res map[string]string
+	if ok {
+		res = map[string]string{
+			"return_code":    "SUCCESS",
+			"result_code":    "SUCCESS",
+			"appid":          o.params["appid"],
+			"mch_id":         o.params["mch_id"],
+			"openid":         o.params["openid"],
+			"trade_type":     o.params["trade_type"],
+			"trade_state":    o.state,
+			"total_fee":      o.params["total_fee"],
+			"cash_fee":       o.params["total_fee"],
+			"out_trade_no":   o.params["out_trade_no"],
+			"transaction_id": o.transactionID,
+			"attach":         o.params["attach"],
+			"time_end":       o.timeEnd,
+		}
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		res = map[string]string{"return_code": "SUCCESS", "result_code": "FAIL", "err_code": "ORDERNOTEXIST", "err_code_des": "订单不存在"}
+	}
+
+	m.reply(w, res)
+}
+
+// 模拟用户完成支付, 并向下单时的通知地址发送支付结果通知
+func (m *mockServer) pay(outTradeNo string) error {
+	m.mu.Lock()
+	o, ok := m.orders[outTradeNo]
+	if ok {
+		o.state = payment.TradeStateSuccess
+		o.transactionID = "42000" + util.RandomString(23)
+		o.timeEnd = time.Now().Format("20060102150405")
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return errors.New("订单不存在: " + outTradeNo)
+	}
+
+	var totalFee int
+	fmt.Sscan(o.params["total_fee"], &totalFee)
+
+	ctx, cancel := timeout()
+	defer cancel()
+
+	s := payment.Simulator{Key: m.key}
+	return s.SendPaidNotify(ctx, o.params["notify_url"], payment.PaidNotify{
+		AppID:         o.params["appid"],
+		MchID:         o.params["mch_id"],
+		OpenID:        o.params["openid"],
+		TradeType:     o.params["trade_type"],
+		TotalFee:      totalFee,
+		CashFee:       float64(totalFee),
+		TransactionID: o.transactionID,
+		OutTradeNo:    outTradeNo,
+		Attach:        o.params["attach"],
+		Timeend:       o.timeEnd,
+	})
+}
+
+// 校验请求签名
+func (m *mockServer) verify(params map[string]string) error {
+	data := make(map[string]string, len(params))
+	for k, v := range params {
+		if k != "sign" && v != "" {
+			data[k] = v
+		}
+	}
+
+	var sign string
+	var err error
+	if params["sign_type"] == payment.SignTypeHMACSHA256 {
+		sign, err = util.SignByHMACSHA256(data, m.key)
+	} else {
+		sign, err = util.SignByMD5(data, m.key)
+	}
+	if err != nil {
+		return err
+	}
+
+	if sign != params["sign"] {
+		return errors.New("签名错误")
+	}
+
+	return nil
+}
+
+// 返回带签名的应答
+func (m *mockServer) reply(w http.ResponseWriter, params map[string]string) {
+	params["nonce_str"] = util.RandomString(32)
+	sign, err := util.SignByMD5(params, m.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	params["sign"] = sign
+
+	w.Header().Set("Content-Type", "text/xml")
+	writeXML(w, params)
+}
+
+// 读取 <xml> 下的全部参数
+func readXML(r io.Reader) (map[string]string, error) {
+	params := make(map[string]string)
+	dec := xml.NewDecoder(r)
+
+	var name string
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return params, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name = t.Name.Local
+		case xml.CharData:
+			if name != "" && name != "xml" {
+				params[name] += string(t)
+			}
+		case xml.EndElement:
+			name = ""
+		}
+	}
+}
+
+func writeXML(w io.Writer, params map[string]string) {
+	io.WriteString(w, "<xml>")
+	for k, v := range params {
+		io.WriteString(w, "<"+k+">")
+		xml.EscapeText(w, []byte(v))
+		io.WriteString(w, "</"+k+">")
+	}
+	io.WriteString(w, "</xml>")
+}
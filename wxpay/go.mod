@@ -3,6 +3,10 @@ module github.com/wanghuobo/weapp
 go 1.12
 
 require (
-	github.com/beevik/etree v1.1.0 // indirect
-	github.com/medivhzhan/weapp v1.5.1 // indirect
+	github.com/beevik/etree v1.1.0
+	github.com/medivhzhan/weapp v1.5.1
+	github.com/xitongsys/parquet-go v1.6.2
+	github.com/xitongsys/parquet-go-source v0.0.0-20241021075129-b732d2ac9c9b
+	go.etcd.io/bbolt v1.3.5
+	golang.org/x/crypto v0.9.0
 )
@@ -0,0 +1,168 @@
+package payment
+
+import (
+	"math"
+	"time"
+
+	"github.com/wanghuobo/weapp/payment/types"
+)
+
+// 记账事件类型
+const (
+	AccountingPayment = "payment" // 收款
+	AccountingRefund  = "refund"  // 退款
+)
+
+// AccountCodes 记账科目代码
+type AccountCodes struct {
+	Cash     string // 微信支付账户, 如 1012.01 其他货币资金-微信
+	Revenue  string // 收入或预收账款
+	Discount string // 商户出资的优惠(免充值代金券), 为空时计入 Revenue 的借方
+	Refund   string // 退款, 为空时冲减 Revenue
+}
+
+// Entry 分录, 借贷金额只有一个不为0, 单位为分
+type Entry struct {
+	Account string `json:"account"`
+	Debit   int64  `json:"debit,omitempty"`
+	Credit  int64  `json:"credit,omitempty"`
+}
+
+// AccountingEvent 复式记账事件
+// ID 为微信订单号或微信退款单号, ERP 可据此去重
+type AccountingEvent struct {
+	ID            string    `json:"id"`
+	Kind          string    `json:"kind"`
+	Time          time.Time `json:"time"`
+	OutTradeNo    string    `json:"out_trade_no"`
+	TransactionID string    `json:"transaction_id"`
+	OutRefundNo   string    `json:"out_refund_no,omitempty"`
+	RefundID      string    `json:"refund_id,omitempty"`
+	Currency      string    `json:"currency"`
+	Entries       []Entry   `json:"entries"`
+}
+
+// Balanced 借贷是否平衡
+func (e AccountingEvent) Balanced() bool {
+	var debit, credit int64
+	for _, en := range e.Entries {
+		debit += en.Debit
+		credit += en.Credit
+	}
+
+	return debit == credit
+}
+
+// Accounting 把支付和退款通知转换为复式记账事件并发布到事件总线
+// 通知处理成功后才发布, 主题为 TopicAccounting
+type Accounting struct {
+	Codes AccountCodes
+	Bus   *EventBus
+}
+
+// Payment 支付通知对应的记账事件
+// 借: 微信支付账户(应结订单金额) 优惠(免充值券金额); 贷: 收入(订单金额)
+func (a *Accounting) Payment(ntf PaidNotify) AccountingEvent {
+	total := int64(ntf.TotalFee)
+	settle := fen(ntf.Settlement)
+	if settle == 0 {
+		settle = total
+	}
+
+	e := AccountingEvent{
+		ID:            ntf.TransactionID,
+		Kind:          AccountingPayment,
+		Time:          time.Now(),
+		OutTradeNo:    ntf.OutTradeNo,
+		TransactionID: ntf.TransactionID,
+		Currency:      currency(ntf.FeeType),
+	}
+	e.Entries = append(e.Entries, Entry{Account: a.Codes.Cash, Debit: settle})
+	if discount := total - settle; discount > 0 {
+		e.Entries = append(e.Entries, Entry{Account: a.discount(), Debit: discount})
+	}
+	e.Entries = append(e.Entries, Entry{Account: a.Codes.Revenue, Credit: total})
+
+	return e
+}
+
+// Refund 退款通知对应的记账事件
+// 借: 退款(退款金额); 贷: 微信支付账户(应结退款金额) 优惠(免充值券退款金额)
+func (a *Accounting) Refund(ntf RefundedNotify) AccountingEvent {
+	refund := fen(ntf.RefundFee)
+	settle := fen(ntf.SettlementRefund)
+	if settle == 0 {
+		settle = refund
+	}
+
+	e := AccountingEvent{
+		ID:            ntf.RefundID,
+		Kind:          AccountingRefund,
+		Time:          time.Now(),
+		OutTradeNo:    ntf.OutTradeNo,
+		TransactionID: ntf.TransactionID,
+		OutRefundNo:   ntf.OutRefundNo,
+		RefundID:      ntf.RefundID,
+		Currency:      currency(""),
+	}
+	e.Entries = append(e.Entries, Entry{Account: a.refund(), Debit: refund})
+	e.Entries = append(e.Entries, Entry{Account: a.Codes.Cash, Credit: settle})
+	if discount := refund - settle; discount > 0 {
+		e.Entries = append(e.Entries, Entry{Account: a.discount(), Credit: discount})
+	}
+
+	return e
+}
+
+// PaidHandler 包装支付通知处理函数, 处理成功后发布记账事件
+func (a *Accounting) PaidHandler(fn func(PaidNotify) (bool, string)) func(PaidNotify) (bool, string) {
+	return func(ntf PaidNotify) (bool, string) {
+		ok, msg := fn(ntf)
+		if ok {
+			a.publish(a.Payment(ntf))
+		}
+		return ok, msg
+	}
+}
+
+// RefundedHandler 包装退款通知处理函数, 退款成功且处理成功后发布记账事件
+func (a *Accounting) RefundedHandler(fn func(RefundedNotify) (bool, string)) func(RefundedNotify) (bool, string) {
+	return func(ntf RefundedNotify) (bool, string) {
+		ok, msg := fn(ntf)
+		if ok && ntf.RefundStatus == "SUCCESS" {
+			a.publish(a.Refund(ntf))
+		}
+		return ok, msg
+	}
+}
+
+func (a *Accounting) publish(e AccountingEvent) {
+	if a.Bus != nil {
+		a.Bus.Publish(Event{Topic: TopicAccounting, Time: e.Time, Data: e})
+	}
+}
+
+func (a *Accounting) discount() string {
+	if a.Codes.Discount != "" {
+		return a.Codes.Discount
+	}
+
+	return a.Codes.Revenue
+}
+
+func (a *Accounting) refund() string {
+	if a.Codes.Refund != "" {
+		return a.Codes.Refund
+	}
+
+	return a.Codes.Revenue
+}
+
+// 通知中以浮点数表示的金额(分)
+func fen(v float64) int64 {
+	return int64(math.Round(v))
+}
+
+func currency(feeType string) string {
+	return string(types.Currency(feeType).OrDefault())
+}
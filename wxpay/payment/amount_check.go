@@ -0,0 +1,107 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 金额校验规则
+const (
+	AmountRuleTotal      = "TOTAL_FEE"      // 通知金额与下单金额一致
+	AmountRuleCash       = "CASH_COUPON"    // 现金支付金额 + 代金券金额 = 订单金额
+	AmountRuleCoupons    = "COUPON_ITEMS"   // 各代金券金额之和 = 代金券金额
+	AmountRuleSettlement = "SETTLEMENT_FEE" // 订单金额 - 代金券金额 <= 应结订单金额 <= 订单金额
+)
+
+// AmountViolation 金额不一致
+type AmountViolation struct {
+	Rule     string // 校验规则, 见 AmountRule 常量
+	Expected int    // 期望金额(分)
+	Actual   int    // 实际金额(分)
+}
+
+func (v AmountViolation) String() string {
+	return fmt.Sprintf("%s: 期望 %d, 实际 %d", v.Rule, v.Expected, v.Actual)
+}
+
+// CheckAmounts 校验支付通知中的金额
+// 结算金额的范围校验中期望金额为下限
+//
+// @ntf 支付结果通知
+// @totalFee 下单时的订单金额, 为0时不校验
+func CheckAmounts(ntf PaidNotify, totalFee int) (vs []AmountViolation) {
+	if totalFee > 0 && ntf.TotalFee != totalFee {
+		vs = append(vs, AmountViolation{Rule: AmountRuleTotal, Expected: totalFee, Actual: ntf.TotalFee})
+	}
+
+	cash, coupon := int(fen(ntf.CashFee)), int(fen(ntf.CouponFee))
+	if cash+coupon != ntf.TotalFee {
+		vs = append(vs, AmountViolation{Rule: AmountRuleCash, Expected: ntf.TotalFee, Actual: cash + coupon})
+	}
+
+	if len(ntf.Coupons) > 0 {
+		sum := 0
+		for _, c := range ntf.Coupons {
+			sum += int(c.CouponFee)
+		}
+		if sum != coupon {
+			vs = append(vs, AmountViolation{Rule: AmountRuleCoupons, Expected: coupon, Actual: sum})
+		}
+	}
+
+	// 应结订单金额 = 订单金额 - 非充值代金券金额, 只在使用非充值代金券时返回
+	// 返回了券类型时可以精确计算, 否则只能校验范围
+	if ntf.couponTyped() {
+		if expected := ntf.SettlementExpected(); ntf.SettlementTotal() != expected {
+			vs = append(vs, AmountViolation{Rule: AmountRuleSettlement, Expected: expected, Actual: ntf.SettlementTotal()})
+		}
+	} else if settlement := int(fen(ntf.Settlement)); settlement > 0 {
+		if settlement > ntf.TotalFee || settlement < ntf.TotalFee-coupon {
+			vs = append(vs, AmountViolation{Rule: AmountRuleSettlement, Expected: ntf.TotalFee - coupon, Actual: settlement})
+		}
+	}
+
+	return
+}
+
+// AssertAmounts 校验支付通知中的金额, 不一致时写入审计事件(AuditAmount)并返回错误
+// 设置了 Config.OrderStore 时与下单金额比较, 可以在支付通知处理函数中调用
+func (c *Client) AssertAmounts(ntf PaidNotify) error {
+	totalFee := 0
+	if store := c.conf().OrderStore; store != nil {
+		r, err := store.GetOrder(ntf.OutTradeNo)
+		if err != nil {
+			return err
+		}
+		if r != nil {
+			totalFee = r.Order.TotalFee
+		}
+	}
+
+	vs := CheckAmounts(ntf, totalFee)
+	if len(vs) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, len(vs))
+	for i, v := range vs {
+		msgs[i] = v.String()
+	}
+	err := errors.New("金额不一致: " + strings.Join(msgs, "; "))
+
+	for _, v := range vs {
+		c.audit(AuditEvent{
+			Operation:     AuditAmount,
+			AppID:         ntf.AppID,
+			MchID:         ntf.MchID,
+			OutTradeNo:    ntf.OutTradeNo,
+			TransactionID: ntf.TransactionID,
+			OpenID:        ntf.OpenID,
+			Amount:        v.Actual,
+			ErrCode:       v.Rule,
+		}, nil, errors.New("金额不一致: "+v.String()))
+	}
+
+	return err
+}
@@ -0,0 +1,112 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+// AppParams APP 调起支付的参数
+type AppParams struct {
+	AppID     string `json:"appid"`
+	PartnerID string `json:"partnerid"` // 商户号
+	PrepayID  string `json:"prepayid"`
+	Package   string `json:"package"` // 固定为 Sign=WXPay
+	NonceStr  string `json:"noncestr"`
+	Timestamp string `json:"timestamp"`
+	Sign      string `json:"sign"`
+}
+
+// AppIdentity 同一商户号下某个 APPID 的下单及支付参数生成
+// 一个商户号绑定小程序、公众号、APP 等多个 APPID 时, 下单使用的 APPID 必须与调起支付签名的 APPID 一致,
+// 否则前端会报签名错误。AppIdentity 保证两者使用同一个 APPID
+type AppIdentity struct {
+	client *Client
+	appID  string
+}
+
+// App 返回指定 APPID 的下单及支付参数生成
+// APPID 必须是 Config.AppID 或在 Config.AppIDs 中
+func (c *Client) App(appID string) (*AppIdentity, error) {
+	cfg := c.conf()
+	if appID != cfg.AppID && !containsString(cfg.AppIDs, appID) {
+		return nil, errors.New("APPID 未绑定到当前客户端: " + appID)
+	}
+
+	return &AppIdentity{client: c, appID: appID}, nil
+}
+
+// AppID 返回绑定的 APPID
+func (a *AppIdentity) AppID() string {
+	return a.appID
+}
+
+// Unify 使用绑定的 APPID 统一下单
+// 订单未设置 appid 时使用绑定的 APPID, 设置了其他 APPID 时返回错误
+func (a *AppIdentity) Unify(ctx context.Context, o Order, opts ...CallOption) (PaidResponse, error) {
+	if o.AppID == "" {
+		o.AppID = a.appID
+	}
+	if o.AppID != a.appID {
+		return PaidResponse{}, errors.New("订单 APPID " + o.AppID + " 与支付参数 APPID " + a.appID + " 不一致")
+	}
+
+	return a.client.Unify(ctx, o, opts...)
+}
+
+// Params 生成小程序、公众号调起支付的参数
+//
+// @res 使用同一 APPID 统一下单的结果
+func (a *AppIdentity) Params(res PaidResponse) (Params, error) {
+	if err := a.check(res); err != nil {
+		return Params{}, err
+	}
+
+	return GetParams(a.appID, a.client.conf().Key, res.NonceStr, res.PrePayID)
+}
+
+// AppParams 生成 APP 调起支付的参数
+//
+// @res 使用同一 APPID 统一下单的结果
+func (a *AppIdentity) AppParams(res PaidResponse) (p AppParams, err error) {
+	if err = a.check(res); err != nil {
+		return
+	}
+
+	cfg := a.client.conf()
+	p = AppParams{
+		AppID:     a.appID,
+		PartnerID: cfg.MchID,
+		PrepayID:  res.PrePayID,
+		Package:   "Sign=WXPay",
+		NonceStr:  util.RandomString(32),
+		Timestamp: strconv.FormatInt(time.Now().Unix(), 10),
+	}
+
+	p.Sign, err = util.SignByMD5(map[string]string{
+		"appid":     p.AppID,
+		"partnerid": p.PartnerID,
+		"prepayid":  p.PrepayID,
+		"package":   p.Package,
+		"noncestr":  p.NonceStr,
+		"timestamp": p.Timestamp,
+	}, cfg.Key)
+
+	return
+}
+
+// 下单结果必须来自同一 APPID
+func (a *AppIdentity) check(res PaidResponse) error {
+	if res.PrePayID == "" {
+		return errors.New("prepay_id 不能为空")
+	}
+
+	if res.AppID != "" && res.AppID != a.appID {
+		return errors.New("下单 APPID " + res.AppID + " 与支付参数 APPID " + a.appID + " 不一致")
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package payment
+
+import (
+	"context"
+	"strconv"
+)
+
+// ApprovalRequest 待审批的资金操作
+type ApprovalRequest struct {
+	Operation   string // AuditRefund 或 AuditTransfer
+	AppID       string
+	MchID       string
+	OutTradeNo  string // 商户订单号
+	OutRefundNo string // 商户退款单号, 仅退款
+	OpenID      string // 收款用户, 仅转账
+	Amount      int    // 金额(分)
+	Desc        string // 退款原因或付款描述
+}
+
+// Approval 审批结果
+type Approval struct {
+	Approved bool
+	Reason   string // 拒绝原因
+}
+
+// Approver 资金操作审批
+// 金额超过阈值的退款和转账在请求微信前需要审批通过, 可以对接复核(双人)流程
+type Approver interface {
+	Approve(ctx context.Context, req ApprovalRequest) (Approval, error)
+}
+
+// ApprovalPolicy 审批策略
+type ApprovalPolicy struct {
+	Approver          Approver
+	RefundThreshold   int // 退款金额超过该值(分)时需要审批, 0 表示全部需要审批
+	TransferThreshold int // 转账金额超过该值(分)时需要审批, 0 表示全部需要审批
+}
+
+// DeniedError 审批未通过
+type DeniedError struct {
+	Operation string
+	Amount    int
+	Reason    string
+}
+
+// Error 实现 error
+func (e *DeniedError) Error() string {
+	return "操作未通过审批: " + e.Operation + " " + strconv.Itoa(e.Amount) + "分, " + e.Reason
+}
+
+// 按策略审批资金操作, 未配置审批或金额未超过阈值时直接通过
+func (p *ApprovalPolicy) approve(ctx context.Context, req ApprovalRequest) error {
+	if p == nil || p.Approver == nil {
+		return nil
+	}
+
+	threshold := p.TransferThreshold
+	if req.Operation == AuditRefund {
+		threshold = p.RefundThreshold
+	}
+
+	if threshold > 0 && req.Amount <= threshold {
+		return nil
+	}
+
+	res, err := p.Approver.Approve(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if !res.Approved {
+		return &DeniedError{Operation: req.Operation, Amount: req.Amount, Reason: res.Reason}
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package payment
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// 审计事件操作类型
+const (
+	AuditUnify    = "unify"    // 统一下单
+	AuditRefund   = "refund"   // 申请退款
+	AuditTransfer = "transfer" // 企业付款到零钱
+	AuditRedpack  = "redpack"  // 发放现金红包
+	AuditAmount   = "amount"   // 金额校验不一致, 见 AssertAmounts
+)
+
+// AuditEvent 资金操作审计事件
+// 每次调用资金相关接口后生成一条, 无论成功与否
+type AuditEvent struct {
+	Time          time.Time `json:"time"`
+	Operation     string    `json:"operation"`
+	AppID         string    `json:"appid"`
+	MchID         string    `json:"mch_id"`
+	OutTradeNo    string    `json:"out_trade_no"`             // 商户订单号, 红包为商户订单号 mch_billno
+	OutRefundNo   string    `json:"out_refund_no,omitempty"`  // 商户退款单号
+	TransactionID string    `json:"transaction_id,omitempty"` // 微信订单号/退款单号/付款单号
+	OpenID        string    `json:"openid,omitempty"`
+	Amount        int       `json:"amount"` // 金额(分)
+
+	URL           string `json:"url"`
+	RequestNonce  string `json:"request_nonce"`
+	ResponseNonce string `json:"response_nonce,omitempty"`
+	StatusCode    int    `json:"status_code,omitempty"`
+	DurationMS    int64  `json:"duration_ms"`
+
+	Success bool   `json:"success"`
+	ErrCode string `json:"err_code,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// AuditSink 审计事件接收者
+// Audit 在调用返回前同步执行, 实现方需要自行处理耗时操作
+type AuditSink interface {
+	Audit(AuditEvent)
+}
+
+// AuditFunc 函数形式的 AuditSink
+type AuditFunc func(AuditEvent)
+
+// Audit 实现 AuditSink
+func (fn AuditFunc) Audit(e AuditEvent) {
+	fn(e)
+}
+
+// JSONAuditSink 以 JSON Lines 格式写入审计事件
+type JSONAuditSink struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewJSONAuditSink 新建 JSON Lines 审计事件接收者
+func NewJSONAuditSink(w io.Writer) *JSONAuditSink {
+	return &JSONAuditSink{w: w, enc: json.NewEncoder(w)}
+}
+
+// Flush 刷新缓冲区, w 为 *bufio.Writer 等带缓冲的写入者时生效
+func (s *JSONAuditSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f, ok := s.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+
+	return nil
+}
+
+// Audit 写入一条审计事件
+func (s *JSONAuditSink) Audit(e AuditEvent) {
+	s.mu.Lock()
+	s.enc.Encode(e)
+	s.mu.Unlock()
+}
+
+// 补全审计事件并发送给 AuditSink
+func (c *Client) audit(e AuditEvent, info *CallInfo, err error) {
+	sink := c.conf().AuditSink
+	if sink == nil {
+		return
+	}
+
+	e.Time = time.Now()
+	if info != nil {
+		e.URL = info.URL
+		e.RequestNonce = info.RequestNonce
+		e.ResponseNonce = info.ResponseNonce
+		e.StatusCode = info.StatusCode
+		e.DurationMS = int64(info.Duration / time.Millisecond)
+	}
+
+	e.Success = err == nil
+	if err != nil {
+		if e.ErrCode == "" {
+			e.ErrCode = ErrCodeOf(err)
+		}
+		e.Error = err.Error()
+	}
+
+	sink.Audit(e)
+}
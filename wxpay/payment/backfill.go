@@ -0,0 +1,178 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// 交易账单列名
+const (
+	billColumnTransactionID = "微信订单号"
+	billColumnOutTradeNo    = "商户订单号"
+)
+
+// Backfill 历史订单补单
+// 按日下载成功支付账单, 对业务系统未记录的微信订单号调用订单查询,
+// 并以合成的支付通知交给通知处理函数, 用于找回通知丢失或处理失败的订单
+type Backfill struct {
+	Client *Client
+
+	// Known 判断订单是否已被业务系统记录, 为空时使用客户端配置的 OrderStore(状态为支付成功视为已记录),
+	// 两者都没有时全部订单视为未记录, 由 Handler 自行去重
+	Known func(outTradeNo, transactionID string) (bool, error)
+
+	// Handler 处理遗漏的订单, 与 HandlePaidNotify 的处理函数相同, 返回 false 时计为失败
+	Handler func(PaidNotify) (bool, string)
+
+	// Bus 不为空时发布 TopicDiscovered 事件
+	Bus *EventBus
+}
+
+// BackfillReport 补单结果
+type BackfillReport struct {
+	Days       int              // 处理的账单天数
+	Rows       int              // 账单中的订单数
+	Known      int              // 已记录的订单数
+	Discovered int              // 补单成功的订单数
+	Failed     map[string]error // 补单失败的订单, key 为微信订单号, 下载账单失败时为账单日期
+}
+
+// Resync 补单
+// 账单日期从 from 到 to(包含), 只使用年月日; 当日账单次日10点后才能下载。
+// 单个订单失败不会中断补单, 失败原因记录在 Failed 中, 只有 ctx 取消时返回错误
+func (b *Backfill) Resync(ctx context.Context, from, to time.Time) (report BackfillReport, err error) {
+	if b.Client == nil {
+		err = errors.New("没有设置客户端")
+		return
+	}
+
+	report.Failed = make(map[string]error)
+
+	from = from.In(cst)
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, cst)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		report.Days++
+		b.resyncDay(ctx, day, &report)
+	}
+
+	err = ctx.Err()
+	return
+}
+
+func (b *Backfill) resyncDay(ctx context.Context, day time.Time, report *BackfillReport) {
+	bl, err := b.Client.DownloadBill(ctx, day, BillTypeSuccess)
+	if err != nil {
+		// 当天没有交易时微信返回 No Bill Exist
+		if e, ok := err.(*Error); !ok || e.ReturnMsg != "No Bill Exist" {
+			report.Failed[day.Format(billDateFormat)] = err
+		}
+		return
+	}
+
+	for i := range bl.Rows {
+		if ctx.Err() != nil {
+			return
+		}
+
+		report.Rows++
+		transactionID := bl.Value(i, billColumnTransactionID)
+		outTradeNo := bl.Value(i, billColumnOutTradeNo)
+
+		known, err := b.known(outTradeNo, transactionID)
+		if err != nil {
+			report.Failed[transactionID] = err
+			continue
+		}
+		if known {
+			report.Known++
+			continue
+		}
+
+		if err := b.discover(ctx, transactionID); err != nil {
+			report.Failed[transactionID] = err
+			continue
+		}
+		report.Discovered++
+	}
+}
+
+func (b *Backfill) known(outTradeNo, transactionID string) (bool, error) {
+	if b.Known != nil {
+		return b.Known(outTradeNo, transactionID)
+	}
+
+	store := b.Client.conf().OrderStore
+	if store == nil {
+		return false, nil
+	}
+
+	r, err := store.GetOrder(outTradeNo)
+	if err != nil || r == nil {
+		return false, err
+	}
+
+	return r.TradeState == TradeStateSuccess || r.TradeState == TradeStateRefund, nil
+}
+
+// 查询订单并交给处理函数
+func (b *Backfill) discover(ctx context.Context, transactionID string) error {
+	res, err := b.Client.QueryOrderByTransactionID(ctx, transactionID)
+	if err != nil {
+		return err
+	}
+	if !res.Paid() {
+		return errors.New("订单状态为 " + res.TradeState)
+	}
+
+	ntf := discoveredNotify(res)
+	if b.Handler != nil {
+		if ok, msg := b.Handler(ntf); !ok {
+			return errors.New("处理失败: " + msg)
+		}
+	}
+
+	if b.Bus != nil {
+		b.Bus.Publish(Event{Topic: TopicDiscovered, Time: time.Now(), Data: ntf})
+	}
+
+	if store := b.Client.conf().OrderStore; store != nil {
+		r, err := store.GetOrder(res.OutTradeNo)
+		if err != nil || r == nil {
+			return err
+		}
+		r.TradeState = res.TradeState
+		r.UpdatedAt = time.Now()
+		return store.SaveOrder(*r)
+	}
+
+	return nil
+}
+
+// 使用查询结果合成支付通知, 不包含签名
+func discoveredNotify(r QueryResult) PaidNotify {
+	return PaidNotify{
+		AppID:         r.AppID,
+		MchID:         r.MchID,
+		TotalFee:      r.TotalFee,
+		NonceStr:      r.NonceStr,
+		OpenID:        r.OpenID,
+		TradeType:     r.TradeType,
+		Bank:          r.BankType,
+		Settlement:    float64(r.Settlement),
+		FeeType:       r.FeeType,
+		CashFee:       float64(r.CashFee),
+		CashFeeType:   r.CashFeeType,
+		CouponFee:     float64(r.CouponFee),
+		CouponCount:   r.CouponCount,
+		TransactionID: r.TransactionID,
+		Attach:        r.Attach,
+		IsSubscribe:   r.IsSubscribe,
+		OutTradeNo:    r.OutTradeNo,
+		Timeend:       r.TimeEnd,
+	}
+}
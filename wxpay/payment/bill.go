@@ -0,0 +1,124 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"time"
+
+	"github.com/wanghuobo/weapp/payment/bill"
+)
+
+const (
+	downloadBillAPI     = "/pay/downloadbill"
+	downloadFundFlowAPI = "/pay/downloadfundflow"
+	billDateFormat      = "20060102"
+)
+
+// 交易账单类型
+const (
+	BillTypeAll            = "ALL"             // 当日所有订单信息
+	BillTypeSuccess        = "SUCCESS"         // 当日成功支付的订单
+	BillTypeRefund         = "REFUND"          // 当日退款订单
+	BillTypeRechargeRefund = "RECHARGE_REFUND" // 当日充值退款订单
+)
+
+// 下载交易账单请求
+type downloadBill struct {
+	AppID    string `sign:"appid"`
+	MchID    string `sign:"mch_id"`
+	BillDate string `sign:"bill_date"`
+	BillType string `sign:"bill_type"`
+	TarType  string `sign:"tar_type"`
+}
+
+// 下载资金账单请求
+type downloadFundFlow struct {
+	AppID       string `sign:"appid"`
+	MchID       string `sign:"mch_id"`
+	BillDate    string `sign:"bill_date"`
+	AccountType string `sign:"account_type"`
+	TarType     string `sign:"tar_type"`
+}
+
+// DownloadBill 下载交易账单
+// 以 GZIP 格式下载后自动解压, 并校验数据行数与汇总行一致, 不一致时返回 *bill.CorruptError
+//
+// @date 账单日期, 只使用年月日
+// @billType 账单类型, 见 BillType 常量
+func (c *Client) DownloadBill(ctx context.Context, date time.Time, billType string, opts ...CallOption) (b *bill.Bill, err error) {
+	if err = c.begin(); err != nil {
+		return
+	}
+	defer c.end()
+
+	opt := c.options(opts)
+	cfg := opt.conf
+	q := downloadBill{
+		AppID:    cfg.AppID,
+		MchID:    cfg.MchID,
+		BillDate: date.Format(billDateFormat),
+		BillType: billType,
+		TarType:  "GZIP",
+	}
+	reqData, err := signedFields(q, cfg.Key, opt.signType)
+	if err != nil {
+		return
+	}
+
+	data, err := c.post(ctx, opt, downloadBillAPI, reqData, false)
+	if err != nil {
+		return
+	}
+
+	return parseBill(data)
+}
+
+// DownloadFundFlow 下载资金账单
+// 资金账单接口需要商户证书, 且固定使用 HMAC-SHA256 签名
+//
+// @accountType 资金账户类型: Basic 基本账户 | Operation 运营账户 | Fees 手续费账户
+func (c *Client) DownloadFundFlow(ctx context.Context, date time.Time, accountType string, opts ...CallOption) (b *bill.Bill, err error) {
+	if err = c.begin(); err != nil {
+		return
+	}
+	defer c.end()
+
+	opt := c.options(opts)
+	cfg := opt.conf
+	q := downloadFundFlow{
+		AppID:       cfg.AppID,
+		MchID:       cfg.MchID,
+		BillDate:    date.Format(billDateFormat),
+		AccountType: accountType,
+		TarType:     "GZIP",
+	}
+	reqData, err := signedFields(q, cfg.Key, SignTypeHMACSHA256)
+	if err != nil {
+		return
+	}
+
+	data, err := c.post(ctx, opt, downloadFundFlowAPI, reqData, true)
+	if err != nil {
+		return
+	}
+
+	return parseBill(data)
+}
+
+// 下载失败时返回 XML 格式的错误信息, 成功时为账单文件
+func parseBill(data []byte) (*bill.Bill, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<xml>")) {
+		var res response
+		if err := xml.Unmarshal(data, &res); err != nil {
+			return nil, err
+		}
+
+		if res.ReturnCode == "" || res.ReturnCode == "SUCCESS" {
+			res.ReturnCode = "FAIL"
+		}
+		return nil, newError(res)
+	}
+
+	return bill.Parse(data)
+}
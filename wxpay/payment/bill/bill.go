@@ -0,0 +1,235 @@
+// Package bill 微信支付对账单解析
+// v2 和 v3 下载的交易账单、资金账单格式相同: 表头行、以 ` 开头的数据行、汇总表头行和汇总行
+package bill
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// CorruptError 对账单不完整或被篡改, 不能用于对账
+type CorruptError struct {
+	Reason string
+}
+
+func (e *CorruptError) Error() string {
+	return "对账单不完整: " + e.Reason
+}
+
+func corrupt(format string, a ...interface{}) error {
+	return &CorruptError{Reason: fmt.Sprintf(format, a...)}
+}
+
+// Bill 解析后的对账单
+type Bill struct {
+	Columns        []string   // 表头
+	Rows           [][]string // 数据行, 已去掉字段前的 `
+	SummaryColumns []string   // 汇总表头
+	Summary        []string   // 汇总数据
+
+	index map[string]int
+}
+
+// Gunzip 解压 GZIP 格式的对账单, 不是 GZIP 格式时原样返回
+func Gunzip(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return data, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, corrupt("解压失败: %v", err)
+	}
+	defer r.Close()
+
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, corrupt("解压失败: %v", err)
+	}
+
+	return out, nil
+}
+
+// VerifyHash 校验下载文件的摘要, 用于 v3 下载账单
+// 摘要按下载的原始文件计算, 压缩账单不需要先解压
+//
+// @hashType SHA1 或 SHA256
+// @hashValue 申请账单时返回的 hash_value
+func VerifyHash(data []byte, hashType, hashValue string) error {
+	var h hash.Hash
+	switch strings.ToUpper(hashType) {
+	case "SHA1":
+		h = sha1.New()
+	case "SHA256":
+		h = sha256.New()
+	default:
+		return errors.New("不支持的摘要算法: " + hashType)
+	}
+
+	h.Write(data)
+	if sum := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(sum, hashValue) {
+		return corrupt("%s 摘要不一致, 期望 %s 实际 %s", hashType, hashValue, sum)
+	}
+
+	return nil
+}
+
+// Parse 解析对账单
+// GZIP 格式自动解压, 并校验数据行数与汇总行中的总笔数一致
+func Parse(data []byte) (*Bill, error) {
+	data, err := Gunzip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) < 3 {
+		return nil, corrupt("缺少表头或汇总行")
+	}
+
+	b := &Bill{Columns: splitLine(lines[0])}
+
+	i := 1
+	for ; i < len(lines) && strings.HasPrefix(lines[i], "`"); i++ {
+		row := splitLine(lines[i])
+		if len(row) < len(b.Columns) {
+			return nil, corrupt("第%d行只有%d个字段, 表头有%d个", i+1, len(row), len(b.Columns))
+		}
+		b.Rows = append(b.Rows, row)
+	}
+
+	if i+2 != len(lines) {
+		return nil, corrupt("缺少汇总行")
+	}
+
+	b.SummaryColumns = splitLine(lines[i])
+	b.Summary = splitLine(lines[i+1])
+	if len(b.Summary) == 0 || len(b.Summary) != len(b.SummaryColumns) {
+		return nil, corrupt("汇总行字段数与汇总表头不一致")
+	}
+
+	// 汇总行第一项为总交易单数或资金流水总笔数
+	total, err := strconv.Atoi(b.Summary[0])
+	if err != nil {
+		return nil, corrupt("无法解析%s: %s", b.SummaryColumns[0], b.Summary[0])
+	}
+	if total != len(b.Rows) {
+		return nil, corrupt("%s为%d, 实际有%d行", b.SummaryColumns[0], total, len(b.Rows))
+	}
+
+	return b, nil
+}
+
+// 按逗号拆分并去掉字段前的 `
+func splitLine(line string) []string {
+	fields := strings.Split(line, ",")
+	for i, f := range fields {
+		fields[i] = strings.TrimPrefix(strings.TrimSpace(f), "`")
+	}
+
+	return fields
+}
+
+// Column 返回列的下标, 不存在时返回 -1
+func (b *Bill) Column(name string) int {
+	if b.index == nil {
+		b.index = make(map[string]int, len(b.Columns))
+		for i, c := range b.Columns {
+			b.index[c] = i
+		}
+	}
+
+	if i, ok := b.index[name]; ok {
+		return i
+	}
+
+	return -1
+}
+
+// Value 返回第 row 行指定列的值, 列不存在时返回空
+func (b *Bill) Value(row int, column string) string {
+	i := b.Column(column)
+	if i < 0 || row < 0 || row >= len(b.Rows) {
+		return ""
+	}
+
+	return b.Rows[row][i]
+}
+
+// Amount 返回第 row 行指定列的金额(分), 空值为0
+// 账单中金额单位为元, 手续费等保留更多小数位, 按四舍五入换算为分
+func (b *Bill) Amount(row int, column string) (int64, error) {
+	return ParseAmount(b.Value(row, column))
+}
+
+// SummaryValue 返回汇总行指定列的值
+func (b *Bill) SummaryValue(column string) string {
+	for i, c := range b.SummaryColumns {
+		if c == column && i < len(b.Summary) {
+			return b.Summary[i]
+		}
+	}
+
+	return ""
+}
+
+// ParseAmount 把以元为单位的金额转换为分, 四舍五入
+func ParseAmount(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	yuan, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		yuan, frac = s[:i], s[i+1:]
+	}
+	if yuan == "" {
+		yuan = "0"
+	}
+
+	for len(frac) < 3 {
+		frac += "0"
+	}
+
+	y, err := strconv.ParseInt(yuan, 10, 64)
+	if err != nil {
+		return 0, errors.New("金额格式错误: " + s)
+	}
+	f, err := strconv.ParseInt(frac[:2], 10, 64)
+	if err != nil {
+		return 0, errors.New("金额格式错误: " + s)
+	}
+	if strings.Trim(frac[2:], "0123456789") != "" {
+		return 0, errors.New("金额格式错误: " + s)
+	}
+
+	fen := y*100 + f
+	if frac[2] >= '5' {
+		fen++
+	}
+	if neg {
+		fen = -fen
+	}
+
+	return fen, nil
+}
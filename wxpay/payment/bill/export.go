@@ -0,0 +1,119 @@
+package bill
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+)
+
+// EnglishColumns 常用账单列的英文列名, 可用于导出到数据仓库
+var EnglishColumns = map[string]string{
+	// 交易账单
+	"交易时间":    "trade_time",
+	"公众账号ID":  "appid",
+	"商户号":     "mch_id",
+	"特约商户号":   "sub_mch_id",
+	"子商户号":    "sub_mch_id",
+	"设备号":     "device_info",
+	"微信订单号":   "transaction_id",
+	"商户订单号":   "out_trade_no",
+	"用户标识":    "openid",
+	"交易类型":    "trade_type",
+	"交易状态":    "trade_state",
+	"付款银行":    "bank_type",
+	"货币种类":    "fee_type",
+	"应结订单金额":  "settlement_total_fee",
+	"代金券金额":   "coupon_fee",
+	"微信退款单号":  "refund_id",
+	"商户退款单号":  "out_refund_no",
+	"退款金额":    "settlement_refund_fee",
+	"充值券退款金额": "coupon_refund_fee",
+	"退款类型":    "refund_channel",
+	"退款状态":    "refund_status",
+	"商品名称":    "body",
+	"商户数据包":   "attach",
+	"手续费":     "fee",
+	"费率":      "rate",
+	"订单金额":    "total_fee",
+	"申请退款金额":  "refund_fee",
+	"费率备注":    "rate_notes",
+
+	// 资金账单
+	"记账时间":      "accounting_time",
+	"微信支付业务单号":  "biz_transaction_id",
+	"资金流水单号":    "fund_flow_id",
+	"业务名称":      "biz_name",
+	"业务类型":      "biz_type",
+	"收支类型":      "financial_type",
+	"收支金额（元）":   "financial_fee",
+	"账户结余（元）":   "balance",
+	"资金变更提交申请人": "applicant",
+	"备注":        "memo",
+	"业务凭证号":     "biz_voucher_id",
+}
+
+// ExportOptions 导出参数
+type ExportOptions struct {
+	// Columns 列名映射, 未映射的列保持原名, 可以使用 EnglishColumns
+	Columns map[string]string
+}
+
+// 导出使用的列名
+func (o ExportOptions) names(columns []string) []string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c
+		if n, ok := o.Columns[c]; ok {
+			names[i] = n
+		}
+	}
+
+	return names
+}
+
+// Records 以列名为键返回数据行
+func (b *Bill) Records(opt ExportOptions) []map[string]string {
+	names := opt.names(b.Columns)
+
+	records := make([]map[string]string, 0, len(b.Rows))
+	for _, row := range b.Rows {
+		r := make(map[string]string, len(names))
+		for i, n := range names {
+			r[n] = row[i]
+		}
+		records = append(records, r)
+	}
+
+	return records
+}
+
+// WriteCSV 以 CSV 格式写出数据行, 第一行为表头, 不包含汇总行
+func (b *Bill) WriteCSV(w io.Writer, opt ExportOptions) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(opt.names(b.Columns)); err != nil {
+		return err
+	}
+
+	for _, row := range b.Rows {
+		if err := cw.Write(row[:len(b.Columns)]); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSONLines 以 JSON Lines 格式写出数据行, 每行一个以列名为键的对象
+func (b *Bill) WriteJSONLines(w io.Writer, opt ExportOptions) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	for _, r := range b.Records(opt) {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+//go:build parquet
+// +build parquet
+
+package bill
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/xitongsys/parquet-go-source/writerfile"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// WriteParquet 以 Parquet 格式写出数据行, 所有列为可空 UTF8 字符串
+// 需要使用 -tags parquet 编译; 列名只能包含字母、数字和下划线, 其余列名替换为 col_序号
+func (b *Bill) WriteParquet(w io.Writer, opt ExportOptions) error {
+	names := opt.names(b.Columns)
+	for i, n := range names {
+		if !parquetName(n) {
+			names[i] = "col_" + strconv.Itoa(i)
+		}
+	}
+
+	fields := make([]string, len(names))
+	for i, n := range names {
+		fields[i] = `{"Tag":"name=` + n + `, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"}`
+	}
+	schema := `{"Tag":"name=bill, repetitiontype=REQUIRED","Fields":[` + strings.Join(fields, ",") + `]}`
+
+	pw, err := writer.NewJSONWriter(schema, writerfile.NewWriterFile(w), 1)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range b.Rows {
+		r := make(map[string]string, len(names))
+		for i, n := range names {
+			r[n] = row[i]
+		}
+
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		if err = pw.Write(string(data)); err != nil {
+			return err
+		}
+	}
+
+	return pw.WriteStop()
+}
+
+func parquetName(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
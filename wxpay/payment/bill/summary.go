@@ -0,0 +1,212 @@
+package bill
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wanghuobo/weapp/payment/types"
+)
+
+// DailySummary 单日单币种的交易汇总, 金额单位为分
+type DailySummary struct {
+	Date     string         // 交易日期 yyyy-MM-dd
+	Currency types.Currency // 货币种类
+
+	Orders  int   // 支付笔数
+	Gross   int64 // 应结订单金额
+	Coupons int64 // 代金券金额
+	Refunds int   // 退款笔数
+	Refund  int64 // 退款金额
+	Fees    int64 // 手续费, 退款退回的手续费已扣除
+	Net     int64 // 净结算金额 = 应结订单金额 - 退款金额 - 手续费
+}
+
+// FundSummary 单日资金账单汇总, 金额单位为分
+type FundSummary struct {
+	Date    string // 记账日期 yyyy-MM-dd
+	Income  int64  // 收入
+	Expense int64  // 支出
+	Net     int64  // 收入 - 支出
+}
+
+// SettlementReport 结算汇总, 供对账使用
+type SettlementReport struct {
+	Trade []DailySummary // 按日期、币种排序
+	Fund  []FundSummary  // 按日期排序
+}
+
+// Totals 按币种合计交易汇总
+func (r SettlementReport) Totals() map[types.Currency]DailySummary {
+	totals := make(map[types.Currency]DailySummary)
+	for _, d := range r.Trade {
+		t := totals[d.Currency]
+		t.Currency = d.Currency
+		t.Orders += d.Orders
+		t.Gross += d.Gross
+		t.Coupons += d.Coupons
+		t.Refunds += d.Refunds
+		t.Refund += d.Refund
+		t.Fees += d.Fees
+		t.Net += d.Net
+		totals[d.Currency] = t
+	}
+
+	return totals
+}
+
+// Summarize 根据交易账单和资金账单计算每日结算汇总, 任一账单可以为 nil
+// 交易账单只有一种币种时, 校验计算结果与账单汇总行一致, 不一致时返回 *CorruptError
+func Summarize(trade, fund *Bill) (r SettlementReport, err error) {
+	if trade != nil {
+		if r.Trade, err = summarizeTrade(trade); err != nil {
+			return
+		}
+	}
+
+	if fund != nil {
+		r.Fund, err = summarizeFund(fund)
+	}
+
+	return
+}
+
+func summarizeTrade(b *Bill) ([]DailySummary, error) {
+	days := make(map[string]*DailySummary)
+	var keys []string
+
+	for i := range b.Rows {
+		currency := types.Currency(b.Value(i, "货币种类")).OrDefault()
+		date := day(b.Value(i, "交易时间"))
+		key := date + " " + string(currency)
+
+		d := days[key]
+		if d == nil {
+			d = &DailySummary{Date: date, Currency: currency}
+			days[key] = d
+			keys = append(keys, key)
+		}
+
+		amounts := make(map[string]int64, 4)
+		for _, col := range []string{"应结订单金额", "代金券金额", "退款金额", "手续费"} {
+			v, err := b.Amount(i, col)
+			if err != nil {
+				return nil, corrupt("第%d行%s: %v", i+2, col, err)
+			}
+			amounts[col] = v
+		}
+
+		// 退款行有微信退款单号, 应结订单金额为原订单金额, 不计入当日收入
+		if b.Value(i, "微信退款单号") != "" {
+			d.Refunds++
+			d.Refund += amounts["退款金额"]
+		} else {
+			d.Orders++
+			d.Gross += amounts["应结订单金额"]
+			d.Coupons += amounts["代金券金额"]
+		}
+		d.Fees += amounts["手续费"]
+	}
+
+	sort.Strings(keys)
+	list := make([]DailySummary, 0, len(keys))
+	for _, k := range keys {
+		d := days[k]
+		d.Net = d.Gross - d.Refund - d.Fees
+		list = append(list, *d)
+	}
+
+	return list, checkTradeSummary(b, list)
+}
+
+// 单币种账单与汇总行比对
+// 手续费逐笔四舍五入后合计可能与汇总行相差几分, 不做比对
+func checkTradeSummary(b *Bill, list []DailySummary) error {
+	r := SettlementReport{Trade: list}
+	totals := r.Totals()
+	if len(totals) != 1 {
+		return nil
+	}
+
+	var t DailySummary
+	for _, v := range totals {
+		t = v
+	}
+
+	for col, v := range map[string]int64{
+		"应结订单总金额": t.Gross,
+		"退款总金额":   t.Refund,
+	} {
+		s := b.SummaryValue(col)
+		if s == "" {
+			continue
+		}
+
+		want, err := ParseAmount(s)
+		if err != nil {
+			return corrupt("汇总行%s: %v", col, err)
+		}
+		if want != v {
+			return corrupt("%s为%s, 按明细计算为%s", col, s, formatAmount(v))
+		}
+	}
+
+	return nil
+}
+
+func summarizeFund(b *Bill) ([]FundSummary, error) {
+	days := make(map[string]*FundSummary)
+	var keys []string
+
+	for i := range b.Rows {
+		date := day(b.Value(i, "记账时间"))
+		d := days[date]
+		if d == nil {
+			d = &FundSummary{Date: date}
+			days[date] = d
+			keys = append(keys, date)
+		}
+
+		v, err := b.Amount(i, "收支金额（元）")
+		if err != nil {
+			return nil, corrupt("第%d行收支金额: %v", i+2, err)
+		}
+
+		switch b.Value(i, "收支类型") {
+		case "收入":
+			d.Income += v
+		case "支出":
+			d.Expense += v
+		default:
+			return nil, corrupt("第%d行收支类型未知: %s", i+2, b.Value(i, "收支类型"))
+		}
+	}
+
+	sort.Strings(keys)
+	list := make([]FundSummary, 0, len(keys))
+	for _, k := range keys {
+		d := days[k]
+		d.Net = d.Income - d.Expense
+		list = append(list, *d)
+	}
+
+	return list, nil
+}
+
+// 取时间中的日期部分
+func day(t string) string {
+	if i := strings.IndexByte(t, ' '); i > 0 {
+		return t[:i]
+	}
+
+	return t
+}
+
+func formatAmount(fen int64) string {
+	sign := ""
+	if fen < 0 {
+		sign, fen = "-", -fen
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, fen/100, fen%100)
+}
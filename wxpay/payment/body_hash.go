@@ -0,0 +1,20 @@
+package payment
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+)
+
+// 读取通知原文, 读取的同时计算 SHA-256
+// 摘要按解析前的原始字节计算, 审计系统可以只保存摘要作为防篡改凭证
+func readBody(r io.Reader) (body []byte, sum string, err error) {
+	h := sha256.New()
+	if body, err = ioutil.ReadAll(io.TeeReader(r, h)); err != nil {
+		return
+	}
+
+	sum = hex.EncodeToString(h.Sum(nil))
+	return
+}
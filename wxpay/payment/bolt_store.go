@@ -0,0 +1,305 @@
+//go:build bolt
+// +build bolt
+
+package payment
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// 存储桶
+var (
+	boltOrders      = []byte("orders")
+	boltDeadLetters = []byte("dead_letters")
+	boltOutbox      = []byte("outbox")
+	boltNotify      = []byte("notify")
+	boltSeq         = []byte("seq")
+	boltNos         = []byte("nos")
+)
+
+// BoltStore 基于 bbolt 的嵌入式存储, 适用于单机部署, 不需要 Redis 或数据库
+// 实现 PendingOrderStore、DeadLetterStore、OutboxStore 和 NoStore, 并提供通知去重。
+// 需要使用 -tags bolt 编译; 同一文件只能被一个进程打开
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore 打开或创建存储文件
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltOrders, boltDeadLetters, boltOutbox, boltNotify, boltSeq, boltNos} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Close 关闭存储文件
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStore) put(bucket []byte, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(key), data)
+	})
+}
+
+// 读取记录, 不存在时返回 false
+func (s *BoltStore) get(bucket []byte, key string, v interface{}) (ok bool, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucket).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+
+		ok = true
+		return json.Unmarshal(data, v)
+	})
+
+	return
+}
+
+// 遍历存储桶
+func (s *BoltStore) each(bucket []byte, fn func(data []byte) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(_, data []byte) error {
+			return fn(data)
+		})
+	})
+}
+
+// SaveOrder 保存订单
+func (s *BoltStore) SaveOrder(r OrderRecord) error {
+	return s.put(boltOrders, r.Order.OutTradeNo, r)
+}
+
+// GetOrder 读取订单
+func (s *BoltStore) GetOrder(outTradeNo string) (*OrderRecord, error) {
+	var r OrderRecord
+	ok, err := s.get(boltOrders, outTradeNo, &r)
+	if !ok || err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// PendingOrders 读取未支付的订单
+// 需要遍历全部订单, 订单较多时应定期清理已完成的订单
+func (s *BoltStore) PendingOrders(limit int) ([]OrderRecord, error) {
+	var list []OrderRecord
+	err := s.each(boltOrders, func(data []byte) error {
+		var r OrderRecord
+		if err := json.Unmarshal(data, &r); err != nil {
+			return err
+		}
+		if r.TradeState == TradeStateNotPay {
+			list = append(list, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+
+	return list, nil
+}
+
+// Put 保存死信
+func (s *BoltStore) Put(letter DeadLetter) error {
+	return s.put(boltDeadLetters, letter.ID, letter)
+}
+
+// List 读取全部死信
+func (s *BoltStore) List() ([]DeadLetter, error) {
+	var letters []DeadLetter
+	err := s.each(boltDeadLetters, func(data []byte) error {
+		var letter DeadLetter
+		if err := json.Unmarshal(data, &letter); err != nil {
+			return err
+		}
+		letters = append(letters, letter)
+		return nil
+	})
+
+	return letters, err
+}
+
+// Delete 删除死信
+func (s *BoltStore) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltDeadLetters).Delete([]byte(id))
+	})
+}
+
+// SaveOutbox 保存外发请求记录
+func (s *BoltStore) SaveOutbox(e OutboxEntry) error {
+	return s.put(boltOutbox, e.ID, e)
+}
+
+// GetOutbox 读取外发请求记录
+func (s *BoltStore) GetOutbox(id string) (*OutboxEntry, error) {
+	var e OutboxEntry
+	ok, err := s.get(boltOutbox, id, &e)
+	if !ok || err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// PendingOutbox 读取未确认结果的外发请求记录
+func (s *BoltStore) PendingOutbox() ([]OutboxEntry, error) {
+	var list []OutboxEntry
+	err := s.each(boltOutbox, func(data []byte) error {
+		var e OutboxEntry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		if e.State == OutboxPending {
+			list = append(list, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+
+	return list, nil
+}
+
+// Reserve 为 scope 预留 n 个连续序号并返回第一个
+func (s *BoltStore) Reserve(scope string, n int64) (first int64, err error) {
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltSeq)
+
+		var cur int64
+		if data := b.Get([]byte(scope)); len(data) == 8 {
+			cur = int64(binary.BigEndian.Uint64(data))
+		}
+
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], uint64(cur+n))
+		first = cur + 1
+
+		return b.Put([]byte(scope), buf[:])
+	})
+
+	return
+}
+
+// Lookup 读取业务键对应的单号
+func (s *BoltStore) Lookup(key string) (no string, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		no = string(tx.Bucket(boltNos).Get([]byte(key)))
+		return nil
+	})
+
+	return
+}
+
+// Bind 业务键没有对应单号时记录 no, 返回业务键最终对应的单号
+func (s *BoltStore) Bind(key, no string) (bound string, err error) {
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltNos)
+		if old := b.Get([]byte(key)); old != nil {
+			bound = string(old)
+			return nil
+		}
+
+		bound = no
+		return b.Put([]byte(key), []byte(no))
+	})
+
+	return
+}
+
+// MarkNotify 记录已处理的通知, 首次记录时返回 true
+// 在通知处理函数中以微信订单号或退款单号调用, 返回 false 时直接应答成功
+//
+// @id 通知的唯一标识, 如 transaction_id 或 refund_id
+func (s *BoltStore) MarkNotify(id string) (first bool, err error) {
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltNotify)
+		if b.Get([]byte(id)) != nil {
+			return nil
+		}
+
+		first = true
+		ts, _ := time.Now().MarshalBinary()
+		return b.Put([]byte(id), ts)
+	})
+
+	return
+}
+
+// UnmarkNotify 删除通知记录, 处理失败需要微信重新发送时调用
+func (s *BoltStore) UnmarkNotify(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltNotify).Delete([]byte(id))
+	})
+}
+
+// PruneNotify 删除 before 之前的通知记录, 返回删除的条数
+// 微信在24小时内重发通知, 可以定期清理更早的记录
+func (s *BoltStore) PruneNotify(before time.Time) (n int, err error) {
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltNotify)
+
+		// 遍历时删除会跳过元素, 先收集再删除
+		var keys [][]byte
+		b.ForEach(func(k, v []byte) error {
+			var t time.Time
+			if t.UnmarshalBinary(v) == nil && t.Before(before) {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			return nil
+		})
+
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		n = len(keys)
+		return nil
+	})
+
+	return
+}
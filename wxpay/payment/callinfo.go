@@ -0,0 +1,43 @@
+package payment
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// CallInfo 单次调用的请求信息, 用于审计日志
+type CallInfo struct {
+	URL           string        // 请求地址
+	RequestNonce  string        // 请求使用的 nonce_str
+	ResponseNonce string        // 返回结果中的 nonce_str
+	StatusCode    int           // HTTP 状态码
+	Duration      time.Duration // 请求耗时
+	ServerTime    time.Time     // 应答头 Date 中的微信服务器时间, 精确到秒
+}
+
+// WithCallInfo 调用结束后把请求信息写入 info
+// 请求失败时 info 中已经获取到的字段同样会被填写
+func WithCallInfo(info *CallInfo) CallOption {
+	return func(o *callOptions) {
+		o.info = info
+	}
+}
+
+// WithNonceCheck 校验请求与返回的 nonce_str
+// v2 接口返回的 nonce_str 由微信生成, 并非请求值的回传,
+// 可以在 fn 中做去重等检查, 返回错误时本次调用失败
+func WithNonceCheck(fn func(requestNonce, responseNonce string) error) CallOption {
+	return func(o *callOptions) {
+		o.nonceCheck = fn
+	}
+}
+
+// 读取 XML 数据中的 nonce_str
+func readNonce(data []byte) string {
+	var v struct {
+		NonceStr string `xml:"nonce_str"`
+	}
+	xml.Unmarshal(data, &v)
+
+	return v.NonceStr
+}
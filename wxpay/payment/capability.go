@@ -0,0 +1,31 @@
+package payment
+
+import "github.com/wanghuobo/weapp/payment/types"
+
+// 需要单独开通的产品对应的接口
+// 未列出的接口(下单、查询、关单、交易账单)所有商户都可以调用
+var apiCapabilities = map[string]types.Capability{
+	refundAPI:           types.CapabilityRefund,
+	transferAPI:         types.CapabilityTransfer,
+	transferInfoAPI:     types.CapabilityTransfer,
+	redpackAPI:          types.CapabilityRedpack,
+	downloadFundFlowAPI: types.CapabilityFundFlow,
+}
+
+// ErrCapabilityDisabled 商户未开通对应产品, 见 types.IsCapabilityDisabled
+var ErrCapabilityDisabled = types.ErrCapabilityDisabled
+
+// Enabled 商户是否开通了指定产品, 未配置 Capabilities 时总是返回 true
+func (c *Client) Enabled(capability types.Capability) bool {
+	return c.conf().Capabilities.Enabled(capability)
+}
+
+// 检查接口对应的产品是否已开通
+func (cfg *Config) requireAPI(api string) error {
+	capability, ok := apiCapabilities[api]
+	if !ok {
+		return nil
+	}
+
+	return cfg.Capabilities.Require(capability)
+}
@@ -0,0 +1,107 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// prepay_id 有效期
+const prepayIDTTL = 2 * time.Hour
+
+// CheckoutState 收银台订单状态
+type CheckoutState string
+
+// 收银台订单状态
+const (
+	CheckoutPayable CheckoutState = "PAYABLE" // 可以使用 PrepayID 继续支付
+	CheckoutPaid    CheckoutState = "PAID"    // 已支付, 展示支付结果
+	CheckoutClosed  CheckoutState = "CLOSED"  // 已关闭或支付失败, 需要使用新订单号下单
+	CheckoutExpired CheckoutState = "EXPIRED" // 订单或 prepay_id 已失效, 需要重新下单
+)
+
+// Checkout 收银台订单状态及继续支付需要的信息
+type Checkout struct {
+	State      CheckoutState
+	PrepayID   string // 状态为 CheckoutPayable 时有效
+	TradeState string // 微信交易状态, 见 TradeState 常量
+	Queried    bool   // 是否查询了微信订单, 为 false 时状态来自订单存储
+}
+
+// ResolveCheckoutState 确定收银台订单状态, 收银台页面可以据此直接分支
+// 订单存储中已记录支付成功或关闭(如已处理支付通知)时不查询微信, 否则查询订单并更新订单存储。
+// 需要设置 Config.OrderStore
+func (c *Client) ResolveCheckoutState(ctx context.Context, outTradeNo string) (ck Checkout, err error) {
+	store := c.conf().OrderStore
+	if store == nil {
+		err = errors.New("未设置订单存储")
+		return
+	}
+
+	r, err := store.GetOrder(outTradeNo)
+	if err != nil {
+		return
+	}
+	if r == nil {
+		err = errors.New("订单不存在: " + outTradeNo)
+		return
+	}
+
+	ck.TradeState = r.TradeState
+	if state, ok := checkoutFinal(r.TradeState); ok {
+		ck.State = state
+		return
+	}
+
+	remote, err := c.QueryOrder(ctx, outTradeNo)
+	switch {
+	case ErrCodeOf(err) == "ORDERNOTEXIST":
+		// 下单成功但微信没有订单, prepay_id 无法使用
+		ck.State, err = CheckoutExpired, nil
+		return
+	case err != nil:
+		return
+	}
+
+	ck.Queried = true
+	ck.TradeState = remote.TradeState
+
+	if remote.TradeState != r.TradeState {
+		r.TradeState = remote.TradeState
+		r.UpdatedAt = time.Now()
+		if err = store.SaveOrder(*r); err != nil {
+			return
+		}
+	}
+
+	if state, ok := checkoutFinal(remote.TradeState); ok {
+		ck.State = state
+		return
+	}
+
+	now := time.Now()
+	prepayAt := r.PrepayAt
+	if prepayAt.IsZero() {
+		prepayAt = r.CreatedAt
+	}
+	if r.PrepayID == "" || now.After(orderExpireAt(*r, 0)) || now.After(prepayAt.Add(prepayIDTTL)) {
+		ck.State = CheckoutExpired
+		return
+	}
+
+	ck.State = CheckoutPayable
+	ck.PrepayID = r.PrepayID
+	return
+}
+
+// 不会再变化的交易状态对应的收银台状态
+func checkoutFinal(tradeState string) (CheckoutState, bool) {
+	switch tradeState {
+	case TradeStateSuccess, TradeStateRefund:
+		return CheckoutPaid, true
+	case TradeStateClosed, TradeStateRevoked, TradeStatePayError:
+		return CheckoutClosed, true
+	}
+
+	return "", false
+}
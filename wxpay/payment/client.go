@@ -0,0 +1,796 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wanghuobo/weapp/payment/types"
+	"github.com/wanghuobo/weapp/util"
+)
+
+// Config 支付客户端配置
+type Config struct {
+	AppID     string // 小程序ID
+	MchID     string // 商户号
+	Key       string // 商户支付密钥
+	CertPath  string // 商户证书路径, 退款/转账/红包需要
+	KeyPath   string // 商户证书私钥路径
+	NotifyURL string // 默认支付结果通知地址
+	ClientIP  string // 默认终端IP, 为空时使用本机IP
+
+	// CertPEM 和 KeyPEM 为 PEM 格式的商户证书及私钥, 设置后忽略 CertPath 和 KeyPath,
+	// 证书不需要写入磁盘, 可以从 util.FileSecret、util.VaultSecret 等读取
+	CertPEM []byte
+	KeyPEM  []byte
+
+	// PKCS12 为 PKCS#12 格式的商户证书(apiclient_cert.p12), 未设置 CertPEM 时使用,
+	// PKCS12Path 为其路径, 二者都设置时使用 PKCS12。证书密码默认为商户号
+	PKCS12         []byte
+	PKCS12Path     string
+	PKCS12Password string
+
+	// NotifySecret 通知地址令牌密钥, 设置后按商户订单号在通知地址末尾追加令牌
+	// 处理通知时使用 HandlePaidNotifyWithToken 校验
+	NotifySecret string
+
+	Profile  string        // 运行环境, 默认 ProfileProd
+	BaseURL  string        // 接口地址, 默认按运行环境选择
+	SignType string        // 签名类型, 默认 MD5
+	Timeout  time.Duration // 请求超时时间, 默认10秒
+
+	// Endpoints 候选接口地址, 如境内地址和 BaseURLHK, 第一个为初始地址
+	// 设置后忽略 BaseURL, 使用 ProbeEndpoints 探测延迟后选择最快的地址,
+	// 请求出现网络错误、超时或5xx时切换到下一个地址并一直使用
+	Endpoints []string
+
+	Transport util.TransportOptions // TLS 最低版本、加密套件及 HTTP/2 配置
+
+	UserAgent string      // 请求 User-Agent, 默认 wxpay-go
+	Header    http.Header // 额外请求头, 不会覆盖 Content-Type 等已设置的头
+
+	// AuditSink 资金操作审计, 为空则不记录
+	AuditSink AuditSink
+
+	// Approval 退款和转账审批策略, 为空则不审批
+	Approval *ApprovalPolicy
+
+	// OrderStore 本地订单存储, 设置后下单成功时自动保存订单
+	OrderStore OrderStore
+
+	// Codec 请求和应答的 XML 序列化方式, 默认 XMLCodec
+	// 通知处理函数(HandlePaidNotify 等)不经过客户端, 仍然使用 encoding/xml
+	Codec Codec
+
+	// GoodsTags 设置后下单前校验订单优惠标记, 校验失败时不下单
+	GoodsTags GoodsTagValidator
+
+	// QueryLimit 同一订单每分钟最多查询次数, 默认6次, 为负数时不限制
+	// 超过时 QueryOrder 直接返回 ErrTooFrequent
+	QueryLimit int
+
+	// Quota 按商户、接口和日期统计调用次数, 为空则不统计
+	Quota *QuotaPolicy
+
+	// Capabilities 商户已开通的产品, 为空表示不限制
+	// 调用未开通产品的接口时直接返回 *types.CapabilityError, 不发送请求
+	Capabilities types.Capabilities
+
+	// StrictRefundReason 退款原因必须通过 Refunder.SetReason 设置, 保证退款原因分类一致
+	StrictRefundReason bool
+
+	// AppIDs 同一商户号绑定的其他 APPID(如公众号、APP), 通过 Client.App 为其下单和生成支付参数
+	AppIDs []string
+
+	// OpenIDPrefixes 各 APPID 下 openid 的固定前缀(APPID -> 前缀), 可以取该 APPID 下任一 openid 的前6位
+	// 设置后下单前检查 openid 是否属于订单的 APPID, 不属于时返回 *OpenIDMismatchError
+	OpenIDPrefixes map[string]string
+
+	// TradeNoPolicy Client.Unify 的订单号重复处理策略, 为空时不处理
+	TradeNoPolicy *TradeNoPolicy
+
+	// Policies 按接口类别的超时和重试策略, 未设置的类别不重试, 可以使用 DefaultPolicies()
+	// 创建客户端时复制, 之后修改传入的 map 不影响客户端
+	Policies map[EndpointClass]EndpointPolicy
+}
+
+// Client 支付客户端
+// 调用时 Config 中的配置作为默认值, 可以通过 CallOption 对单次调用进行覆盖
+type Client struct {
+	config    atomic.Value // *Config, Reload 时整体替换
+	http      *http.Client
+	endpoints *endpoints // 多个接口地址的选择, 未配置 Endpoints 时为 nil
+	pacer     apiPacer   // 限频后的请求节流, 见 Pacing
+	queries   queryLimiter
+
+	tlsMu sync.Mutex
+	tls   *http.Client // 双向认证客户端, 首次使用时加载证书, SetCertificate 时替换
+
+	hooks hooks // OnRequest 等注册的钩子
+
+	mu       sync.Mutex
+	closed   bool
+	inflight sync.WaitGroup
+	closers  []func(context.Context) error
+}
+
+// NewClient 新建支付客户端
+func NewClient(cfg Config) (*Client, error) {
+	if err := cfg.normalize(); err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		http:      &http.Client{Transport: util.NewTransport(cfg.Transport)},
+		endpoints: newEndpoints(cfg.Endpoints),
+	}
+	c.config.Store(&cfg)
+
+	return c, nil
+}
+
+// 校验配置并填写默认值
+func (cfg *Config) normalize() error {
+	if cfg.AppID == "" || cfg.MchID == "" || cfg.Key == "" {
+		return errors.New("appid, mch_id 和 key 不能为空")
+	}
+
+	if cfg.Profile == "" {
+		cfg.Profile = ProfileProd
+	}
+
+	if cfg.BaseURL == "" {
+		if cfg.Profile == ProfileMock {
+			return errors.New("mock 环境必须设置 BaseURL")
+		}
+		cfg.BaseURL = profileBaseURL(cfg.Profile)
+	}
+
+	if err := checkProfileURL(cfg.Profile, cfg.BaseURL); err != nil {
+		return err
+	}
+
+	if cfg.SignType == "" {
+		cfg.SignType = SignTypeMD5
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	cfg.Policies = copyPolicies(cfg.Policies)
+
+	for _, u := range cfg.Endpoints {
+		if err := checkProfileURL(cfg.Profile, u); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Config 返回客户端配置
+func (c *Client) Config() Config {
+	return *c.conf()
+}
+
+// 当前配置, 不能修改返回的配置
+func (c *Client) conf() *Config {
+	return c.config.Load().(*Config)
+}
+
+// CallOption 单次调用参数, 覆盖客户端默认配置
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	timeout   time.Duration
+	signType  string
+	baseURL   string
+	notifyURL string
+
+	info       *CallInfo
+	nonceCheck func(requestNonce, responseNonce string) error
+	timeoutSet bool // 通过 WithTimeout 指定了超时时间, 不使用 Config.Policies 中的超时
+	baseURLSet bool // 通过 WithBaseURL 指定了接口地址, 重试时不切换接口地址
+
+	tradeNoPolicy *TradeNoPolicy
+
+	conf *Config // 本次调用使用的配置, 热加载不影响进行中的请求
+}
+
+// WithTimeout 覆盖本次调用的超时时间
+func WithTimeout(d time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.timeout = d
+		o.timeoutSet = true
+	}
+}
+
+// WithSignType 覆盖本次调用的签名类型
+// 仅统一下单和退款支持 HMAC-SHA256, 转账和红包接口固定使用 MD5
+func WithSignType(signType string) CallOption {
+	return func(o *callOptions) {
+		o.signType = signType
+	}
+}
+
+// WithBaseURL 覆盖本次调用的接口地址, 按 Config.Policies 重试时同样使用该地址
+func WithBaseURL(u string) CallOption {
+	return func(o *callOptions) {
+		o.baseURL = u
+		o.baseURLSet = true
+	}
+}
+
+// WithTradeNoPolicy 覆盖本次统一下单的订单号重复处理策略, 为 nil 时不处理
+func WithTradeNoPolicy(p *TradeNoPolicy) CallOption {
+	return func(o *callOptions) {
+		o.tradeNoPolicy = p
+	}
+}
+
+// WithNotifyURL 覆盖本次调用的通知地址
+func WithNotifyURL(u string) CallOption {
+	return func(o *callOptions) {
+		o.notifyURL = u
+	}
+}
+
+// 读取一次配置, 同一次调用的各个字段来自同一份配置
+func (c *Client) options(opts []CallOption) callOptions {
+	cfg := c.conf()
+	o := callOptions{
+		timeout:   cfg.Timeout,
+		signType:  cfg.SignType,
+		baseURL:   cfg.BaseURL,
+		notifyURL: cfg.NotifyURL,
+		info:      new(CallInfo),
+
+		tradeNoPolicy: cfg.TradeNoPolicy,
+		conf:          cfg,
+	}
+	if c.endpoints != nil {
+		o.baseURL = c.endpoints.get()
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+func (c *Client) tlsClient() (*http.Client, error) {
+	c.tlsMu.Lock()
+	defer c.tlsMu.Unlock()
+
+	if c.tls != nil {
+		return c.tls, nil
+	}
+
+	cfg := c.conf()
+	cert, err := cfg.certificate()
+	if err != nil {
+		return nil, err
+	}
+
+	c.tls = &http.Client{Transport: util.NewTransport(cfg.Transport, cert)}
+	return c.tls, nil
+}
+
+// 读取商户证书, 依次使用 CertPEM、PKCS12、PKCS12Path 和 CertPath
+func (cfg *Config) certificate() (tls.Certificate, error) {
+	if len(cfg.CertPEM) > 0 {
+		return tls.X509KeyPair(cfg.CertPEM, cfg.KeyPEM)
+	}
+
+	if len(cfg.PKCS12) > 0 || cfg.PKCS12Path != "" {
+		data := cfg.PKCS12
+		if len(data) == 0 {
+			var err error
+			if data, err = ioutil.ReadFile(cfg.PKCS12Path); err != nil {
+				return tls.Certificate{}, err
+			}
+		}
+
+		return util.LoadPKCS12(data, cfg.pkcs12Password())
+	}
+
+	return tls.LoadX509KeyPair(cfg.CertPath, cfg.KeyPath)
+}
+
+// 是否配置了商户证书
+func (cfg *Config) hasCertificate() bool {
+	return len(cfg.CertPEM) > 0 || len(cfg.PKCS12) > 0 || cfg.PKCS12Path != "" || cfg.CertPath != ""
+}
+
+// PKCS#12 证书密码, 默认为商户号
+func (cfg *Config) pkcs12Password() string {
+	if cfg.PKCS12Password != "" {
+		return cfg.PKCS12Password
+	}
+
+	return cfg.MchID
+}
+
+// SetCertificate 替换商户证书, 之后的退款、转账等请求使用新证书
+// 进行中的请求不受影响, 旧证书的空闲连接会被关闭
+//
+// @certPEM PEM 格式的商户证书
+// @keyPEM PEM 格式的商户证书私钥
+func (c *Client) SetCertificate(certPEM, keyPEM []byte) error {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return err
+	}
+
+	c.setCertificate(cert)
+	return nil
+}
+
+// SetPKCS12 替换为 PKCS#12 格式的商户证书, 见 SetCertificate
+//
+// @password 证书密码, 为空时使用商户号
+func (c *Client) SetPKCS12(data []byte, password string) error {
+	if password == "" {
+		password = c.conf().MchID
+	}
+
+	cert, err := util.LoadPKCS12(data, password)
+	if err != nil {
+		return err
+	}
+
+	c.setCertificate(cert)
+	return nil
+}
+
+func (c *Client) setCertificate(cert tls.Certificate) {
+	cli := &http.Client{Transport: util.NewTransport(c.conf().Transport, cert)}
+
+	c.tlsMu.Lock()
+	old := c.tls
+	c.tls = cli
+	c.tlsMu.Unlock()
+
+	if old != nil {
+		old.CloseIdleConnections()
+	}
+}
+
+// 请求体缓冲, 高并发下单时复用以减少内存分配
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// 使用缓冲的请求体, Transport 关闭请求体时归还缓冲
+type pooledBody struct {
+	*bytes.Reader
+	buf  *bytes.Buffer
+	once sync.Once
+}
+
+func (b *pooledBody) Close() error {
+	b.once.Do(func() { bufferPool.Put(b.buf) })
+	return nil
+}
+
+// 发送一次 XML 请求
+// retryable 表示网络错误、超时或5xx等可以按 Config.Policies 重试的失败
+//
+// @cert 是否使用商户证书
+func (c *Client) send(ctx context.Context, o callOptions, api string, obj interface{}, cert bool) (body []byte, retryable bool, err error) {
+	cfg := o.conf
+	if err = cfg.requireAPI(api); err != nil {
+		return
+	}
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	if err = cfg.encode(buf, obj); err != nil {
+		bufferPool.Put(buf)
+		return
+	}
+	data := buf.Bytes()
+	reqBody := &pooledBody{Reader: bytes.NewReader(data), buf: buf}
+	// 请求发出前返回时归还缓冲, 发出后由 Transport 关闭请求体
+	sent := false
+	defer func() {
+		if !sent {
+			reqBody.Close()
+		}
+	}()
+
+	cli := c.http
+	if cert {
+		if cli, err = c.tlsClient(); err != nil {
+			return
+		}
+	}
+
+	// 限频节流的等待不计入请求超时
+	if err = c.pacer.wait(ctx, api); err != nil {
+		return
+	}
+
+	// 超时仍视为接口地址故障, 调用方取消不算
+	parent := ctx
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	uri := o.baseURL + api
+	if err = checkProfileURL(cfg.Profile, uri); err != nil {
+		return
+	}
+
+	info := o.info
+	*info = CallInfo{URL: uri, RequestNonce: readNonce(data)}
+
+	req, err := http.NewRequest(http.MethodPost, uri, nil)
+	if err != nil {
+		return
+	}
+	req = req.WithContext(ctx)
+	req.Body = reqBody
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	util.SetHeaders(req, cfg.UserAgent, cfg.Header)
+
+	cfg.Quota.record(cfg.MchID, api)
+
+	c.hooks.onRequest(parent, api, data)
+	defer func() {
+		c.hooks.onResponse(parent, api, *info, body, err)
+	}()
+
+	start := time.Now()
+	sent = true
+	res, err := cli.Do(req)
+	if err != nil {
+		info.Duration = time.Since(start)
+		c.endpointFailed(parent, o.baseURL)
+		// 调用方取消时不重试
+		return nil, parent.Err() == nil, err
+	}
+	defer res.Body.Close()
+
+	body, err = ioutil.ReadAll(res.Body)
+	info.Duration = time.Since(start)
+	info.StatusCode = res.StatusCode
+	info.ServerTime, _ = http.ParseTime(res.Header.Get("Date"))
+	if err != nil {
+		return nil, parent.Err() == nil, err
+	}
+
+	if res.StatusCode >= http.StatusInternalServerError {
+		c.endpointFailed(parent, o.baseURL)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, res.StatusCode >= http.StatusInternalServerError, fmt.Errorf("http code error : uri=%v , statusCode=%v", uri, res.StatusCode)
+	}
+
+	c.pacer.observe(api, isFreqLimited(body))
+
+	info.ResponseNonce = readNonce(body)
+	if o.nonceCheck != nil {
+		if err = o.nonceCheck(info.RequestNonce, info.ResponseNonce); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return body, false, nil
+}
+
+// Unify 统一下单
+// 未填写的 AppID, MchID, NotifyURL 使用客户端配置。
+// 设置了 OrderStore 时下单成功后保存订单, 保存失败会返回错误, 但下单结果仍然有效。
+// 设置了 Config.TradeNoPolicy 或 WithTradeNoPolicy 时, 订单号重复按策略生成派生订单号重新下单
+func (c *Client) Unify(ctx context.Context, o Order, opts ...CallOption) (PaidResponse, error) {
+	opt := c.options(opts)
+
+	return opt.tradeNoPolicy.unify(o, func(o Order) (PaidResponse, error) {
+		return c.unify(ctx, o, opt)
+	})
+}
+
+// 统一下单一次
+func (c *Client) unify(ctx context.Context, o Order, opt callOptions) (res PaidResponse, err error) {
+	if err = c.begin(); err != nil {
+		return
+	}
+	defer c.end()
+
+	cfg := opt.conf
+	if o.AppID == "" {
+		o.AppID = cfg.AppID
+	}
+	if o.MchID == "" {
+		o.MchID = cfg.MchID
+	}
+	if o.IP == "" {
+		o.IP = cfg.ClientIP
+	}
+	if o.NotifyURL == "" || opt.notifyURL != cfg.NotifyURL {
+		o.NotifyURL = opt.notifyURL
+	}
+
+	if err = cfg.checkOpenID(o.AppID, o.OpenID); err != nil {
+		return
+	}
+
+	if o.Tag != "" && cfg.GoodsTags != nil {
+		if err = cfg.GoodsTags.ValidateGoodsTag(ctx, o.Tag); err != nil {
+			return
+		}
+	}
+
+	defer func() {
+		c.audit(AuditEvent{
+			Operation:  AuditUnify,
+			AppID:      o.AppID,
+			MchID:      o.MchID,
+			OutTradeNo: o.OutTradeNo,
+			OpenID:     o.OpenID,
+			Amount:     o.TotalFee,
+		}, opt.info, err)
+	}()
+
+	if cfg.NotifySecret != "" {
+		if o.NotifyURL, err = SignNotifyURL(o.NotifyURL, cfg.NotifySecret, o.OutTradeNo); err != nil {
+			return
+		}
+	}
+
+	reqData, err := o.prepare(cfg.Key, opt.signType)
+	if err != nil {
+		return
+	}
+
+	data, err := c.post(ctx, opt, unifyAPI, reqData, false)
+	if err != nil {
+		return
+	}
+
+	if res, err = parsePaidResponse(cfg.codec(), data, o); err != nil {
+		err = openIDMismatch(err, o.AppID, o.OpenID)
+		return
+	}
+
+	err = saveOrder(cfg.OrderStore, o, res)
+	return
+}
+
+// Refund 申请退款
+func (c *Client) Refund(ctx context.Context, r Refunder, opts ...CallOption) (res RefundedResponse, err error) {
+	if err = c.begin(); err != nil {
+		return
+	}
+	defer c.end()
+
+	opt := c.options(opts)
+	cfg := opt.conf
+	if r.AppID == "" {
+		r.AppID = cfg.AppID
+	}
+	if r.MchID == "" {
+		r.MchID = cfg.MchID
+	}
+	if opt.notifyURL != cfg.NotifyURL {
+		r.NotifyURL = opt.notifyURL
+	}
+
+	defer func() {
+		c.audit(AuditEvent{
+			Operation:     AuditRefund,
+			AppID:         r.AppID,
+			MchID:         r.MchID,
+			OutTradeNo:    r.OutTradeNo,
+			OutRefundNo:   r.OutRefundNo,
+			TransactionID: res.RefundID,
+			Amount:        r.RefundFee,
+		}, opt.info, err)
+	}()
+
+	// 未开通时不进入审批
+	if err = cfg.requireAPI(refundAPI); err != nil {
+		return
+	}
+	if err = checkRefundDesc(r.RefundDesc, cfg.StrictRefundReason); err != nil {
+		return
+	}
+
+	err = cfg.Approval.approve(ctx, ApprovalRequest{
+		Operation:   AuditRefund,
+		AppID:       r.AppID,
+		MchID:       r.MchID,
+		OutTradeNo:  r.OutTradeNo,
+		OutRefundNo: r.OutRefundNo,
+		Amount:      r.RefundFee,
+		Desc:        r.RefundDesc,
+	})
+	if err != nil {
+		return
+	}
+
+	// 令牌按商户订单号生成, 只传微信订单号时无法在通知中校验
+	if r.NotifyURL != "" && r.OutTradeNo != "" && cfg.NotifySecret != "" {
+		if r.NotifyURL, err = SignNotifyURL(r.NotifyURL, cfg.NotifySecret, r.OutTradeNo); err != nil {
+			return
+		}
+	}
+
+	reqData, err := r.prepare(cfg.Key, opt.signType)
+	if err != nil {
+		return
+	}
+
+	data, err := c.post(ctx, opt, refundAPI, reqData, true)
+	if err != nil {
+		return
+	}
+
+	return parseRefundedResponse(cfg.codec(), data, r)
+}
+
+// Transfer 企业付款到零钱
+func (c *Client) Transfer(ctx context.Context, t Transferer, opts ...CallOption) (res TransferResponse, err error) {
+	if err = c.begin(); err != nil {
+		return
+	}
+	defer c.end()
+
+	opt := c.options(opts)
+	cfg := opt.conf
+	if t.AppID == "" {
+		t.AppID = cfg.AppID
+	}
+	if t.MchID == "" {
+		t.MchID = cfg.MchID
+	}
+	if t.IP == "" {
+		t.IP = cfg.ClientIP
+	}
+
+	defer func() {
+		c.audit(AuditEvent{
+			Operation:     AuditTransfer,
+			AppID:         t.AppID,
+			MchID:         t.MchID,
+			OutTradeNo:    t.OutTradeNo,
+			TransactionID: res.TransactionID,
+			OpenID:        t.ToUser,
+			Amount:        t.Amount,
+		}, opt.info, err)
+	}()
+
+	// 未开通时不进入审批
+	if err = cfg.requireAPI(transferAPI); err != nil {
+		return
+	}
+
+	err = cfg.Approval.approve(ctx, ApprovalRequest{
+		Operation:  AuditTransfer,
+		AppID:      t.AppID,
+		MchID:      t.MchID,
+		OutTradeNo: t.OutTradeNo,
+		OpenID:     t.ToUser,
+		Amount:     t.Amount,
+		Desc:       t.Desc,
+	})
+	if err != nil {
+		return
+	}
+
+	reqData, err := t.prepare(cfg.Key)
+	if err != nil {
+		return
+	}
+
+	data, err := c.post(ctx, opt, transferAPI, reqData, true)
+	if err != nil {
+		return
+	}
+
+	return parseTransferResponse(cfg.codec(), data, t.AppID, t.MchID)
+}
+
+// TransferInfo 查询企业付款
+func (c *Client) TransferInfo(ctx context.Context, t TransferInfo, opts ...CallOption) (res TransferInfoResponse, err error) {
+	if err = c.begin(); err != nil {
+		return
+	}
+	defer c.end()
+
+	opt := c.options(opts)
+	cfg := opt.conf
+	if t.AppID == "" {
+		t.AppID = cfg.AppID
+	}
+	if t.MchID == "" {
+		t.MchID = cfg.MchID
+	}
+
+	reqData, err := t.prepare(cfg.Key)
+	if err != nil {
+		return
+	}
+
+	data, err := c.post(ctx, opt, transferInfoAPI, reqData, true)
+	if err != nil {
+		return
+	}
+
+	return parseTransferInfoResponse(cfg.codec(), data, t.AppID, t.MchID)
+}
+
+// SendRedpack 发放现金红包
+func (c *Client) SendRedpack(ctx context.Context, r Redpacker, opts ...CallOption) (res RedpackResponse, err error) {
+	if err = c.begin(); err != nil {
+		return
+	}
+	defer c.end()
+
+	opt := c.options(opts)
+	cfg := opt.conf
+	if r.AppID == "" {
+		r.AppID = cfg.AppID
+	}
+	if r.MchID == "" {
+		r.MchID = cfg.MchID
+	}
+	if r.IP == "" {
+		r.IP = cfg.ClientIP
+	}
+
+	defer func() {
+		c.audit(AuditEvent{
+			Operation:     AuditRedpack,
+			AppID:         r.AppID,
+			MchID:         r.MchID,
+			OutTradeNo:    r.BillNo,
+			TransactionID: res.ListID,
+			OpenID:        r.ToUser,
+			Amount:        r.Amount,
+		}, opt.info, err)
+	}()
+
+	if err = r.Validate(); err != nil {
+		return
+	}
+
+	if r.Guard != nil {
+		var cancel func()
+		if cancel, err = r.Guard.Reserve(r.ToUser); err != nil {
+			return
+		}
+		defer func() {
+			if err != nil {
+				cancel()
+			}
+		}()
+	}
+
+	reqData, err := r.prepare(cfg.Key)
+	if err != nil {
+		return
+	}
+
+	data, err := c.post(ctx, opt, redpackAPI, reqData, true)
+	if err != nil {
+		return
+	}
+
+	res, err = parseRedpackResponse(cfg.codec(), data, r.AppID, r.MchID)
+	return
+}
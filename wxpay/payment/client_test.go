@@ -0,0 +1,276 @@
+package payment
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+// 模拟统一下单接口, 读取请求后返回固定的成功应答
+// check 不为 nil 时检查请求字段
+func newUnifyMock(tb testing.TB, check func(raw map[string]string)) *httptest.Server {
+	params := map[string]string{
+		"return_code": "SUCCESS",
+		"result_code": "SUCCESS",
+		"appid":       "wxd930ea5d5a258f4f",
+		"mch_id":      "10000100",
+		"nonce_str":   testNonce,
+		"trade_type":  "JSAPI",
+		"prepay_id":   "wx201410272009395522657a690389285100",
+	}
+	sign, err := util.SignByMD5(params, testKey)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	body := "<xml>"
+	for k, v := range params {
+		body += "<" + k + ">" + v + "</" + k + ">"
+	}
+	body += "<sign>" + sign + "</sign></xml>"
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		data, _ := ioutil.ReadAll(req.Body)
+		if check != nil {
+			raw, err := parseRawFields(data)
+			if err != nil {
+				tb.Error(err)
+			}
+			check(raw)
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		io.WriteString(w, body)
+	}))
+}
+
+// 并发统一下单, 分配统计包含模拟服务端
+func BenchmarkUnify(b *testing.B) {
+	srv := newUnifyMock(b, nil)
+	defer srv.Close()
+
+	c, err := NewClient(Config{
+		AppID:     "wxd930ea5d5a258f4f",
+		MchID:     "10000100",
+		Key:       testKey,
+		Profile:   ProfileMock,
+		BaseURL:   srv.URL,
+		NotifyURL: "https://example.com/notify",
+		ClientIP:  "123.12.12.123",
+		Transport: util.TransportOptions{MaxIdleConnsPerHost: 256},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var seq int64
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			o := testOrder()
+			o.OutTradeNo = "B" + strconv.FormatInt(atomic.AddInt64(&seq, 1), 10)
+
+			if _, err := c.Unify(ctx, o); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func TestPostRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int // 依次返回的状态码, 之后返回200
+		attempts int32
+		retries  int
+		fail     bool
+	}{
+		{"success", nil, 1, 0, false},
+		{"retry 5xx", []int{http.StatusBadGateway, http.StatusServiceUnavailable}, 3, 2, false},
+		{"give up", []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError}, 3, 2, true},
+		{"no retry 4xx", []int{http.StatusBadRequest}, 1, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var n int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				i := atomic.AddInt32(&n, 1) - 1
+				if int(i) < len(tt.statuses) {
+					w.WriteHeader(tt.statuses[i])
+					return
+				}
+				io.WriteString(w, "<xml><return_code>SUCCESS</return_code></xml>")
+			}))
+			defer srv.Close()
+
+			c, err := NewClient(Config{
+				AppID:   "wxd930ea5d5a258f4f",
+				MchID:   "10000100",
+				Key:     testKey,
+				Profile: ProfileMock,
+				BaseURL: srv.URL,
+				Policies: map[EndpointClass]EndpointPolicy{
+					ClassQuery: {Retry: RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var retries int
+			c.OnRetry(func(ctx context.Context, api string, attempt int, err error) {
+				retries++
+				if attempt != retries+1 || err == nil {
+					t.Errorf("OnRetry attempt = %d, err = %v", attempt, err)
+				}
+			})
+
+			_, err = c.post(context.Background(), c.options(nil), orderQueryAPI, fields{{name: "appid", value: "wxd930ea5d5a258f4f"}}, false)
+			if (err != nil) != tt.fail {
+				t.Fatalf("err = %v", err)
+			}
+			if _, ok := err.(permanentError); ok {
+				t.Fatalf("err 不应为 permanentError: %v", err)
+			}
+			if n != tt.attempts || retries != tt.retries {
+				t.Fatalf("attempts = %d, retries = %d, want %d, %d", n, retries, tt.attempts, tt.retries)
+			}
+		})
+	}
+}
+
+// WithBaseURL 指定的地址重试时不切换到候选地址
+func TestPostRetryPinnedBaseURL(t *testing.T) {
+	var pinned, other int32
+	fail := func(n *int32) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(n, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+	}
+	a, b, p := fail(&other), fail(&other), fail(&pinned)
+	defer a.Close()
+	defer b.Close()
+	defer p.Close()
+
+	c, err := NewClient(Config{
+		AppID:     "wxd930ea5d5a258f4f",
+		MchID:     "10000100",
+		Key:       testKey,
+		Profile:   ProfileMock,
+		BaseURL:   a.URL,
+		Endpoints: []string{a.URL, b.URL},
+		Policies: map[EndpointClass]EndpointPolicy{
+			ClassQuery: {Retry: RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.post(context.Background(), c.options([]CallOption{WithBaseURL(p.URL)}), orderQueryAPI, fields{{name: "appid", value: "wxd930ea5d5a258f4f"}}, false)
+	if err == nil {
+		t.Fatal("post 应返回错误")
+	}
+	if pinned != 3 || other != 0 {
+		t.Fatalf("pinned = %d, other = %d, want 3, 0", pinned, other)
+	}
+}
+
+func TestPoliciesCopied(t *testing.T) {
+	p := DefaultPolicies()
+	p[ClassMoney] = EndpointPolicy{Retry: RetryPolicy{MaxAttempts: 5}}
+	if _, ok := DefaultPolicies()[ClassMoney]; ok {
+		t.Fatal("修改 DefaultPolicies() 的返回值影响了默认策略")
+	}
+
+	c, err := NewClient(Config{AppID: "wx", MchID: "1", Key: "k", Policies: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(p, ClassMoney)
+	if _, ok := c.conf().Policies[ClassMoney]; !ok {
+		t.Fatal("修改传入的 Policies 影响了客户端")
+	}
+}
+
+// 校验优惠标记时执行 fn, 用于在下单过程中热加载配置
+type goodsTagHook func()
+
+func (f goodsTagHook) ValidateGoodsTag(ctx context.Context, tag string) error {
+	f()
+	return nil
+}
+
+// 下单过程中热加载配置, 每个请求的通知地址、终端IP和签名来自同一份配置
+func TestReloadDuringUnify(t *testing.T) {
+	srv := newUnifyMock(t, func(raw map[string]string) {
+		s := strings.TrimPrefix(raw["notify_url"], "https://example.com/notify/")
+		if raw["spbill_create_ip"] != "10.0.0."+s {
+			t.Errorf("notify_url = %s, spbill_create_ip = %s", raw["notify_url"], raw["spbill_create_ip"])
+		}
+		if err := verifySign(raw, testKey+s); err != nil {
+			t.Errorf("notify_url = %s: %v", raw["notify_url"], err)
+		}
+	})
+	defer srv.Close()
+
+	var c *Client
+	var gen int32
+	var reload goodsTagHook
+	conf := func(n int32) Config {
+		s := strconv.Itoa(int(n))
+		return Config{
+			AppID:     "wxd930ea5d5a258f4f",
+			MchID:     "10000100",
+			Key:       testKey + s,
+			Profile:   ProfileMock,
+			BaseURL:   srv.URL,
+			NotifyURL: "https://example.com/notify/" + s,
+			ClientIP:  "10.0.0." + s,
+			GoodsTags: reload,
+		}
+	}
+	reload = func() {
+		if err := c.Reload(conf(atomic.AddInt32(&gen, 1))); err != nil {
+			t.Error(err)
+		}
+	}
+
+	c, err := NewClient(conf(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				o := testOrder()
+				o.IP, o.NotifyURL, o.Tag = "", "", "reload"
+				o.OutTradeNo = "R" + strconv.Itoa(i*100+j)
+				if _, err := c.Unify(context.Background(), o); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
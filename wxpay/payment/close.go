@@ -0,0 +1,83 @@
+package payment
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrClientClosed 客户端已关闭
+var ErrClientClosed = errors.New("支付客户端已关闭")
+
+// 开始一次调用, 客户端关闭后返回 ErrClientClosed
+func (c *Client) begin() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrClientClosed
+	}
+	c.inflight.Add(1)
+
+	return nil
+}
+
+// 结束一次调用
+func (c *Client) end() {
+	c.inflight.Done()
+}
+
+// OnClose 注册客户端关闭时执行的函数
+// 证书刷新、定时任务等后台任务通过该方法注册停止逻辑, 按注册的相反顺序执行
+func (c *Client) OnClose(fn func(context.Context) error) {
+	c.mu.Lock()
+	c.closers = append(c.closers, fn)
+	c.mu.Unlock()
+}
+
+// Close 关闭客户端
+// 拒绝新的调用, 等待进行中的调用结束后停止后台任务并刷新审计记录。
+// ctx 超时后不再等待进行中的调用, 但仍会执行后续的关闭步骤并返回 ctx 的错误
+func (c *Client) Close(ctx context.Context) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	closers := c.closers
+	c.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.inflight.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	for i := len(closers) - 1; i >= 0; i-- {
+		if e := closers[i](ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	if f, ok := c.conf().AuditSink.(interface{ Flush() error }); ok {
+		if e := f.Flush(); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	c.http.CloseIdleConnections()
+	c.tlsMu.Lock()
+	if c.tls != nil {
+		c.tls.CloseIdleConnections()
+	}
+	c.tlsMu.Unlock()
+
+	return err
+}
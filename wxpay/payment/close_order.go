@@ -0,0 +1,210 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+const closeOrderAPI = "/pay/closeorder"
+
+// 关闭订单请求
+type closeOrder struct {
+	AppID      string `sign:"appid"`
+	MchID      string `sign:"mch_id"`
+	OutTradeNo string `sign:"out_trade_no"`
+}
+
+type closeOrderResponse struct {
+	response
+	AppID string `xml:"appid"`
+	MchID string `xml:"mch_id"`
+}
+
+// CloseOrder 关闭订单
+// 订单生成后不能马上调用关单接口, 最短调用时间间隔为5分钟。
+// 订单已关闭时返回 ORDERCLOSED 错误, 已支付时返回 ORDERPAID 错误, 可以通过 ErrCodeOf 判断
+func (c *Client) CloseOrder(ctx context.Context, outTradeNo string, opts ...CallOption) (err error) {
+	if err = c.begin(); err != nil {
+		return
+	}
+	defer c.end()
+
+	opt := c.options(opts)
+	cfg := opt.conf
+	q := closeOrder{AppID: cfg.AppID, MchID: cfg.MchID, OutTradeNo: outTradeNo}
+	reqData, err := signedFields(q, cfg.Key, opt.signType)
+	if err != nil {
+		return
+	}
+
+	data, err := c.post(ctx, opt, closeOrderAPI, reqData, false)
+	if err != nil {
+		return
+	}
+
+	var res closeOrderResponse
+	if err = cfg.codec().Unmarshal(data, &res); err != nil {
+		return
+	}
+
+	if err = res.Check(); err != nil {
+		return
+	}
+
+	return checkEcho(q.AppID, q.MchID, res.AppID, res.MchID)
+}
+
+// CloseWorker 超时订单自动关闭
+// 定期从订单存储中读取未支付的订单, 关闭超过 time_expire 的订单并更新本地状态。
+// 订单已关闭(ORDERCLOSED)视为关闭成功, 已支付(ORDERPAID)时更新为支付成功
+type CloseWorker struct {
+	Client *Client
+	Store  PendingOrderStore // 为空时使用客户端配置的 OrderStore
+
+	Interval      time.Duration // 扫描间隔, 默认1分钟
+	Jitter        time.Duration // 扫描间隔随机抖动, 默认为 Interval 的五分之一, 避免多实例同时扫描
+	Grace         time.Duration // 超过失效时间多久后关闭, 默认1分钟, 避免与用户支付同时发生
+	DefaultExpiry time.Duration // 订单未设置 time_expire 时的有效期, 默认2小时(与微信一致)
+	BatchSize     int           // 每次扫描读取的订单数, 默认100
+
+	// OnError 关闭失败时调用, 为空则忽略, 下次扫描时重试
+	OnError func(OrderRecord, error)
+
+	loop util.Loop
+}
+
+// Run 持续扫描并关闭超时订单, 直到 ctx 取消, 实现 util.Runner
+func (w *CloseWorker) Run(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	jitter := w.Jitter
+	if jitter <= 0 {
+		jitter = interval / 5
+	}
+	w.loop.Interval, w.loop.Jitter = interval, jitter
+
+	return w.loop.Run(ctx, func(ctx context.Context) error {
+		_, err := w.RunOnce(ctx)
+		return err
+	}, func(err error) {
+		if w.OnError != nil {
+			w.OnError(OrderRecord{}, err)
+		}
+	})
+}
+
+// Report 运行情况, 每次扫描计为一次执行
+func (w *CloseWorker) Report() util.RunReport {
+	return w.loop.Report()
+}
+
+// RunOnce 扫描一次并关闭超时订单, 返回关闭的订单数
+func (w *CloseWorker) RunOnce(ctx context.Context) (closed int, err error) {
+	store, err := w.store()
+	if err != nil {
+		return
+	}
+
+	size := w.BatchSize
+	if size <= 0 {
+		size = 100
+	}
+
+	deadline := time.Now().Add(-w.grace())
+	records, err := store.PendingOrders(size)
+	if err != nil {
+		return
+	}
+
+	for _, r := range records {
+		if ctx.Err() != nil {
+			return closed, ctx.Err()
+		}
+
+		if w.expireAt(r).After(deadline) {
+			continue
+		}
+
+		state := TradeStateClosed
+		if e := w.Client.CloseOrder(ctx, r.Order.OutTradeNo); e != nil {
+			switch ErrCodeOf(e) {
+			case "ORDERCLOSED":
+			case "ORDERPAID":
+				state = TradeStateSuccess
+			default:
+				if w.OnError != nil {
+					w.OnError(r, e)
+				}
+				continue
+			}
+		}
+
+		r.TradeState = state
+		r.UpdatedAt = time.Now()
+		if e := store.SaveOrder(r); e != nil {
+			if w.OnError != nil {
+				w.OnError(r, e)
+			}
+			continue
+		}
+
+		if state == TradeStateClosed {
+			closed++
+		}
+	}
+
+	return
+}
+
+func (w *CloseWorker) store() (PendingOrderStore, error) {
+	if w.Store != nil {
+		return w.Store, nil
+	}
+
+	if s, ok := w.Client.conf().OrderStore.(PendingOrderStore); ok {
+		return s, nil
+	}
+
+	return nil, errors.New("订单存储不支持读取未支付订单")
+}
+
+func (w *CloseWorker) grace() time.Duration {
+	if w.Grace <= 0 {
+		return time.Minute
+	}
+
+	return w.Grace
+}
+
+// 订单失效时间
+func (w *CloseWorker) expireAt(r OrderRecord) time.Time {
+	return orderExpireAt(r, w.DefaultExpiry)
+}
+
+// 订单失效时间
+// 未设置 time_expire 和有效期时使用 def, def 为0时为2小时(与微信一致)
+func orderExpireAt(r OrderRecord, def time.Duration) time.Time {
+	if !r.Order.ExpiredAt.IsZero() {
+		return r.Order.ExpiredAt
+	}
+
+	expiry := r.Order.ExpireIn
+	if expiry <= 0 {
+		expiry = def
+	}
+	if expiry <= 0 {
+		expiry = 2 * time.Hour
+	}
+
+	start := r.Order.StartedAt
+	if start.IsZero() {
+		start = r.CreatedAt
+	}
+
+	return start.Add(expiry)
+}
@@ -0,0 +1,52 @@
+package payment
+
+import (
+	"bytes"
+	"encoding/xml"
+)
+
+// Codec 请求和应答的 XML 序列化方式
+// 可以替换为更快的 XML 实现, 或在序列化时加解密个别参数。
+// 请求数据为实现了 xml.Marshaler 的参数列表, 应答数据为带 xml 标签的结构体指针
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// XMLCodec 使用 encoding/xml, 客户端默认使用
+var XMLCodec Codec = xmlCodec{}
+
+type xmlCodec struct{}
+
+func (xmlCodec) Marshal(v interface{}) ([]byte, error) {
+	return xml.Marshal(v)
+}
+
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
+}
+
+// 客户端使用的序列化方式
+func (cfg *Config) codec() Codec {
+	if cfg.Codec != nil {
+		return cfg.Codec
+	}
+
+	return XMLCodec
+}
+
+// 把请求数据编码到 buf
+// 未设置 Codec 时直接写入 buf, 避免额外的内存分配
+func (cfg *Config) encode(buf *bytes.Buffer, v interface{}) error {
+	if cfg.Codec == nil {
+		return xml.NewEncoder(buf).Encode(v)
+	}
+
+	b, err := cfg.Codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = buf.Write(b)
+	return err
+}
@@ -0,0 +1,63 @@
+package payment
+
+// 优惠券类型
+const (
+	CouponTypeCash   = "CASH"    // 充值代金券, 商户预充值, 计入应结订单金额
+	CouponTypeNoCash = "NO_CASH" // 免充值优惠券, 不计入应结订单金额
+)
+
+// CashPaid 用户现金支付金额(分)
+func (ntf PaidNotify) CashPaid() int {
+	return int(fen(ntf.CashFee))
+}
+
+// CouponTotal 优惠券总金额(分), 包括充值代金券和免充值券
+func (ntf PaidNotify) CouponTotal() int {
+	return int(fen(ntf.CouponFee))
+}
+
+// NoCashCouponTotal 免充值券金额(分)
+// 未返回券类型时(未开通免充值券功能)全部按充值代金券计算, 返回0
+func (ntf PaidNotify) NoCashCouponTotal() (sum int) {
+	for _, c := range ntf.Coupons {
+		if c.CouponType == CouponTypeNoCash {
+			sum += int(c.CouponFee)
+		}
+	}
+
+	return
+}
+
+// SettlementExpected 按优惠券计算的应结订单金额(分)
+// 应结订单金额 = 订单金额 - 免充值券金额, 充值代金券由商户预充值, 仍计入结算
+func (ntf PaidNotify) SettlementExpected() int {
+	return ntf.TotalFee - ntf.NoCashCouponTotal()
+}
+
+// SettlementTotal 通知中的应结订单金额(分)
+// 微信只在使用免充值券时返回 settlement_total_fee, 未返回时等于订单金额
+func (ntf PaidNotify) SettlementTotal() int {
+	if ntf.Settlement == 0 {
+		return ntf.TotalFee
+	}
+
+	return int(fen(ntf.Settlement))
+}
+
+// Consistent 通知中的金额是否自洽
+// 现金支付金额 + 优惠券金额 = 订单金额, 各优惠券金额之和 = 优惠券金额,
+// 返回券类型时应结订单金额 = 订单金额 - 免充值券金额。不一致的详情见 CheckAmounts
+func (ntf PaidNotify) Consistent() bool {
+	return len(CheckAmounts(ntf, 0)) == 0
+}
+
+// 是否返回了券类型
+func (ntf PaidNotify) couponTyped() bool {
+	for _, c := range ntf.Coupons {
+		if c.CouponType != "" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,476 @@
+package payment
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 通知类型
+const (
+	NotifyPaid     = "paid"     // 支付结果通知
+	NotifyRefunded = "refunded" // 退款结果通知
+)
+
+// DeadLetter 处理失败的通知
+type DeadLetter struct {
+	ID       string    `json:"id"`       // 通知内容的 SHA256, 同一通知重复失败时保持不变
+	Kind     string    `json:"kind"`     // 通知类型: NotifyPaid | NotifyRefunded
+	Path     string    `json:"path"`     // 通知请求路径, 包含通知地址令牌
+	Body     []byte    `json:"body"`     // 原始通知内容
+	Error    string    `json:"error"`    // 最后一次失败原因
+	Attempts int       `json:"attempts"` // 失败次数
+	Time     time.Time `json:"time"`
+}
+
+// DeadLetterSink 保存处理失败的通知, 供之后重放
+type DeadLetterSink interface {
+	Put(DeadLetter) error
+}
+
+// DeadLetterStore 可读取和删除的死信存储, 重放时使用
+type DeadLetterStore interface {
+	DeadLetterSink
+	List() ([]DeadLetter, error)
+	Delete(id string) error
+}
+
+// NotifyStats 通知处理计数
+type NotifyStats struct {
+	Received       int64 // 收到的通知
+	Failed         int64 // 解析或校验失败
+	CallbackFailed int64 // 处理函数返回失败
+	DeadLettered   int64 // 写入死信
+	SinkErrors     int64 // 写入死信失败
+	Shed           int64 // 处理饱和时直接应答失败
+	Dropped        int64 // 超过 RejectLimit 未写入死信的校验失败通知
+}
+
+// NotifyGuard 通知处理保护
+// 通知解析/校验失败时立即写入死信; 处理函数对同一通知连续失败 MaxFailures 次后写入死信。
+type NotifyGuard struct {
+	Sink        DeadLetterSink
+	MaxFailures int // 同一通知处理失败多少次后写入死信, 默认3次
+
+	// Limiter 并发限制, 饱和时直接应答 FAIL 并返回 ErrNotifyBusy, 不写入死信
+	Limiter *NotifyLimiter
+
+	// RejectLimit 每分钟最多写入死信的解析/校验失败通知数, 默认60
+	// 这类通知可能是伪造的请求, 超出的只计入 Dropped, 防止占满死信存储
+	RejectLimit int
+
+	// MaxTracked 最多记录多少个通知的处理失败次数, 默认1024
+	// 超出时淘汰最早开始失败的记录, 处理成功或写入死信后删除记录
+	MaxTracked int
+
+	stats    NotifyStats
+	mu       sync.Mutex
+	failures map[string]failure
+
+	rejectStart time.Time // 当前计数周期的开始时间
+	rejected    int       // 当前周期已写入死信的校验失败通知数
+}
+
+// 通知处理失败记录
+type failure struct {
+	count int
+	first time.Time
+}
+
+// NewNotifyGuard 新建通知处理保护
+func NewNotifyGuard(sink DeadLetterSink) *NotifyGuard {
+	return &NotifyGuard{Sink: sink}
+}
+
+// Stats 返回通知处理计数
+func (g *NotifyGuard) Stats() NotifyStats {
+	return NotifyStats{
+		Received:       atomic.LoadInt64(&g.stats.Received),
+		Failed:         atomic.LoadInt64(&g.stats.Failed),
+		CallbackFailed: atomic.LoadInt64(&g.stats.CallbackFailed),
+		DeadLettered:   atomic.LoadInt64(&g.stats.DeadLettered),
+		SinkErrors:     atomic.LoadInt64(&g.stats.SinkErrors),
+		Shed:           atomic.LoadInt64(&g.stats.Shed),
+		Dropped:        atomic.LoadInt64(&g.stats.Dropped),
+	}
+}
+
+// HandlePaidNotify 处理支付结果通知
+// 签名校验失败时应答 FAIL, 不调用处理函数, 并作为校验失败写入死信
+//
+// @key 微信支付 KEY
+func (g *NotifyGuard) HandlePaidNotify(res http.ResponseWriter, req *http.Request, key string, fn func(PaidNotify) (bool, string)) error {
+	if !g.acquire(req) {
+		return writeBusy(res)
+	}
+	defer g.release()
+
+	return g.handle(NotifyPaid, req, func(cb func(bool, string) (bool, string)) error {
+		if err := verifyPaidRequest(req, key); err != nil {
+			if werr := writeMuxReplay(res, newReplay(false, err.Error())); werr != nil {
+				return werr
+			}
+			return err
+		}
+
+		return HandlePaidNotify(res, req, func(ntf PaidNotify) (bool, string) {
+			return cb(fn(ntf))
+		})
+	})
+}
+
+// HandleRefundedNotify 处理退款结果通知
+func (g *NotifyGuard) HandleRefundedNotify(res http.ResponseWriter, req *http.Request, key string, fn func(RefundedNotify) (bool, string)) error {
+	if !g.acquire(req) {
+		return writeBusy(res)
+	}
+	defer g.release()
+
+	return g.handle(NotifyRefunded, req, func(cb func(bool, string) (bool, string)) error {
+		return HandleRefundedNotify(res, req, key, func(ntf RefundedNotify) (bool, string) {
+			return cb(fn(ntf))
+		})
+	})
+}
+
+// 读取并保留通知内容, 根据处理结果计数及写入死信
+//
+// @handle 实际的通知处理, cb 用于获取处理函数的返回值
+func (g *NotifyGuard) handle(kind string, req *http.Request, handle func(cb func(bool, string) (bool, string)) error) error {
+	atomic.AddInt64(&g.stats.Received, 1)
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	sum := sha256.Sum256(body)
+	letter := DeadLetter{
+		ID:   hex.EncodeToString(sum[:]),
+		Kind: kind,
+		Path: req.URL.Path,
+		Body: body,
+	}
+
+	err = handle(func(ok bool, msg string) (bool, string) {
+		if ok {
+			g.reset(letter.ID)
+			return ok, msg
+		}
+
+		atomic.AddInt64(&g.stats.CallbackFailed, 1)
+		if n := g.fail(letter.ID); n >= g.maxFailures() {
+			letter.Attempts = n
+			letter.Error = msg
+			g.put(letter)
+			g.reset(letter.ID)
+		}
+
+		return ok, msg
+	})
+
+	if err != nil {
+		atomic.AddInt64(&g.stats.Failed, 1)
+		if !g.allowReject() {
+			atomic.AddInt64(&g.stats.Dropped, 1)
+			return err
+		}
+
+		letter.Attempts = 1
+		letter.Error = err.Error()
+		g.put(letter)
+	}
+
+	return err
+}
+
+// 校验失败的通知是否还可以写入死信, 按分钟计数
+func (g *NotifyGuard) allowReject() bool {
+	limit := g.RejectLimit
+	if limit <= 0 {
+		limit = 60
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(g.rejectStart) >= time.Minute {
+		g.rejectStart = now
+		g.rejected = 0
+	}
+
+	if g.rejected >= limit {
+		return false
+	}
+	g.rejected++
+
+	return true
+}
+
+func (g *NotifyGuard) acquire(req *http.Request) bool {
+	if g.Limiter == nil {
+		return true
+	}
+
+	if !g.Limiter.acquire(req) {
+		atomic.AddInt64(&g.stats.Shed, 1)
+		return false
+	}
+
+	return true
+}
+
+func (g *NotifyGuard) release() {
+	if g.Limiter != nil {
+		g.Limiter.release()
+	}
+}
+
+func (g *NotifyGuard) maxFailures() int {
+	if g.MaxFailures <= 0 {
+		return 3
+	}
+
+	return g.MaxFailures
+}
+
+func (g *NotifyGuard) maxTracked() int {
+	if g.MaxTracked <= 0 {
+		return 1024
+	}
+
+	return g.MaxTracked
+}
+
+func (g *NotifyGuard) fail(id string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.failures == nil {
+		g.failures = make(map[string]failure)
+	}
+
+	f, ok := g.failures[id]
+	if !ok {
+		// 记录已满时淘汰最早开始失败的通知
+		if len(g.failures) >= g.maxTracked() {
+			var oldest string
+			for k, v := range g.failures {
+				if oldest == "" || v.first.Before(g.failures[oldest].first) {
+					oldest = k
+				}
+			}
+			delete(g.failures, oldest)
+		}
+		f.first = time.Now()
+	}
+	f.count++
+	g.failures[id] = f
+
+	return f.count
+}
+
+func (g *NotifyGuard) reset(id string) {
+	g.mu.Lock()
+	delete(g.failures, id)
+	g.mu.Unlock()
+}
+
+func (g *NotifyGuard) put(letter DeadLetter) {
+	if g.Sink == nil {
+		return
+	}
+
+	letter.Time = time.Now()
+	if err := g.Sink.Put(letter); err != nil {
+		atomic.AddInt64(&g.stats.SinkErrors, 1)
+		return
+	}
+
+	atomic.AddInt64(&g.stats.DeadLettered, 1)
+}
+
+// FileDeadLetterSink 以 JSON 文件保存死信, 每条通知一个文件
+type FileDeadLetterSink struct {
+	Dir string
+}
+
+// NewFileDeadLetterSink 新建文件死信存储, 目录不存在时自动创建
+func NewFileDeadLetterSink(dir string) (*FileDeadLetterSink, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return &FileDeadLetterSink{Dir: dir}, nil
+}
+
+// Put 保存死信, 同一通知覆盖之前的记录
+func (s *FileDeadLetterSink) Put(letter DeadLetter) error {
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Join(s.Dir, letter.ID+".json")
+	tmp := name + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, name)
+}
+
+// List 读取全部死信
+func (s *FileDeadLetterSink) List() ([]DeadLetter, error) {
+	names, err := filepath.Glob(filepath.Join(s.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]DeadLetter, 0, len(names))
+	for _, name := range names {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		var letter DeadLetter
+		if err = json.Unmarshal(data, &letter); err != nil {
+			return nil, errors.New("死信文件格式错误: " + name)
+		}
+		letters = append(letters, letter)
+	}
+
+	return letters, nil
+}
+
+// Delete 删除死信
+func (s *FileDeadLetterSink) Delete(id string) error {
+	err := os.Remove(filepath.Join(s.Dir, filepath.Base(id)+".json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// SQLDeadLetterSink 使用数据库保存死信
+//
+// 参考表结构:
+//
+//	CREATE TABLE wxpay_dead_letters (
+//	    id         VARCHAR(64) PRIMARY KEY,
+//	    kind       VARCHAR(16) NOT NULL,
+//	    path       VARCHAR(255) NOT NULL,
+//	    body       BLOB NOT NULL,
+//	    error      TEXT NOT NULL,
+//	    attempts   INT NOT NULL,
+//	    created_at TIMESTAMP NOT NULL
+//	)
+type SQLDeadLetterSink struct {
+	DB    *sql.DB
+	Table string // 表名, 默认 wxpay_dead_letters
+
+	// Placeholder 生成第 n 个(从1开始)参数占位符, 默认为 ?, PostgreSQL 可使用 $n
+	Placeholder func(n int) string
+}
+
+// NewSQLDeadLetterSink 新建数据库死信存储
+func NewSQLDeadLetterSink(db *sql.DB) *SQLDeadLetterSink {
+	return &SQLDeadLetterSink{DB: db}
+}
+
+func (s *SQLDeadLetterSink) table() string {
+	if s.Table == "" {
+		return "wxpay_dead_letters"
+	}
+
+	return s.Table
+}
+
+func (s *SQLDeadLetterSink) placeholders(n int) string {
+	var buf bytes.Buffer
+	for i := 1; i <= n; i++ {
+		if i > 1 {
+			buf.WriteString(", ")
+		}
+		if s.Placeholder != nil {
+			buf.WriteString(s.Placeholder(i))
+		} else {
+			buf.WriteString("?")
+		}
+	}
+
+	return buf.String()
+}
+
+// Put 保存死信, 同一通知先删除旧记录再写入
+func (s *SQLDeadLetterSink) Put(letter DeadLetter) error {
+	if s.DB == nil {
+		return errors.New("未设置数据库连接")
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec("DELETE FROM "+s.table()+" WHERE id = "+s.placeholders(1), letter.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	query := "INSERT INTO " + s.table() + " (id, kind, path, body, error, attempts, created_at) VALUES (" + s.placeholders(7) + ")"
+	if _, err = tx.Exec(query, letter.ID, letter.Kind, letter.Path, letter.Body, letter.Error, letter.Attempts, letter.Time); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// List 读取全部死信
+func (s *SQLDeadLetterSink) List() ([]DeadLetter, error) {
+	if s.DB == nil {
+		return nil, errors.New("未设置数据库连接")
+	}
+
+	rows, err := s.DB.Query("SELECT id, kind, path, body, error, attempts, created_at FROM " + s.table() + " ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var letters []DeadLetter
+	for rows.Next() {
+		var letter DeadLetter
+		if err = rows.Scan(&letter.ID, &letter.Kind, &letter.Path, &letter.Body, &letter.Error, &letter.Attempts, &letter.Time); err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+
+	return letters, rows.Err()
+}
+
+// Delete 删除死信
+func (s *SQLDeadLetterSink) Delete(id string) error {
+	if s.DB == nil {
+		return errors.New("未设置数据库连接")
+	}
+
+	_, err := s.DB.Exec("DELETE FROM "+s.table()+" WHERE id = "+s.placeholders(1), id)
+	return err
+}
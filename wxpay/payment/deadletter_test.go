@@ -0,0 +1,98 @@
+package payment
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+type memoryDeadLetterSink struct {
+	mu      sync.Mutex
+	letters map[string]DeadLetter
+}
+
+func (s *memoryDeadLetterSink) Put(letter DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.letters == nil {
+		s.letters = make(map[string]DeadLetter)
+	}
+	s.letters[letter.ID] = letter
+
+	return nil
+}
+
+func paidNotifyBody(t *testing.T, key, outTradeNo string) []byte {
+	body, err := Simulator{Key: key}.PaidNotifyBody(PaidNotify{
+		AppID:         "wxd930ea5d5a258f4f",
+		MchID:         "10000100",
+		TotalFee:      100,
+		CashFee:       100,
+		TransactionID: "4200000000000000000000000000",
+		OutTradeNo:    outTradeNo,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return body
+}
+
+// 伪造的通知按 RejectLimit 限制写入死信
+func TestNotifyGuardRejectLimit(t *testing.T) {
+	sink := &memoryDeadLetterSink{}
+	g := NewNotifyGuard(sink)
+	g.RejectLimit = 2
+
+	for i := 0; i < 5; i++ {
+		body := paidNotifyBody(t, "forged", strconv.Itoa(i))
+		req := httptest.NewRequest(http.MethodPost, "/notify", bytes.NewReader(body))
+		if err := g.HandlePaidNotify(httptest.NewRecorder(), req, testKey, func(PaidNotify) (bool, string) {
+			t.Fatal("签名错误的通知不应调用处理函数")
+			return true, "OK"
+		}); err == nil {
+			t.Fatal("签名错误的通知应返回错误")
+		}
+	}
+
+	st := g.Stats()
+	if len(sink.letters) != 2 || st.Failed != 5 || st.DeadLettered != 2 || st.Dropped != 3 {
+		t.Fatalf("letters = %d, stats = %+v", len(sink.letters), st)
+	}
+}
+
+// 失败记录不超过 MaxTracked, 处理成功后删除
+func TestNotifyGuardFailuresBounded(t *testing.T) {
+	g := NewNotifyGuard(&memoryDeadLetterSink{})
+	g.MaxTracked = 2
+
+	// 同一通知重发时内容不变
+	bodies := make(map[string][]byte)
+	handle := func(outTradeNo string, ok bool) {
+		body, found := bodies[outTradeNo]
+		if !found {
+			body = paidNotifyBody(t, testKey, outTradeNo)
+			bodies[outTradeNo] = body
+		}
+		req := httptest.NewRequest(http.MethodPost, "/notify", bytes.NewReader(body))
+		g.HandlePaidNotify(httptest.NewRecorder(), req, testKey, func(PaidNotify) (bool, string) {
+			return ok, "retry"
+		})
+	}
+
+	for i := 0; i < 5; i++ {
+		handle(strconv.Itoa(i), false)
+	}
+	if n := len(g.failures); n != 2 {
+		t.Fatalf("failures = %d, want 2", n)
+	}
+
+	handle("4", true)
+	if n := len(g.failures); n != 1 {
+		t.Fatalf("failures = %d, want 1", n)
+	}
+}
@@ -0,0 +1,96 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	defaultMu     sync.RWMutex
+	defaultClient *Client
+)
+
+// SetDefault 设置默认支付客户端, 供包级函数使用
+func SetDefault(c *Client) {
+	defaultMu.Lock()
+	defaultClient = c
+	defaultMu.Unlock()
+}
+
+// Default 返回默认支付客户端, 未设置时返回 nil
+func Default() *Client {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+
+	return defaultClient
+}
+
+func mustDefault() (*Client, error) {
+	c := Default()
+	if c == nil {
+		return nil, errors.New("未设置默认支付客户端, 请先调用 SetDefault")
+	}
+
+	return c, nil
+}
+
+// Unify 使用默认客户端统一下单
+func Unify(ctx context.Context, o Order, opts ...CallOption) (res PaidResponse, err error) {
+	c, err := mustDefault()
+	if err != nil {
+		return
+	}
+
+	return c.Unify(ctx, o, opts...)
+}
+
+// Refund 使用默认客户端申请退款
+func Refund(ctx context.Context, r Refunder, opts ...CallOption) (res RefundedResponse, err error) {
+	c, err := mustDefault()
+	if err != nil {
+		return
+	}
+
+	return c.Refund(ctx, r, opts...)
+}
+
+// QueryRefund 使用默认客户端查询退款
+func QueryRefund(ctx context.Context, q RefundQuery, opts ...CallOption) (res RefundQueryResult, err error) {
+	c, err := mustDefault()
+	if err != nil {
+		return
+	}
+
+	return c.QueryRefund(ctx, q, opts...)
+}
+
+// Transfer 使用默认客户端企业付款到零钱
+func Transfer(ctx context.Context, t Transferer, opts ...CallOption) (res TransferResponse, err error) {
+	c, err := mustDefault()
+	if err != nil {
+		return
+	}
+
+	return c.Transfer(ctx, t, opts...)
+}
+
+// GetTransferInfo 使用默认客户端查询企业付款
+func GetTransferInfo(ctx context.Context, t TransferInfo, opts ...CallOption) (res TransferInfoResponse, err error) {
+	c, err := mustDefault()
+	if err != nil {
+		return
+	}
+
+	return c.TransferInfo(ctx, t, opts...)
+}
+
+// SendRedpack 使用默认客户端发放现金红包
+func SendRedpack(ctx context.Context, r Redpacker, opts ...CallOption) (res RedpackResponse, err error) {
+	c, err := mustDefault()
+	if err != nil {
+		return
+	}
+
+	return c.SendRedpack(ctx, r, opts...)
+}
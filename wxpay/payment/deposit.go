@@ -0,0 +1,191 @@
+package payment
+
+import (
+	"encoding/xml"
+	"errors"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+const (
+	depositMicropayAPI   = "/deposit/micropay"
+	depositOrderQueryAPI = "/deposit/orderquery"
+	depositReverseAPI    = "/deposit/reverse"
+	depositConsumeAPI    = "/deposit/consume"
+
+	// 押金接口仅支持 HMAC-SHA256 签名
+	depositSignType = "HMAC-SHA256"
+)
+
+// Deposit 押金支付(付款码)
+// 押金支付先冻结用户资金, 之后通过 Consume 扣除部分或全部押金, 剩余部分自动退回
+type Deposit struct {
+	// 必填 ...
+	AppID      string `sign:"appid"`              // 公众账号ID
+	MchID      string `sign:"mch_id"`             // 商户号
+	Body       string `sign:"body,cdata,max=128"` // 商品描述
+	OutTradeNo string `sign:"out_trade_no"`       // 商户订单号
+	TotalFee   int    `sign:"total_fee"`          // 押金金额(分)
+	AuthCode   string `sign:"auth_code"`          // 用户付款码
+
+	// 选填 ...
+	IP     string `sign:"spbill_create_ip"`               // 终端IP, 为空时使用本机IP
+	Detail string `sign:"detail,omitzero,cdata,max=6000"` // 商品详情
+	Attach string `sign:"attach,omitzero,cdata,max=127"`  // 附加数据
+}
+
+// DepositResponse 押金订单信息
+type DepositResponse struct {
+	AppID         string `xml:"appid" json:"appid"`
+	MchID         string `xml:"mch_id" json:"mch_id"`
+	OpenID        string `xml:"openid" json:"openid"`
+	TradeType     string `xml:"trade_type" json:"trade_type"`
+	TransactionID string `xml:"transaction_id" json:"transaction_id"` // 微信支付订单号
+	OutTradeNo    string `xml:"out_trade_no" json:"out_trade_no"`
+	// 交易状态: SUCCESS 支付成功 | REFUND 转入退款 | USERPAYING 用户支付中 | PAYERROR 支付失败
+	// REVOKED 已撤销 | CONSUMED 已消费 | SETTLING 结算中
+	TradeState     string `xml:"trade_state" json:"trade_state"`
+	BankType       string `xml:"bank_type" json:"bank_type"`
+	TotalFee       int    `xml:"total_fee" json:"total_fee"`     // 押金总金额
+	ConsumeFee     int    `xml:"consume_fee" json:"consume_fee"` // 已消费金额
+	CashFee        int    `xml:"cash_fee" json:"cash_fee"`
+	Attach         string `xml:"attach" json:"attach"`
+	TimeEnd        string `xml:"time_end" json:"time_end"`
+	TradeStateDesc string `xml:"trade_state_desc" json:"trade_state_desc"`
+}
+
+type depositResponse struct {
+	response
+	DepositResponse
+}
+
+// 请求前准备
+func (d Deposit) prepare(key string) (fields, error) {
+	if d.IP == "" {
+		ip, err := localIP()
+		if err != nil {
+			return nil, err
+		}
+
+		d.IP = ip
+	}
+
+	// 是否押金支付, 固定为 Y
+	return signedFields(d, key, depositSignType, field{name: "deposit", value: "Y"})
+}
+
+// Pay 押金支付
+// 返回 USERPAYING 时需要通过 DepositQuery 轮询确认支付结果
+func (d Deposit) Pay(key string) (res DepositResponse, err error) {
+	reqData, err := d.prepare(key)
+	if err != nil {
+		return
+	}
+
+	data, err := util.PostXML(baseURL+depositMicropayAPI, reqData)
+	if err != nil {
+		return
+	}
+
+	return parseDepositResponse(data, d.AppID, d.MchID)
+}
+
+// DepositQuery 查询押金订单
+type DepositQuery struct {
+	AppID         string `sign:"appid"`
+	MchID         string `sign:"mch_id"`
+	TransactionID string `sign:"transaction_id,omitzero"` // 微信订单号, 和商户订单号二选一
+	OutTradeNo    string `sign:"out_trade_no,omitzero"`   // 商户订单号
+}
+
+// 请求前准备
+func (q DepositQuery) prepare(key string) (fields, error) {
+	if q.TransactionID == "" && q.OutTradeNo == "" {
+		return nil, errors.New("out_trade_no 和 transaction_id 必须填写一个")
+	}
+
+	return signedFields(q, key, depositSignType)
+}
+
+// Query 查询押金订单
+func (q DepositQuery) Query(key string) (res DepositResponse, err error) {
+	reqData, err := q.prepare(key)
+	if err != nil {
+		return
+	}
+
+	data, err := util.PostXML(baseURL+depositOrderQueryAPI, reqData)
+	if err != nil {
+		return
+	}
+
+	return parseDepositResponse(data, q.AppID, q.MchID)
+}
+
+// Reverse 撤销押金订单
+// 支付失败或用户取消时调用, 已冻结的押金全额退回, 需要证书
+func (q DepositQuery) Reverse(key, certPath, keyPath string) (res DepositResponse, err error) {
+	reqData, err := q.prepare(key)
+	if err != nil {
+		return
+	}
+
+	data, err := util.TSLPostXML(baseURL+depositReverseAPI, reqData, certPath, keyPath)
+	if err != nil {
+		return
+	}
+
+	return parseDepositResponse(data, q.AppID, q.MchID)
+}
+
+// DepositConsume 押金扣费
+type DepositConsume struct {
+	AppID         string `sign:"appid"`
+	MchID         string `sign:"mch_id"`
+	TransactionID string `sign:"transaction_id"` // 微信订单号
+	TotalFee      int    `sign:"total_fee"`      // 押金总金额
+	ConsumeFee    int    `sign:"consume_fee"`    // 本次扣费金额, 不能大于押金总金额
+}
+
+// 请求前准备
+func (c DepositConsume) prepare(key string) (fields, error) {
+	if c.ConsumeFee <= 0 || c.ConsumeFee > c.TotalFee {
+		return nil, errors.New("扣费金额必须大于0且不能超过押金总金额")
+	}
+
+	return signedFields(c, key, depositSignType)
+}
+
+// Consume 押金扣费
+// 扣除部分或全部押金, 剩余押金自动退回用户, 需要证书
+func (c DepositConsume) Consume(key, certPath, keyPath string) (res DepositResponse, err error) {
+	reqData, err := c.prepare(key)
+	if err != nil {
+		return
+	}
+
+	data, err := util.TSLPostXML(baseURL+depositConsumeAPI, reqData, certPath, keyPath)
+	if err != nil {
+		return
+	}
+
+	return parseDepositResponse(data, c.AppID, c.MchID)
+}
+
+func parseDepositResponse(data []byte, appID, mchID string) (res DepositResponse, err error) {
+	var dres depositResponse
+	if err = xml.Unmarshal(data, &dres); err != nil {
+		return
+	}
+
+	if err = dres.Check(); err != nil {
+		return
+	}
+
+	if err = checkEcho(appID, mchID, dres.AppID, dres.MchID); err != nil {
+		return
+	}
+
+	res = dres.DepositResponse
+	return
+}
@@ -0,0 +1,146 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// 前端调起支付失败分类
+const (
+	PayFailCancel  = "cancel"  // 用户取消
+	PayFailSign    = "sign"    // 支付签名错误, 通常是 GetParams 的 appid 或密钥与下单不一致
+	PayFailExpired = "expired" // prepay_id 失效或订单已关闭
+	PayFailPaid    = "paid"    // 订单已支付
+	PayFailNetwork = "network" // 网络错误或超时
+	PayFailOther   = "other"
+)
+
+// PayFailure 前端调起支付失败记录
+type PayFailure struct {
+	ErrMsg   string    // wx.requestPayment fail 回调中的 errMsg
+	Category string    // 失败分类, 见 PayFail 常量
+	Time     time.Time // 记录时间
+}
+
+// ClassifyPayFailure 根据 errMsg 对前端支付失败进行分类
+func ClassifyPayFailure(errMsg string) string {
+	msg := strings.ToLower(errMsg)
+	switch {
+	case strings.Contains(msg, "cancel"):
+		return PayFailCancel
+	case strings.Contains(msg, "sign") || strings.Contains(msg, "签名"):
+		return PayFailSign
+	case strings.Contains(msg, "prepay_id") || strings.Contains(msg, "过期") || strings.Contains(msg, "关闭"):
+		return PayFailExpired
+	case strings.Contains(msg, "已支付"):
+		return PayFailPaid
+	case strings.Contains(msg, "timeout") || strings.Contains(msg, "network") || strings.Contains(msg, "网络"):
+		return PayFailNetwork
+	}
+
+	return PayFailOther
+}
+
+// RecordPayFailure 记录前端调起支付失败的原因
+// 小程序 wx.requestPayment 的 fail 回调把 errMsg 上报给后端后调用, 需要设置 Config.OrderStore
+func (c *Client) RecordPayFailure(outTradeNo, errMsg string) error {
+	store := c.conf().OrderStore
+	if store == nil {
+		return errors.New("未设置订单存储")
+	}
+
+	r, err := store.GetOrder(outTradeNo)
+	if err != nil {
+		return err
+	}
+	if r == nil {
+		return errors.New("订单不存在: " + outTradeNo)
+	}
+
+	now := time.Now()
+	r.PayFailures = append(r.PayFailures, PayFailure{
+		ErrMsg:   errMsg,
+		Category: ClassifyPayFailure(errMsg),
+		Time:     now,
+	})
+	r.UpdatedAt = now
+
+	return store.SaveOrder(*r)
+}
+
+// Diagnosis 订单支付诊断报告
+type Diagnosis struct {
+	OutTradeNo string
+	Failures   []PayFailure // 前端上报的失败记录
+	Remote     QueryResult  // 订单查询结果
+	QueryError string       // 订单查询失败原因
+	Mismatches []Mismatch   // 本地订单与查询结果不一致的字段
+	Conclusion string       // 诊断结论
+}
+
+// Diagnose 结合前端失败记录和订单查询结果生成诊断报告
+func (c *Client) Diagnose(ctx context.Context, outTradeNo string) (d Diagnosis, err error) {
+	store := c.conf().OrderStore
+	if store == nil {
+		err = errors.New("未设置订单存储")
+		return
+	}
+
+	r, err := store.GetOrder(outTradeNo)
+	if err != nil {
+		return
+	}
+	if r == nil {
+		err = errors.New("订单不存在: " + outTradeNo)
+		return
+	}
+
+	d.OutTradeNo = outTradeNo
+	d.Failures = r.PayFailures
+
+	remote, qerr := c.QueryOrder(ctx, outTradeNo)
+	if qerr != nil {
+		d.QueryError = qerr.Error()
+		d.Conclusion = "订单查询失败, 无法确认支付状态"
+		return
+	}
+
+	d.Remote = remote
+	d.Mismatches = Diff(r.Order, remote)
+	d.Conclusion = conclude(d)
+
+	return
+}
+
+// 根据最近一次前端失败和订单状态得出结论
+func conclude(d Diagnosis) string {
+	if len(d.Mismatches) > 0 {
+		return "本地订单与微信订单不一致, 请检查订单号是否被复用"
+	}
+
+	if d.Remote.Paid() {
+		if len(d.Failures) > 0 {
+			return "前端上报支付失败, 但订单已支付, 以支付结果为准"
+		}
+		return "订单已支付"
+	}
+
+	if len(d.Failures) == 0 {
+		return "订单未支付, 前端未上报失败"
+	}
+
+	switch d.Failures[len(d.Failures)-1].Category {
+	case PayFailCancel:
+		return "用户取消支付"
+	case PayFailSign:
+		return "支付签名错误, 请检查 GetParams 使用的 appid 和密钥是否与下单一致"
+	case PayFailExpired:
+		return "prepay_id 已失效或订单已关闭, 需要重新下单"
+	case PayFailNetwork:
+		return "用户网络异常, 可以提示用户重试"
+	}
+
+	return "订单未支付, 前端失败原因: " + d.Failures[len(d.Failures)-1].ErrMsg
+}
@@ -0,0 +1,42 @@
+package payment
+
+import "strconv"
+
+// Mismatch 本地订单与查询结果不一致的字段
+type Mismatch struct {
+	Field  string // 字段名, 与微信接口字段一致
+	Local  string
+	Remote string
+}
+
+// Diff 比较本地订单与订单查询结果
+// 用于支付通知校验和对账, 本地未填写的 openid 和 attach 不做比较
+func Diff(local Order, remote QueryResult) []Mismatch {
+	var diff []Mismatch
+	add := func(field, l, r string) {
+		if l != r {
+			diff = append(diff, Mismatch{Field: field, Local: l, Remote: r})
+		}
+	}
+
+	add("out_trade_no", local.OutTradeNo, remote.OutTradeNo)
+	add("total_fee", strconv.Itoa(local.TotalFee), strconv.Itoa(remote.TotalFee))
+
+	if local.AppID != "" {
+		add("appid", local.AppID, remote.AppID)
+	}
+
+	if local.MchID != "" {
+		add("mch_id", local.MchID, remote.MchID)
+	}
+
+	if local.OpenID != "" {
+		add("openid", local.OpenID, remote.OpenID)
+	}
+
+	if local.Attach != "" {
+		add("attach", local.Attach, remote.Attach)
+	}
+
+	return diff
+}
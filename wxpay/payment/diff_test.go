@@ -0,0 +1,70 @@
+package payment
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	remote := QueryResult{
+		AppID:      "wxd930ea5d5a258f4f",
+		MchID:      "10000100",
+		OpenID:     "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o",
+		TotalFee:   100,
+		OutTradeNo: "20150806125346",
+		Attach:     "store=1",
+	}
+
+	tests := []struct {
+		name  string
+		local Order
+		want  []Mismatch
+	}{
+		{
+			name: "match",
+			local: Order{
+				AppID:      "wxd930ea5d5a258f4f",
+				MchID:      "10000100",
+				OpenID:     "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o",
+				TotalFee:   100,
+				OutTradeNo: "20150806125346",
+				Attach:     "store=1",
+			},
+		},
+		{
+			// 本地未填写的 appid, mch_id, openid 和 attach 不比较
+			name:  "optional fields empty",
+			local: Order{TotalFee: 100, OutTradeNo: "20150806125346"},
+		},
+		{
+			name: "amount openid and attach",
+			local: Order{
+				OpenID:     "oUpF8uMuAJO_other",
+				TotalFee:   1,
+				OutTradeNo: "20150806125346",
+				Attach:     "store=2",
+			},
+			want: []Mismatch{
+				{Field: "total_fee", Local: "1", Remote: "100"},
+				{Field: "openid", Local: "oUpF8uMuAJO_other", Remote: "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o"},
+				{Field: "attach", Local: "store=2", Remote: "store=1"},
+			},
+		},
+		{
+			name:  "other order",
+			local: Order{AppID: "wx0000000000000000", TotalFee: 100, OutTradeNo: "20150806125347"},
+			want: []Mismatch{
+				{Field: "out_trade_no", Local: "20150806125347", Remote: "20150806125346"},
+				{Field: "appid", Local: "wx0000000000000000", Remote: "wxd930ea5d5a258f4f"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.local, remote); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Diff = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package payment
+
+import "errors"
+
+// 校验返回结果中回传的 appid 和 mch_id 与请求一致
+// 多商户部署时可以及早发现配置串用, 微信未回传的字段不做校验
+//
+// @appID @mchID 请求使用的值
+// @resAppID @resMchID 返回结果中的值
+func checkEcho(appID, mchID, resAppID, resMchID string) error {
+	if resAppID != "" && resAppID != appID {
+		return errors.New("返回的 appid 与请求不一致: " + resAppID + " != " + appID)
+	}
+
+	if resMchID != "" && resMchID != mchID {
+		return errors.New("返回的 mch_id 与请求不一致: " + resMchID + " != " + mchID)
+	}
+
+	return nil
+}
+
+// 校验服务商模式返回结果中回传的 sub_appid 和 sub_mch_id 与请求一致
+// 同一服务商下的子商户串用时, appid 和 mch_id 校验无法发现, 请求或返回结果未填写的字段不做校验
+//
+// @subAppID @subMchID 请求使用的值
+// @resSubAppID @resSubMchID 返回结果中的值
+func checkSubEcho(subAppID, subMchID, resSubAppID, resSubMchID string) error {
+	if subAppID != "" && resSubAppID != "" && resSubAppID != subAppID {
+		return errors.New("返回的 sub_appid 与请求不一致: " + resSubAppID + " != " + subAppID)
+	}
+
+	if subMchID != "" && resSubMchID != "" && resSubMchID != subMchID {
+		return errors.New("返回的 sub_mch_id 与请求不一致: " + resSubMchID + " != " + subMchID)
+	}
+
+	return nil
+}
@@ -0,0 +1,248 @@
+package payment
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+// 请求参数
+// 按结构体字段顺序保存参数名和值, 同时用于生成请求 XML 和签名
+type fields []field
+
+type field struct {
+	name   string
+	value  string
+	nosign bool // 发送但不参与签名
+	cdata  bool // 以 CDATA 输出, 用于可能包含 XML 特殊字符的商品描述等
+}
+
+// 设置参数, 已存在时覆盖
+func (fs *fields) set(name, value string) {
+	for i := range *fs {
+		if (*fs)[i].name == name {
+			(*fs)[i].value = value
+			return
+		}
+	}
+
+	*fs = append(*fs, field{name: name, value: value})
+}
+
+// 读取参数值
+func (fs fields) get(name string) string {
+	for _, f := range fs {
+		if f.name == name {
+			return f.value
+		}
+	}
+
+	return ""
+}
+
+// 参与签名的参数
+// sign 本身、标记为 nosign 的参数以及值为空的参数不参与签名
+func (fs fields) signData() map[string]string {
+	data := make(map[string]string, len(fs))
+	for _, f := range fs {
+		if f.name != "sign" && !f.nosign && f.value != "" {
+			data[f.name] = f.value
+		}
+	}
+
+	return data
+}
+
+// MarshalXML 以 <xml> 为根节点输出全部参数
+func (fs fields) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Local: "xml"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for _, f := range fs {
+		var v interface{} = f.value
+		if f.cdata {
+			v = struct {
+				Value string `xml:",cdata"`
+			}{f.value}
+		}
+
+		if err := e.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: f.name}}); err != nil {
+			return err
+		}
+	}
+
+	if err := e.EncodeToken(start.End()); err != nil {
+		return err
+	}
+
+	return e.Flush()
+}
+
+// 根据 sign 标签把结构体转换为请求参数
+//
+//	`sign:"name"`          必填参数, 值为空时同样发送
+//	`sign:"name,omitzero"` 选填参数, 零值时不发送
+//	`sign:"name,nosign"`   发送但不参与签名
+//	`sign:"name,cdata"`    以 CDATA 输出
+//	`sign:"name,max=128"`  值的最大字节数(不是字符数), 超过时返回错误
+//	`sign:"-"` 或没有标签    忽略该字段
+//
+// 按照微信签名规则, 值为空的参数即使发送也不参与签名, 见 fields.signData。
+// 支持 string、整数、浮点数和 time.Time(格式为 yyyyMMddHHmmss), 匿名嵌入的结构体按顺序展开
+func encodeFields(v interface{}) (fields, error) {
+	return encodeTag(v, "sign")
+}
+
+// 根据指定标签把结构体转换为参数, 标签选项 omitempty 等同于 omitzero
+// 用于按 xml 标签把应答或通知结构体还原为参数
+func encodeTag(v interface{}, key string) (fields, error) {
+	var fs fields
+	if err := appendFields(&fs, reflect.ValueOf(v), key); err != nil {
+		return nil, err
+	}
+
+	return fs, nil
+}
+
+func appendFields(fs *fields, rv reflect.Value, key string) error {
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return errors.New("只能编码结构体: " + rv.Type().String())
+	}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		fv := rv.Field(i)
+
+		tag, ok := sf.Tag.Lookup(key)
+		if !ok && sf.Anonymous && fv.Kind() == reflect.Struct {
+			if err := appendFields(fs, fv, key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !ok || tag == "-" {
+			continue
+		}
+
+		opts := strings.Split(tag, ",")
+		f := field{name: opts[0]}
+		omitzero := false
+		max := 0
+		for _, opt := range opts[1:] {
+			switch {
+			case opt == "omitzero", opt == "omitempty":
+				omitzero = true
+			case opt == "nosign":
+				f.nosign = true
+			case opt == "cdata":
+				f.cdata = true
+			case strings.HasPrefix(opt, "max="):
+				n, err := strconv.Atoi(strings.TrimPrefix(opt, "max="))
+				if err != nil {
+					return errors.New(rt.Name() + "." + sf.Name + ": 错误的 " + key + " 标签选项 " + opt)
+				}
+				max = n
+			default:
+				return errors.New(rt.Name() + "." + sf.Name + ": 未知的 " + key + " 标签选项 " + opt)
+			}
+		}
+
+		if omitzero && isZero(fv) {
+			continue
+		}
+
+		var err error
+		if f.value, err = formatValue(fv); err != nil {
+			return errors.New(rt.Name() + "." + sf.Name + ": " + err.Error())
+		}
+
+		if err = checkValue(f.name, f.value, max); err != nil {
+			return err
+		}
+
+		*fs = append(*fs, f)
+	}
+
+	return nil
+}
+
+// 检查参数值是否为有效的 UTF-8 且不超过最大字节数
+// 微信按字节计算长度, 一个汉字占3个字节, emoji 占4个字节
+func checkValue(name, value string, max int) error {
+	if !utf8.ValidString(value) {
+		return errors.New(name + " 不是有效的 UTF-8 字符串")
+	}
+
+	if max > 0 && len(value) > max {
+		return fmt.Errorf("%s 长度为%d字节, 超过上限%d字节, 可以使用 util.TruncateBytes 截断", name, len(value), max)
+	}
+
+	return nil
+}
+
+func isZero(v reflect.Value) bool {
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.IsZero()
+	}
+
+	return v.Interface() == reflect.Zero(v.Type()).Interface()
+}
+
+func formatValue(v reflect.Value) (string, error) {
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(paymentTimeFormat), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	}
+
+	return "", errors.New("不支持的字段类型 " + v.Type().String())
+}
+
+// 生成带签名的请求参数
+//
+// @v 带 sign 标签的请求结构体
+// @signType 签名类型, 为空时使用 MD5 且不发送 sign_type 参数(转账、红包等接口)
+// @extra 结构体以外的参数, 追加在结构体字段之后
+func signedFields(v interface{}, key, signType string, extra ...field) (fields, error) {
+	fs, err := encodeFields(v)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range extra {
+		fs.set(f.name, f.value)
+	}
+
+	fs.set("nonce_str", util.RandomString(32))
+	if signType != "" {
+		fs.set("sign_type", signType)
+	}
+
+	fs.set("sign", fs.sign(signType, key))
+
+	return fs, nil
+}
@@ -0,0 +1,107 @@
+package payment
+
+import (
+	"context"
+	"time"
+)
+
+// EndpointClass 接口类别
+type EndpointClass string
+
+// 接口类别
+const (
+	ClassMoney    EndpointClass = "money"    // 资金操作: 退款、企业付款、红包、押金扣款及撤销
+	ClassOrder    EndpointClass = "order"    // 下单及关单
+	ClassQuery    EndpointClass = "query"    // 查询
+	ClassDownload EndpointClass = "download" // 账单下载
+)
+
+var apiClasses = map[string]EndpointClass{
+	refundAPI:            ClassMoney,
+	transferAPI:          ClassMoney,
+	redpackAPI:           ClassMoney,
+	depositMicropayAPI:   ClassMoney,
+	depositConsumeAPI:    ClassMoney,
+	depositReverseAPI:    ClassMoney,
+	unifyAPI:             ClassOrder,
+	closeOrderAPI:        ClassOrder,
+	orderQueryAPI:        ClassQuery,
+	refundQueryAPI:       ClassQuery,
+	transferInfoAPI:      ClassQuery,
+	depositOrderQueryAPI: ClassQuery,
+	downloadBillAPI:      ClassDownload,
+	downloadFundFlowAPI:  ClassDownload,
+}
+
+// EndpointPolicy 接口类别的超时和重试策略
+// 只重试网络错误、超时和5xx, 业务错误(如 SYSTEMERROR)由调用方处理。
+// 重试发送完全相同的请求(单号和随机字符串不变), 微信按单号保证幂等
+type EndpointPolicy struct {
+	Timeout time.Duration // 单次请求超时时间, 为0时使用 Config.Timeout, WithTimeout 优先
+	Retry   RetryPolicy   // MaxAttempts 不大于1时不重试
+}
+
+// 推荐的接口类别策略
+var defaultPolicies = map[EndpointClass]EndpointPolicy{
+	ClassQuery:    {Retry: RetryPolicy{MaxAttempts: 3, Backoff: 200 * time.Millisecond}},
+	ClassDownload: {Timeout: time.Minute, Retry: RetryPolicy{MaxAttempts: 2, Backoff: time.Second}},
+}
+
+// DefaultPolicies 返回推荐的接口类别策略, 每次返回新的副本, 可以修改后设置到 Config.Policies
+// 查询重试3次, 账单下载超时1分钟并重试2次, 资金操作和下单不自动重试
+func DefaultPolicies() map[EndpointClass]EndpointPolicy {
+	return copyPolicies(defaultPolicies)
+}
+
+func copyPolicies(src map[EndpointClass]EndpointPolicy) map[EndpointClass]EndpointPolicy {
+	if src == nil {
+		return nil
+	}
+
+	dst := make(map[EndpointClass]EndpointPolicy, len(src))
+	for class, p := range src {
+		dst[class] = p
+	}
+
+	return dst
+}
+
+// 按接口类别的策略发送请求
+func (c *Client) post(ctx context.Context, o callOptions, api string, obj interface{}, cert bool) ([]byte, error) {
+	p, ok := o.conf.Policies[apiClasses[api]]
+	if !ok {
+		body, _, err := c.send(ctx, o, api, obj, cert)
+		return body, err
+	}
+
+	if p.Timeout > 0 && !o.timeoutSet {
+		o.timeout = p.Timeout
+	}
+
+	var body []byte
+	var last error
+	attempt := 0
+	err := Retry(ctx, p.Retry, func() error {
+		attempt++
+		if attempt > 1 {
+			// 切换接口地址后使用新地址重试, WithBaseURL 指定的地址不切换
+			if c.endpoints != nil && !o.baseURLSet {
+				o.baseURL = c.Endpoint()
+			}
+			c.hooks.onRetry(ctx, api, attempt, last)
+		}
+
+		var retryable bool
+		body, retryable, last = c.send(ctx, o, api, obj, cert)
+		if last != nil && !retryable {
+			return permanentError{last}
+		}
+		return last
+	})
+
+	// 不可重试的错误原样返回
+	if e, ok := err.(permanentError); ok {
+		err = e.error
+	}
+	return body, err
+}
@@ -0,0 +1,97 @@
+package payment
+
+// Error 微信支付返回的错误信息
+type Error struct {
+	ReturnCode string // 返回状态码: SUCCESS/FAIL
+	ReturnMsg  string // 返回信息
+	ResultCode string // 业务结果: SUCCESS/FAIL
+	ErrCode    string // 错误代码
+	ErrCodeDes string // 错误代码描述
+
+	// 可以展示给用户的中文错误信息, 未收录的错误代码为通用提示
+	UserMessage string
+}
+
+func (e *Error) Error() string {
+	if e.ReturnCode != "SUCCESS" {
+		return "交易失败: " + e.ReturnMsg
+	}
+
+	return "发生错误: " + e.ErrCodeDes
+}
+
+// Message 获取指定语言的用户提示信息
+//
+// @lang 语言: LangZH/LangEN
+func (e *Error) Message(lang string) string {
+	return userMessage(e.ErrCode, lang)
+}
+
+// Permanent 是否为重试也无法成功的错误
+// 如权限不足, 金额超限, 用户账户异常等, 需要人工或用户处理
+func (e *Error) Permanent() bool {
+	return permanentErrCodes[e.ErrCode]
+}
+
+// 重试无法成功的错误代码
+var permanentErrCodes = map[string]bool{
+	"NOAUTH":                true,
+	"AMOUNT_LIMIT":          true,
+	"USER_ACCOUNT_ABNORMAL": true,
+	"NOTENOUGH":             true,
+	"ORDERPAID":             true,
+	"ORDERCLOSED":           true,
+	"OUT_TRADE_NO_USED":     true,
+	"APPID_NOT_EXIST":       true,
+	"MCHID_NOT_EXIST":       true,
+	"APPID_MCHID_NOT_MATCH": true,
+	"LACK_PARAMS":           true,
+	"PARAM_ERROR":           true,
+	"SIGNERROR":             true,
+	"XML_FORMAT_ERROR":      true,
+	"OPENID_ERROR":          true,
+	"NAME_MISMATCH":         true,
+	"MONEY_LIMIT":           true,
+	"SENDNUM_LIMIT":         true,
+	"V2_ACCOUNT_SIMPLE_BAN": true,
+	"INVALID_TRANSACTIONID": true,
+	"REFUND_FEE_INVALID":    true,
+}
+
+// 根据返回信息创建错误
+func newError(res response) *Error {
+	return &Error{
+		ReturnCode:  res.ReturnCode,
+		ReturnMsg:   res.ReturnMsg,
+		ResultCode:  res.ResultCode,
+		ErrCode:     res.ErrCode,
+		ErrCodeDes:  res.ErrCodeDes,
+		UserMessage: userMessage(res.ErrCode, LangZH),
+	}
+}
+
+// ErrCodeOf 获取错误代码, 不是微信支付返回的错误时返回空
+func ErrCodeOf(err error) string {
+	if e, ok := err.(*Error); ok {
+		return e.ErrCode
+	}
+
+	return ""
+}
+
+// IsPermanent 是否为重试也无法成功的错误
+func IsPermanent(err error) bool {
+	switch e := err.(type) {
+	case *Error:
+		return e.Permanent()
+	case permanentError:
+		return true
+	}
+
+	return false
+}
+
+// 标记不应重试的错误, 如4xx应答和签名错误, 使 Retry 直接返回
+type permanentError struct {
+	error
+}
@@ -0,0 +1,64 @@
+package payment
+
+import (
+	"sync"
+	"time"
+)
+
+// 事件主题
+const (
+	TopicAccounting = "accounting" // 记账事件, 数据为 AccountingEvent
+	TopicDiscovered = "discovered" // 补单发现的遗漏订单, 数据为 PaidNotify
+)
+
+// Event 事件
+type Event struct {
+	Topic string
+	Time  time.Time
+	Data  interface{}
+}
+
+// EventHandler 事件处理函数
+type EventHandler func(Event)
+
+// EventBus 进程内事件总线
+// Publish 在调用方协程中同步分发, 处理函数需要自行处理耗时操作
+type EventBus struct {
+	mu   sync.RWMutex
+	subs map[string][]EventHandler
+}
+
+// NewEventBus 新建事件总线
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[string][]EventHandler)}
+}
+
+// Subscribe 订阅主题, topic 为空时订阅全部主题
+func (b *EventBus) Subscribe(topic string, fn EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subs == nil {
+		b.subs = make(map[string][]EventHandler)
+	}
+	b.subs[topic] = append(b.subs[topic], fn)
+}
+
+// Publish 发布事件, 未设置时间时使用当前时间
+func (b *EventBus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	handlers := make([]EventHandler, 0, len(b.subs[e.Topic])+len(b.subs[""]))
+	handlers = append(handlers, b.subs[e.Topic]...)
+	if e.Topic != "" {
+		handlers = append(handlers, b.subs[""]...)
+	}
+	b.mu.RUnlock()
+
+	for _, fn := range handlers {
+		fn(e)
+	}
+}
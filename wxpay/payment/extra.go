@@ -0,0 +1,56 @@
+package payment
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// 按序号返回的参数, 已解析到 Coupons 等字段
+var indexedField = regexp.MustCompile(`^coupon_(type|id|fee|refund_id|refund_fee)_\d+(_\d+)?$`)
+
+// 通知中结构体未收录的参数
+// 微信新增参数后, 在库支持之前商户即可通过 Extra 读取
+//
+// @v 解析通知使用的结构体
+func extraFields(data []byte, v interface{}) map[string]string {
+	raw, _ := parseRawFields(data)
+
+	known := map[string]bool{"sign": true, "sign_type": true}
+	xmlNames(reflect.TypeOf(v), known)
+
+	var extra map[string]string
+	for name, value := range raw {
+		if known[name] || indexedField.MatchString(name) {
+			continue
+		}
+
+		if extra == nil {
+			extra = make(map[string]string)
+		}
+		extra[name] = value
+	}
+
+	return extra
+}
+
+// 读取结构体 xml 标签中的参数名, 匿名嵌入的结构体同样读取
+func xmlNames(t reflect.Type, names map[string]bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("xml")
+		if !ok && sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			xmlNames(sf.Type, names)
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package payment
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// 支持自定义序列化的参数
+const (
+	FieldAttach    = "attach"     // 附加数据, 原样返回
+	FieldDetail    = "detail"     // 商品详情
+	FieldSceneInfo = "scene_info" // 场景信息
+)
+
+// FieldCodec attach、detail、scene_info 等字符串参数的序列化方式
+// 例如把 protobuf 消息序列化后 base64 编码放入 attach
+type FieldCodec interface {
+	Marshal(v interface{}) (string, error)
+	Unmarshal(data string, v interface{}) error
+}
+
+// JSONCodec 使用 JSON 序列化, 未注册序列化方式的参数默认使用
+var JSONCodec FieldCodec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) (string, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func (jsonCodec) Unmarshal(data string, v interface{}) error {
+	if s, ok := v.(*string); ok {
+		*s = data
+		return nil
+	}
+
+	return json.Unmarshal([]byte(data), v)
+}
+
+var (
+	fieldCodecsMu sync.RWMutex
+	fieldCodecs   = make(map[string]FieldCodec)
+)
+
+// RegisterFieldCodec 注册参数的序列化方式
+// 下单时的 Order.SetAttach 等方法和通知、查询结果的 DecodeAttach 等方法使用同一个序列化方式
+//
+// @name 参数名, 见 FieldAttach/FieldDetail/FieldSceneInfo
+// @c 序列化方式, 为 nil 时恢复默认的 JSONCodec
+func RegisterFieldCodec(name string, c FieldCodec) {
+	fieldCodecsMu.Lock()
+	defer fieldCodecsMu.Unlock()
+
+	if c == nil {
+		delete(fieldCodecs, name)
+		return
+	}
+	fieldCodecs[name] = c
+}
+
+func fieldCodec(name string) FieldCodec {
+	fieldCodecsMu.RLock()
+	c, ok := fieldCodecs[name]
+	fieldCodecsMu.RUnlock()
+
+	if !ok {
+		return JSONCodec
+	}
+
+	return c
+}
+
+// EncodeField 使用注册的序列化方式编码参数值
+func EncodeField(name string, v interface{}) (string, error) {
+	return fieldCodec(name).Marshal(v)
+}
+
+// DecodeField 使用注册的序列化方式解码参数值
+// 参数值为空时不做处理
+func DecodeField(name, data string, v interface{}) error {
+	if data == "" {
+		return nil
+	}
+
+	return fieldCodec(name).Unmarshal(data, v)
+}
+
+// SetAttach 序列化并设置附加数据
+func (o *Order) SetAttach(v interface{}) (err error) {
+	o.Attach, err = EncodeField(FieldAttach, v)
+	return
+}
+
+// SetDetail 序列化并设置商品详情
+func (o *Order) SetDetail(v interface{}) (err error) {
+	o.Detail, err = EncodeField(FieldDetail, v)
+	return
+}
+
+// SetSceneInfo 序列化并设置场景信息
+func (o *Order) SetSceneInfo(v interface{}) (err error) {
+	o.SceneInfo, err = EncodeField(FieldSceneInfo, v)
+	return
+}
+
+// DecodeAttach 解码订单附加数据
+func (o Order) DecodeAttach(v interface{}) error {
+	return DecodeField(FieldAttach, o.Attach, v)
+}
+
+// DecodeAttach 解码通知中原样返回的附加数据
+func (ntf PaidNotify) DecodeAttach(v interface{}) error {
+	return DecodeField(FieldAttach, ntf.Attach, v)
+}
+
+// DecodeAttach 解码查询结果中的附加数据
+func (r QueryResult) DecodeAttach(v interface{}) error {
+	return DecodeField(FieldAttach, r.Attach, v)
+}
+
+// SetAttach 序列化并设置附加数据
+func (d *Deposit) SetAttach(v interface{}) (err error) {
+	d.Attach, err = EncodeField(FieldAttach, v)
+	return
+}
+
+// SetDetail 序列化并设置商品详情
+func (d *Deposit) SetDetail(v interface{}) (err error) {
+	d.Detail, err = EncodeField(FieldDetail, v)
+	return
+}
+
+// DecodeAttach 解码押金订单附加数据
+func (res DepositResponse) DecodeAttach(v interface{}) error {
+	return DecodeField(FieldAttach, res.Attach, v)
+}
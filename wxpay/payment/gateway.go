@@ -0,0 +1,46 @@
+package payment
+
+import (
+	"net/http"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+// ServePaidNotify 处理 API 网关、云函数等转交的支付结果通知
+// body 需要是原始字节, base64 编码的请求体先用 util.GatewayBody 解码, 返回的应答交给网关写回微信
+// 签名错误时不调用处理函数, 应答 FAIL
+//
+// @key 微信支付 KEY
+func ServePaidNotify(headers map[string]string, body []byte, key string, fn func(PaidNotify) (bool, string)) (util.GatewayResponse, error) {
+	var err error
+	res, e := util.ServeGateway(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err = verifyPaidRequest(req, key); err != nil {
+			if werr := writeMuxReplay(w, newReplay(false, err.Error())); werr != nil {
+				err = werr
+			}
+			return
+		}
+
+		err = HandlePaidNotify(w, req, fn)
+	}), "", headers, body)
+	if e != nil {
+		return res, e
+	}
+
+	return res, err
+}
+
+// ServeRefundedNotify 处理 API 网关、云函数等转交的退款结果通知
+//
+// @key 微信支付 KEY
+func ServeRefundedNotify(headers map[string]string, body []byte, key string, fn func(RefundedNotify) (bool, string)) (util.GatewayResponse, error) {
+	var err error
+	res, e := util.ServeGateway(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err = HandleRefundedNotify(w, req, key, fn)
+	}), "", headers, body)
+	if e != nil {
+		return res, e
+	}
+
+	return res, err
+}
@@ -0,0 +1,56 @@
+package payment
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestServePaidNotify(t *testing.T) {
+	body, err := Simulator{Key: testKey}.PaidNotifyBody(PaidNotify{
+		AppID:         "wxd930ea5d5a258f4f",
+		MchID:         "10000100",
+		TotalFee:      100,
+		CashFee:       100,
+		TransactionID: "4200000000000000000000000000",
+		OutTradeNo:    "20150806125346",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := bytes.Replace(body, []byte("<total_fee>100</total_fee>"), []byte("<total_fee>1</total_fee>"), 1)
+	if bytes.Equal(tampered, body) {
+		t.Fatalf("通知中没有 total_fee: %s", body)
+	}
+
+	tests := []struct {
+		name   string
+		body   []byte
+		key    string
+		called bool
+	}{
+		{"signed", body, testKey, true},
+		{"tampered", tampered, testKey, false},
+		{"wrong key", body, "forged", false},
+		{"no key", body, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var called bool
+			headers := map[string]string{"Content-Type": "text/xml"}
+			res, err := ServePaidNotify(headers, tt.body, tt.key, func(PaidNotify) (bool, string) {
+				called = true
+				return true, "OK"
+			})
+
+			if called != tt.called || (err == nil) != tt.called {
+				t.Fatalf("called = %v, err = %v", called, err)
+			}
+			if got := strings.Contains(string(res.Body), "SUCCESS"); got != tt.called {
+				t.Fatalf("replay = %s", res.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+package payment
+
+import "context"
+
+// GoodsTagValidator 下单前校验订单优惠标记(goods_tag)
+// 优惠标记对应的代金券活动已结束时用户无法享受优惠, 容易引起投诉,
+// v3.GoodsTagValidator 按代金券批次状态校验
+type GoodsTagValidator interface {
+	ValidateGoodsTag(ctx context.Context, tag string) error
+}
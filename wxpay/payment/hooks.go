@@ -0,0 +1,188 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// RequestHook 请求发出前调用, body 为签名后的请求内容, 不能修改
+type RequestHook func(ctx context.Context, api string, body []byte)
+
+// ResponseHook 请求结束后调用, 网络错误、非200应答等请求失败时 err 不为空
+// 业务错误(return_code/result_code 为 FAIL)不在这里体现, 需要自行解析 body
+type ResponseHook func(ctx context.Context, api string, info CallInfo, body []byte, err error)
+
+// RetryHook 按 Config.Policies 重试前调用
+// attempt 为即将发出的第几次请求, err 为上一次请求的错误
+type RetryHook func(ctx context.Context, api string, attempt int, err error)
+
+// NotifyVerifiedHook 通知签名校验(退款通知为解密)通过后, 调用处理函数前调用
+// kind 为 NotifyPaid、NotifyRefunded 或 NotifyNative
+type NotifyVerifiedHook func(kind string, body []byte)
+
+// NotifyRejectedHook 通知校验失败时调用, 这类通知不会交给处理函数
+type NotifyRejectedHook func(kind string, body []byte, err error)
+
+// 客户端注册的钩子
+type hooks struct {
+	mu       sync.RWMutex
+	request  []RequestHook
+	response []ResponseHook
+	retry    []RetryHook
+	verified []NotifyVerifiedHook
+	rejected []NotifyRejectedHook
+}
+
+// OnRequest 注册请求钩子, 可以多次注册, 按注册顺序调用
+// 钩子在调用方协程中同步执行, 耗时操作需要自行异步处理
+func (c *Client) OnRequest(fn RequestHook) {
+	c.hooks.mu.Lock()
+	defer c.hooks.mu.Unlock()
+
+	c.hooks.request = append(c.hooks.request, fn)
+}
+
+// OnResponse 注册应答钩子, 每次请求(包括重试)调用一次
+func (c *Client) OnResponse(fn ResponseHook) {
+	c.hooks.mu.Lock()
+	defer c.hooks.mu.Unlock()
+
+	c.hooks.response = append(c.hooks.response, fn)
+}
+
+// OnRetry 注册重试钩子
+func (c *Client) OnRetry(fn RetryHook) {
+	c.hooks.mu.Lock()
+	defer c.hooks.mu.Unlock()
+
+	c.hooks.retry = append(c.hooks.retry, fn)
+}
+
+// OnNotifyVerified 注册通知校验通过钩子
+// 只对 Client.HandlePaidNotify、Client.HandleRefundedNotify 和 Client.HandleNativeCallback 生效
+func (c *Client) OnNotifyVerified(fn NotifyVerifiedHook) {
+	c.hooks.mu.Lock()
+	defer c.hooks.mu.Unlock()
+
+	c.hooks.verified = append(c.hooks.verified, fn)
+}
+
+// OnNotifyRejected 注册通知校验失败钩子, 生效范围同 OnNotifyVerified
+func (c *Client) OnNotifyRejected(fn NotifyRejectedHook) {
+	c.hooks.mu.Lock()
+	defer c.hooks.mu.Unlock()
+
+	c.hooks.rejected = append(c.hooks.rejected, fn)
+}
+
+func (h *hooks) onRequest(ctx context.Context, api string, body []byte) {
+	h.mu.RLock()
+	fns := h.request
+	h.mu.RUnlock()
+
+	for _, fn := range fns {
+		fn(ctx, api, body)
+	}
+}
+
+func (h *hooks) onResponse(ctx context.Context, api string, info CallInfo, body []byte, err error) {
+	h.mu.RLock()
+	fns := h.response
+	h.mu.RUnlock()
+
+	for _, fn := range fns {
+		fn(ctx, api, info, body, err)
+	}
+}
+
+func (h *hooks) onRetry(ctx context.Context, api string, attempt int, err error) {
+	h.mu.RLock()
+	fns := h.retry
+	h.mu.RUnlock()
+
+	for _, fn := range fns {
+		fn(ctx, api, attempt, err)
+	}
+}
+
+func (h *hooks) onNotifyVerified(kind string, body []byte) {
+	h.mu.RLock()
+	fns := h.verified
+	h.mu.RUnlock()
+
+	for _, fn := range fns {
+		fn(kind, body)
+	}
+}
+
+func (h *hooks) onNotifyRejected(kind string, body []byte, err error) {
+	h.mu.RLock()
+	fns := h.rejected
+	h.mu.RUnlock()
+
+	for _, fn := range fns {
+		fn(kind, body, err)
+	}
+}
+
+// 校验通知并调用钩子, 校验失败时应答 FAIL 并返回错误
+// 请求体读取后会被恢复, 之后可以交给 HandlePaidNotify 等处理
+func (c *Client) verifyNotify(res http.ResponseWriter, req *http.Request, kind string, verify func(raw map[string]string) error) error {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	raw, err := parseRawFields(body)
+	if err == nil {
+		err = verify(raw)
+	}
+	if err != nil {
+		c.hooks.onNotifyRejected(kind, body, err)
+		if werr := writeMuxReplay(res, newReplay(false, err.Error())); werr != nil {
+			return werr
+		}
+		return err
+	}
+
+	c.hooks.onNotifyVerified(kind, body)
+	return nil
+}
+
+// HandlePaidNotify 处理支付结果通知, 使用客户端配置的支付密钥校验签名, 见 HandlePaidNotify
+// 签名错误时不调用处理函数, 直接应答 FAIL
+func (c *Client) HandlePaidNotify(res http.ResponseWriter, req *http.Request, fn func(PaidNotify) (bool, string)) error {
+	key := c.conf().Key
+	err := c.verifyNotify(res, req, NotifyPaid, func(raw map[string]string) error {
+		return verifySign(raw, key)
+	})
+	if err != nil {
+		return err
+	}
+
+	return HandlePaidNotify(res, req, fn)
+}
+
+// HandleRefundedNotify 处理退款结果通知, 使用客户端配置的支付密钥解密, 见 HandleRefundedNotify
+// 解密失败时不调用处理函数, 直接应答 FAIL
+func (c *Client) HandleRefundedNotify(res http.ResponseWriter, req *http.Request, fn func(RefundedNotify) (bool, string)) error {
+	key := c.conf().Key
+	err := c.verifyNotify(res, req, NotifyRefunded, func(raw map[string]string) error {
+		if raw["req_info"] == "" {
+			return errors.New("退款通知缺少 req_info")
+		}
+
+		_, err := decryptRefundInfo(raw["req_info"], key)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return HandleRefundedNotify(res, req, key, fn)
+}
@@ -0,0 +1,31 @@
+package payment
+
+import (
+	"sync"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+var localIPCache struct {
+	sync.Mutex
+	ip string
+}
+
+// 本机IP, 用作未填写时的终端IP
+// 探测成功后缓存, 避免每次下单都遍历网卡
+func localIP() (string, error) {
+	localIPCache.Lock()
+	defer localIPCache.Unlock()
+
+	if localIPCache.ip != "" {
+		return localIPCache.ip, nil
+	}
+
+	ip, err := util.FetchIP()
+	if err != nil {
+		return "", err
+	}
+	localIPCache.ip = ip.String()
+
+	return localIPCache.ip, nil
+}
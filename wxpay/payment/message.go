@@ -0,0 +1,81 @@
+package payment
+
+import "sync"
+
+// 用户提示语言
+const (
+	LangZH = "zh"
+	LangEN = "en"
+)
+
+// Message 错误代码对应的用户提示
+type Message struct {
+	ZH string // 中文提示
+	EN string // 英文提示
+}
+
+func (m Message) get(lang string) string {
+	if lang == LangEN {
+		return m.EN
+	}
+
+	return m.ZH
+}
+
+// 未收录的错误代码使用的通用提示
+var defaultMessage = Message{
+	ZH: "支付失败, 请稍后重试",
+	EN: "Payment failed, please try again later",
+}
+
+var (
+	messagesMu sync.RWMutex
+
+	// 错误代码对应的用户提示
+	messages = map[string]Message{
+		"NOAUTH":                {"商户暂不支持该支付方式", "The merchant does not support this payment method"},
+		"AMOUNT_LIMIT":          {"支付金额超出限制", "The amount exceeds the limit"},
+		"USER_ACCOUNT_ABNORMAL": {"您的微信支付账户异常, 请联系微信客服", "Your WeChat Pay account is abnormal, please contact WeChat support"},
+		"NOTENOUGH":             {"余额不足, 请更换支付方式", "Insufficient balance, please use another payment method"},
+		"ORDERPAID":             {"订单已支付, 请勿重复支付", "The order has already been paid"},
+		"ORDERCLOSED":           {"订单已关闭, 请重新下单", "The order has been closed, please place a new order"},
+		"SYSTEMERROR":           {"系统繁忙, 请稍后重试", "The system is busy, please try again later"},
+		"BANKERROR":             {"银行系统异常, 请稍后重试", "The bank system is unavailable, please try again later"},
+		"USERPAYING":            {"等待支付中, 请在微信中输入密码", "Waiting for payment, please enter your password in WeChat"},
+		"AUTHCODEEXPIRE":        {"付款码已过期, 请刷新后重试", "The payment code has expired, please refresh it"},
+		"MONEY_LIMIT":           {"已达到付款金额上限", "The payment limit has been reached"},
+		"NAME_MISMATCH":         {"收款人姓名校验不一致", "The payee name does not match"},
+		"V2_ACCOUNT_SIMPLE_BAN": {"您的微信账户未实名认证, 无法收款", "Your WeChat account has not completed real-name verification"},
+		"FREQ_LIMIT":            {"操作过于频繁, 请稍后再试", "Too many requests, please try again later"},
+	}
+)
+
+// SetMessage 设置错误代码对应的用户提示, 覆盖默认提示
+func SetMessage(errCode string, m Message) {
+	messagesMu.Lock()
+	defer messagesMu.Unlock()
+
+	messages[errCode] = m
+}
+
+// SetDefaultMessage 设置未收录错误代码使用的通用提示
+func SetDefaultMessage(m Message) {
+	messagesMu.Lock()
+	defer messagesMu.Unlock()
+
+	defaultMessage = m
+}
+
+// 获取错误代码对应的用户提示
+func userMessage(errCode, lang string) string {
+	messagesMu.RLock()
+	defer messagesMu.RUnlock()
+
+	if m, ok := messages[errCode]; ok {
+		if msg := m.get(lang); msg != "" {
+			return msg
+		}
+	}
+
+	return defaultMessage.get(lang)
+}
@@ -0,0 +1,68 @@
+package payment
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/wanghuobo/weapp/payment/types"
+)
+
+// 交易类型
+const (
+	TradeTypeJSAPI    = string(types.TradeTypeJSAPI)    // 小程序/公众号支付
+	TradeTypeNative   = string(types.TradeTypeNative)   // 扫码支付
+	TradeTypeMWEB     = string(types.TradeTypeMWEB)     // H5 支付
+	TradeTypeMicropay = string(types.TradeTypeMicropay) // 付款码支付
+	TradeTypeApp      = string(types.TradeTypeApp)      // APP 支付
+)
+
+// MWebRedirectURL 在 mweb_url 后追加支付完成后的回跳地址
+// 回跳地址必须属于商户平台配置的 H5 支付域名, 否则微信会提示"商家参数格式有误"
+//
+// @mwebURL 统一下单返回的 mweb_url
+// @redirectURL 回跳页面地址, 不需要预先编码
+// @domains H5 支付授权域名, 包含其子域名
+func MWebRedirectURL(mwebURL, redirectURL string, domains ...string) (string, error) {
+	if !strings.HasPrefix(mwebURL, "https://wx.tenpay.com/") {
+		return "", errors.New("mweb_url 格式错误: " + mwebURL)
+	}
+
+	if err := CheckH5Domain(redirectURL, domains...); err != nil {
+		return "", err
+	}
+
+	sep := "&"
+	if !strings.Contains(mwebURL, "?") {
+		sep = "?"
+	}
+
+	return mwebURL + sep + "redirect_url=" + url.QueryEscape(redirectURL), nil
+}
+
+// CheckH5Domain 检查地址是否属于 H5 支付授权域名
+// 跳转 mweb_url 的页面(即 Referer)和回跳地址都需要满足该限制
+func CheckH5Domain(rawURL string, domains ...string) error {
+	if len(domains) == 0 {
+		return errors.New("未设置 H5 支付授权域名")
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("地址必须以 http 或 https 开头: " + rawURL)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return nil
+		}
+	}
+
+	return errors.New("地址不属于 H5 支付授权域名: " + rawURL)
+}
@@ -0,0 +1,185 @@
+package payment
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+// 扫码支付模式一二维码链接前缀
+const bizPayURLPrefix = "weixin://wxpay/bizpayurl?"
+
+// BizPayURL 生成扫码支付模式一的二维码链接
+// 二维码只包含商品ID, 可以长期使用; 用户扫码后微信回调商户平台配置的扫码回调地址,
+// 由 HandleNativeCallback 按商品ID下单并返回 prepay_id
+//
+// @appID 公众号 APPID
+// @mchID 商户号
+// @key 微信支付密钥
+// @productID 商品ID, 不超过32个字符
+func BizPayURL(appID, mchID, key, productID string) (string, error) {
+	if productID == "" || len(productID) > 32 {
+		return "", errors.New("product_id 不能为空且不超过32个字符")
+	}
+
+	fs := fields{
+		{name: "appid", value: appID},
+		{name: "mch_id", value: mchID},
+		{name: "nonce_str", value: util.RandomString(32)},
+		{name: "product_id", value: productID},
+		{name: "time_stamp", value: strconv.FormatInt(time.Now().Unix(), 10)},
+	}
+
+	q := url.Values{"sign": {fs.sign(SignTypeMD5, key)}}
+	for _, f := range fs {
+		q.Set(f.name, f.value)
+	}
+
+	return bizPayURLPrefix + q.Encode(), nil
+}
+
+// NativeCallback 扫码支付模式一回调
+// 用户扫描 BizPayURL 生成的二维码后微信发送
+type NativeCallback struct {
+	AppID       string `xml:"appid" json:"appid"`
+	MchID       string `xml:"mch_id" json:"mch_id"`
+	OpenID      string `xml:"openid" json:"openid"`             // 扫码用户在该 APPID 下的 openid
+	IsSubscribe string `xml:"is_subscribe" json:"is_subscribe"` // 是否关注公众号: Y/N
+	NonceStr    string `xml:"nonce_str" json:"nonce_str"`
+	ProductID   string `xml:"product_id" json:"product_id"` // 二维码中的商品ID
+}
+
+// Order 根据回调生成扫码支付订单, 需要再填写金额、商品描述和商户订单号
+func (cb NativeCallback) Order() Order {
+	return Order{
+		AppID:     cb.AppID,
+		MchID:     cb.MchID,
+		OpenID:    cb.OpenID,
+		TradeType: TradeTypeNative,
+		ProductID: cb.ProductID,
+	}
+}
+
+// HandleNativeCallback 处理扫码支付模式一回调
+// 校验签名后调用 fn, fn 按商品ID统一下单(可以使用 NativeCallback.Order)并返回 prepay_id,
+// 返回错误时应答下单失败, 错误信息会展示给用户
+//
+// @key 微信支付密钥
+func HandleNativeCallback(res http.ResponseWriter, req *http.Request, key string, fn func(NativeCallback) (prepayID string, err error)) error {
+	body, _, err := readBody(req.Body)
+	if err != nil {
+		return err
+	}
+
+	cb, err := VerifyNativeCallback(body, key)
+	if err != nil {
+		return writeNativeReply(res, nil, key, "", err)
+	}
+
+	prepayID, ferr := fn(cb)
+	if ferr == nil && prepayID == "" {
+		ferr = errors.New("prepay_id 不能为空")
+	}
+	if err = writeNativeReply(res, &cb, key, prepayID, ferr); err != nil {
+		return err
+	}
+
+	return ferr
+}
+
+// VerifyNativeCallback 校验扫码支付模式一回调的签名并解析
+// 用于在 HandleNativeCallback 之外(如网关、消息队列)处理回调
+//
+// @body 回调原文
+// @key 微信支付密钥
+func VerifyNativeCallback(body []byte, key string) (cb NativeCallback, err error) {
+	raw, err := parseRawFields(body)
+	if err != nil {
+		return
+	}
+
+	if raw["product_id"] == "" {
+		err = errors.New("扫码回调缺少 product_id")
+		return
+	}
+
+	if err = verifySign(raw, key); err != nil {
+		return
+	}
+
+	err = xml.Unmarshal(body, &cb)
+	return
+}
+
+// BuildBizPayURL 使用客户端配置生成扫码支付模式一的二维码链接, 见 BizPayURL
+func (c *Client) BuildBizPayURL(productID string) (string, error) {
+	cfg := c.conf()
+	return BizPayURL(cfg.AppID, cfg.MchID, cfg.Key, productID)
+}
+
+// HandleNativeCallback 处理扫码支付模式一回调, 使用客户端配置的支付密钥, 见 HandleNativeCallback
+// 回调的商户号与客户端配置不一致, 或 APPID 不是 Config.AppID 及 Config.AppIDs 之一时应答失败
+func (c *Client) HandleNativeCallback(res http.ResponseWriter, req *http.Request, fn func(NativeCallback) (prepayID string, err error)) error {
+	cfg := c.conf()
+	err := c.verifyNotify(res, req, NotifyNative, func(raw map[string]string) error {
+		return verifySign(raw, cfg.Key)
+	})
+	if err != nil {
+		return err
+	}
+
+	return HandleNativeCallback(res, req, cfg.Key, func(cb NativeCallback) (string, error) {
+		if cb.MchID != cfg.MchID || (cb.AppID != cfg.AppID && !containsString(cfg.AppIDs, cb.AppID)) {
+			return "", errors.New("商户配置错误")
+		}
+
+		return fn(cb)
+	})
+}
+
+// 应答扫码回调
+// cb 为空表示回调本身有误(return_code=FAIL), ferr 不为空表示下单失败(result_code=FAIL)
+func writeNativeReply(res http.ResponseWriter, cb *NativeCallback, key, prepayID string, ferr error) error {
+	var fs fields
+	if cb == nil {
+		fs = fields{
+			{name: "return_code", value: "FAIL"},
+			{name: "return_msg", value: ferr.Error()},
+		}
+	} else {
+		fs = fields{
+			{name: "return_code", value: "SUCCESS"},
+			{name: "appid", value: cb.AppID},
+			{name: "mch_id", value: cb.MchID},
+			{name: "nonce_str", value: util.RandomString(32)},
+			{name: "prepay_id", value: prepayID},
+			{name: "result_code", value: "SUCCESS"},
+		}
+		if ferr != nil {
+			fs.set("result_code", "FAIL")
+			fs.set("err_code_des", ferr.Error())
+		}
+		fs.set("sign", fs.sign(SignTypeMD5, key))
+	}
+
+	b, err := xml.Marshal(fs)
+	if err != nil {
+		return err
+	}
+
+	res.WriteHeader(http.StatusOK)
+	if _, err = res.Write(b); err != nil {
+		return err
+	}
+
+	if cb == nil {
+		return ferr
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// NoStore 单号生成器的持久化存储
+// 多个实例共享同一存储即可保证单号在重启和水平扩展后仍然唯一
+type NoStore interface {
+	// Reserve 原子地为 scope 预留 n 个连续序号并返回第一个, 序号从1开始
+	// 可以使用 Redis INCRBY 或数据库行锁实现
+	Reserve(scope string, n int64) (int64, error)
+	// Lookup 读取业务键对应的单号, 没有记录时返回空
+	Lookup(key string) (string, error)
+	// Bind 业务键没有对应单号时记录 no, 返回业务键最终对应的单号
+	Bind(key, no string) (string, error)
+}
+
+// NoGenerator 单号生成器
+// 单号格式为 前缀 + 日期(yyyyMMdd) + 定长序号, 如 R20200101000000001
+type NoGenerator struct {
+	Prefix    string  // 单号前缀
+	Store     NoStore // 持久化存储
+	Block     int64   // 每次从存储预留的序号数量, 默认100
+	Width     int     // 序号宽度, 默认10
+	MaxLength int     // 单号最大长度
+
+	mu    sync.Mutex
+	scope string
+	next  int64
+	limit int64
+}
+
+// NewRefundNoGenerator 新建退款单号(out_refund_no)生成器, 最长64个字符
+func NewRefundNoGenerator(store NoStore) *NoGenerator {
+	return &NoGenerator{Prefix: "R", Store: store, MaxLength: 64}
+}
+
+// NewBatchNoGenerator 新建批量转账批次号(out_batch_no)生成器, 最长32个字符
+func NewBatchNoGenerator(store NoStore) *NoGenerator {
+	return &NoGenerator{Prefix: "B", Store: store, MaxLength: 32}
+}
+
+// Next 生成新单号
+func (g *NoGenerator) Next() (string, error) {
+	if g.Store == nil {
+		return "", errors.New("单号生成器没有设置 Store")
+	}
+
+	for _, r := range g.Prefix {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return "", errors.New("单号前缀只能包含字母和数字: " + g.Prefix)
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	scope := g.Prefix + time.Now().Format("20060102")
+	if scope != g.scope || g.next >= g.limit {
+		block := g.Block
+		if block <= 0 {
+			block = 100
+		}
+
+		first, err := g.Store.Reserve(scope, block)
+		if err != nil {
+			return "", err
+		}
+
+		g.scope, g.next, g.limit = scope, first, first+block
+	}
+
+	width := g.Width
+	if width <= 0 {
+		width = 10
+	}
+
+	no := fmt.Sprintf("%s%0*d", scope, width, g.next)
+	if g.MaxLength > 0 && len(no) > g.MaxLength {
+		return "", fmt.Errorf("单号超过%d个字符: %s", g.MaxLength, no)
+	}
+	g.next++
+
+	return no, nil
+}
+
+// For 返回业务键对应的单号, 同一业务键重复调用返回同一单号
+// 用于重试退款或转账时保证不会重复出款
+//
+// @key 业务键, 如售后单号
+func (g *NoGenerator) For(key string) (string, error) {
+	if key == "" {
+		return "", errors.New("业务键不能为空")
+	}
+
+	if g.Store == nil {
+		return "", errors.New("单号生成器没有设置 Store")
+	}
+
+	key = g.Prefix + ":" + key
+	no, err := g.Store.Lookup(key)
+	if err != nil || no != "" {
+		return no, err
+	}
+
+	if no, err = g.Next(); err != nil {
+		return "", err
+	}
+
+	return g.Store.Bind(key, no)
+}
+
+// MemoryNoStore 基于内存的单号存储, 仅适用于单实例测试, 重启后不能保证唯一
+type MemoryNoStore struct {
+	mu       sync.Mutex
+	seq      map[string]int64
+	bindings map[string]string
+}
+
+// NewMemoryNoStore 新建基于内存的单号存储
+func NewMemoryNoStore() *MemoryNoStore {
+	return &MemoryNoStore{
+		seq:      make(map[string]int64),
+		bindings: make(map[string]string),
+	}
+}
+
+// Reserve 预留序号
+func (s *MemoryNoStore) Reserve(scope string, n int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	first := s.seq[scope] + 1
+	s.seq[scope] += n
+
+	return first, nil
+}
+
+// Lookup 读取业务键对应的单号
+func (s *MemoryNoStore) Lookup(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.bindings[key], nil
+}
+
+// Bind 记录业务键对应的单号
+func (s *MemoryNoStore) Bind(key, no string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if old, ok := s.bindings[key]; ok {
+		return old, nil
+	}
+	s.bindings[key] = no
+
+	return no, nil
+}
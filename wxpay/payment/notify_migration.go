@@ -0,0 +1,102 @@
+package payment
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// NotifyMigration 通知地址迁移
+// 新订单使用带版本号的通知地址(如 https://example.com/notify/v2), 已下单的订单仍会通知到旧地址,
+// 迁移期间处理函数同时挂载在新旧地址上, 由 Wrap 按路径中的版本号计数并拒绝已下线的版本
+type NotifyMigration struct {
+	Version string   // 当前版本, 新订单的通知地址使用该版本
+	Accept  []string // 迁移期间仍然接受的旧版本, 空字符串表示不带版本号的原地址
+
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// URL 生成当前版本的通知地址, 版本号以路径的形式追加在地址末尾
+// 需要通知地址令牌时在此基础上调用 SignNotifyURL
+func (m *NotifyMigration) URL(notifyURL string) (string, error) {
+	u, err := url.Parse(notifyURL)
+	if err != nil {
+		return "", err
+	}
+
+	if u.RawQuery != "" {
+		return "", errors.New("通知地址不能携带参数")
+	}
+
+	if m.Version != "" {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + m.Version
+	}
+
+	return u.String(), nil
+}
+
+// 通知请求路径中的版本号, 不带版本号时返回空字符串
+// ok 表示该版本是否仍然接受
+func (m *NotifyMigration) version(req *http.Request) (v string, ok bool) {
+	versions := append([]string{m.Version}, m.Accept...)
+	for _, s := range strings.Split(req.URL.Path, "/") {
+		if s != "" && containsString(versions, s) {
+			return s, true
+		}
+	}
+
+	return "", containsString(versions, "")
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Wrap 按通知地址版本计数, 不再接受的版本应答 404
+func (m *NotifyMigration) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		v, ok := m.version(req)
+		if !ok {
+			m.count("rejected")
+			http.NotFound(res, req)
+			return
+		}
+
+		m.count(v)
+		h.ServeHTTP(res, req)
+	})
+}
+
+func (m *NotifyMigration) count(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.counts == nil {
+		m.counts = make(map[string]int64)
+	}
+	m.counts[key]++
+}
+
+// Stats 各版本收到的通知数
+// 不带版本号的原地址计入空字符串, 被拒绝的通知计入 "rejected"。
+// 旧版本长时间没有通知后即可从 Accept 中移除
+func (m *NotifyMigration) Stats() map[string]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := make(map[string]int64, len(m.counts))
+	for k, v := range m.counts {
+		res[k] = v
+	}
+
+	return res
+}
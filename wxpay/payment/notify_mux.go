@@ -0,0 +1,120 @@
+package payment
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net/http"
+)
+
+// 通知类型, 由 DetectNotify 识别
+const (
+	NotifyNative   = "native"   // 扫码支付模式一回调
+	NotifyTransfer = "transfer" // 含付款单号的通知
+	NotifyRedpack  = "redpack"  // 含红包单号的通知
+	NotifyUnknown  = "unknown"  // 无法识别
+)
+
+// DetectNotify 按通知内容识别通知类型
+// 退款通知包含加密的 req_info; 扫码支付模式一回调包含 product_id 但没有微信订单号;
+// 支付通知包含 transaction_id、total_fee 或代金券字段
+func DetectNotify(body []byte) string {
+	raw, err := parseRawFields(body)
+	if err != nil {
+		return NotifyUnknown
+	}
+
+	has := func(names ...string) bool {
+		for _, name := range names {
+			if _, ok := raw[name]; ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case has("req_info"):
+		return NotifyRefunded
+	case has("product_id") && !has("transaction_id"):
+		return NotifyNative
+	case has("transaction_id", "total_fee", "coupon_fee", "coupon_count"):
+		return NotifyPaid
+	case has("partner_trade_no", "payment_no"):
+		return NotifyTransfer
+	case has("mch_billno", "send_listid"):
+		return NotifyRedpack
+	}
+
+	return NotifyUnknown
+}
+
+// NotifyMux 同一通知地址接收多种通知时按内容分发
+// 商户平台只配置了一个回调地址时使用, 各处理函数与 HandlePaidNotify 等一致
+type NotifyMux struct {
+	Key string // 微信支付密钥, 用于校验支付通知和扫码回调签名、解密退款通知, 不能为空
+
+	Paid     func(PaidNotify) (bool, string)
+	Refunded func(RefundedNotify) (bool, string)
+	Native   func(NativeCallback) (prepayID string, err error)
+
+	// Other 处理其他类型的通知, 为空时应答 FAIL
+	Other func(kind string, body []byte) (bool, string)
+}
+
+// Handle 识别并处理通知
+// 未设置 Key 时不处理任何通知, 直接应答 FAIL; 支付通知签名错误时不调用处理函数
+func (m *NotifyMux) Handle(res http.ResponseWriter, req *http.Request) error {
+	if m.Key == "" {
+		return m.fail(res, errors.New("NotifyMux 未设置支付密钥"))
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	kind := DetectNotify(body)
+	switch {
+	case kind == NotifyPaid && m.Paid != nil:
+		if err = verifyPaidRequest(req, m.Key); err != nil {
+			return m.fail(res, err)
+		}
+		return HandlePaidNotify(res, req, m.Paid)
+	case kind == NotifyRefunded && m.Refunded != nil:
+		return HandleRefundedNotify(res, req, m.Key, m.Refunded)
+	case kind == NotifyNative && m.Native != nil:
+		return HandleNativeCallback(res, req, m.Key, m.Native)
+	case m.Other != nil:
+		return writeMuxReplay(res, newReplay(m.Other(kind, body)))
+	}
+
+	return m.fail(res, errors.New("没有 "+kind+" 类型通知的处理函数"))
+}
+
+// 应答 FAIL 并返回错误
+func (m *NotifyMux) fail(res http.ResponseWriter, err error) error {
+	if werr := writeMuxReplay(res, newReplay(false, err.Error())); werr != nil {
+		return werr
+	}
+
+	return err
+}
+
+// ServeHTTP 实现 http.Handler, 忽略处理错误
+func (m *NotifyMux) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	m.Handle(res, req)
+}
+
+func writeMuxReplay(res http.ResponseWriter, r replay) error {
+	b, err := xml.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	res.WriteHeader(http.StatusOK)
+	_, err = res.Write(b)
+	return err
+}
@@ -0,0 +1,60 @@
+package payment
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNotifyMuxPaid(t *testing.T) {
+	ntf := PaidNotify{
+		AppID:         "wxd930ea5d5a258f4f",
+		MchID:         "10000100",
+		TotalFee:      1,
+		CashFee:       1,
+		TransactionID: "4200000000000000000000000000",
+		OutTradeNo:    "20150806125346",
+	}
+
+	tests := []struct {
+		name    string
+		muxKey  string
+		signKey string
+		called  bool
+	}{
+		{"signed", testKey, testKey, true},
+		{"forged", testKey, "forged", false},
+		{"no key", "", testKey, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := Simulator{Key: tt.signKey}.PaidNotifyBody(ntf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var called bool
+			mux := &NotifyMux{
+				Key: tt.muxKey,
+				Paid: func(PaidNotify) (bool, string) {
+					called = true
+					return true, "OK"
+				},
+			}
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/notify", bytes.NewReader(body))
+			err = mux.Handle(rec, req)
+
+			if called != tt.called || (err == nil) != tt.called {
+				t.Fatalf("called = %v, err = %v", called, err)
+			}
+			if got := strings.Contains(rec.Body.String(), "SUCCESS"); got != tt.called {
+				t.Fatalf("replay = %s", rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package payment
+
+import (
+	"sync"
+	"time"
+)
+
+// 通知处理失败原因, 作为 return_msg 的前缀返回给微信并用于统计
+const (
+	FailReasonOrderNotFound  = "ORDER_NOT_FOUND" // 找不到本地订单
+	FailReasonAmountMismatch = "AMOUNT_MISMATCH" // 金额与本地订单不一致
+	FailReasonStateConflict  = "STATE_CONFLICT"  // 本地订单状态不允许处理, 如已关闭
+	FailReasonTemporary      = "TEMPORARY"       // 临时故障(数据库不可用等), 等待微信重发
+	FailReasonInternal       = "INTERNAL"        // 未分类的错误
+)
+
+// NotifyFailure 带失败原因的通知处理错误
+type NotifyFailure struct {
+	Reason string // 失败原因, 见 FailReason 常量, 也可以自定义
+	Err    error
+}
+
+func (e *NotifyFailure) Error() string {
+	if e.Err == nil {
+		return e.Reason
+	}
+
+	return e.Reason + ": " + e.Err.Error()
+}
+
+// FailNotify 创建带失败原因的通知处理错误
+func FailNotify(reason string, err error) error {
+	return &NotifyFailure{Reason: reason, Err: err}
+}
+
+// 处理成功但需要关注的情况
+type notifyWarning struct {
+	msg string
+}
+
+func (w *notifyWarning) Error() string {
+	return w.msg
+}
+
+// WarnNotify 处理成功但需要记录的警告, 如重复通知、金额使用了代金券等
+// 处理函数返回该错误时仍应答 SUCCESS
+func WarnNotify(msg string) error {
+	return &notifyWarning{msg: msg}
+}
+
+// NotifyOutcome 一次通知的处理结果
+type NotifyOutcome struct {
+	Kind       string    // NotifyPaid/NotifyRefunded
+	OutTradeNo string    // 商户订单号
+	ID         string    // 微信订单号或微信退款单号
+	OK         bool      // 是否应答 SUCCESS
+	Reason     string    // 失败原因, 成功时为空
+	Warning    string    // 成功但有警告时的说明
+	Message    string    // 应答的 return_msg
+	Time       time.Time // 处理时间
+}
+
+// NotifyOutcomes 按处理结果应答通知
+// 处理函数返回 error 而不是 (bool, string): nil 应答 SUCCESS, WarnNotify 应答 SUCCESS 并记录警告,
+// 其他错误按原因应答 FAIL, return_msg 为 "原因: 错误信息"
+type NotifyOutcomes struct {
+	// Classify 没有使用 FailNotify 的错误的失败原因, 为空时为 FailReasonInternal
+	Classify func(error) string
+
+	// Record 记录每次处理结果, 用于后续分析, 为空则只计数
+	Record func(NotifyOutcome)
+
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// Paid 转换支付结果通知处理函数, 可以作为 HandlePaidNotify 的处理函数
+func (o *NotifyOutcomes) Paid(fn func(PaidNotify) error) func(PaidNotify) (bool, string) {
+	return func(ntf PaidNotify) (bool, string) {
+		return o.reply(NotifyOutcome{Kind: NotifyPaid, OutTradeNo: ntf.OutTradeNo, ID: ntf.TransactionID}, fn(ntf))
+	}
+}
+
+// Refunded 转换退款结果通知处理函数, 可以作为 HandleRefundedNotify 的处理函数
+func (o *NotifyOutcomes) Refunded(fn func(RefundedNotify) error) func(RefundedNotify) (bool, string) {
+	return func(ntf RefundedNotify) (bool, string) {
+		return o.reply(NotifyOutcome{Kind: NotifyRefunded, OutTradeNo: ntf.OutTradeNo, ID: ntf.RefundID}, fn(ntf))
+	}
+}
+
+// 根据处理函数的错误生成应答并记录
+func (o *NotifyOutcomes) reply(out NotifyOutcome, err error) (bool, string) {
+	out.Time = time.Now()
+
+	switch e := err.(type) {
+	case nil:
+		out.OK, out.Message = true, "OK"
+	case *notifyWarning:
+		out.OK, out.Message, out.Warning = true, "OK", e.msg
+	case *NotifyFailure:
+		out.Reason, out.Message = e.Reason, e.Error()
+	default:
+		out.Reason = FailReasonInternal
+		if o.Classify != nil {
+			if r := o.Classify(err); r != "" {
+				out.Reason = r
+			}
+		}
+		out.Message = out.Reason + ": " + err.Error()
+	}
+
+	key := out.Reason
+	switch {
+	case out.Warning != "":
+		key = "WARNING"
+	case out.OK:
+		key = "SUCCESS"
+	}
+
+	o.mu.Lock()
+	if o.counts == nil {
+		o.counts = make(map[string]int64)
+	}
+	o.counts[key]++
+	o.mu.Unlock()
+
+	if o.Record != nil {
+		o.Record(out)
+	}
+
+	return out.OK, out.Message
+}
+
+// Counts 按结果统计的通知数, 成功为 "SUCCESS", 成功但有警告为 "WARNING", 失败为失败原因
+func (o *NotifyOutcomes) Counts() map[string]int64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	res := make(map[string]int64, len(o.counts))
+	for k, v := range o.counts {
+		res[k] = v
+	}
+
+	return res
+}
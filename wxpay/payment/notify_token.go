@@ -0,0 +1,87 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// 通知地址令牌长度
+const notifyTokenLength = 32
+
+// NotifyToken 根据商户订单号生成通知地址令牌
+// 通知地址不能携带参数, 令牌以路径的形式追加在通知地址末尾
+//
+// @secret 生成令牌的密钥, 不要与支付密钥相同
+// @outTradeNo 商户订单号
+func NotifyToken(secret, outTradeNo string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(outTradeNo))
+
+	return hex.EncodeToString(mac.Sum(nil))[:notifyTokenLength]
+}
+
+// SignNotifyURL 在通知地址路径末尾追加令牌
+// 如 https://example.com/notify 变为 https://example.com/notify/{token}
+func SignNotifyURL(notifyURL, secret, outTradeNo string) (string, error) {
+	u, err := url.Parse(notifyURL)
+	if err != nil {
+		return "", err
+	}
+
+	if u.RawQuery != "" {
+		return "", errors.New("通知地址不能携带参数")
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + NotifyToken(secret, outTradeNo)
+
+	return u.String(), nil
+}
+
+// VerifyNotifyToken 校验通知请求路径中的令牌
+func VerifyNotifyToken(req *http.Request, secret, outTradeNo string) error {
+	token := path.Base(req.URL.Path)
+	if !hmac.Equal([]byte(token), []byte(NotifyToken(secret, outTradeNo))) {
+		return errors.New("通知地址令牌错误")
+	}
+
+	return nil
+}
+
+// HandlePaidNotifyWithToken 处理支付结果通知, 先校验签名再校验通知地址令牌
+// 令牌只是额外的校验, 签名或令牌错误时都不会调用处理函数, 直接返回 FAIL
+//
+// @key 微信支付 KEY
+// @secret 生成令牌的密钥
+func HandlePaidNotifyWithToken(res http.ResponseWriter, req *http.Request, key, secret string, fn func(PaidNotify) (bool, string)) error {
+	if err := verifyPaidRequest(req, key); err != nil {
+		if werr := writeMuxReplay(res, newReplay(false, err.Error())); werr != nil {
+			return werr
+		}
+		return err
+	}
+
+	return HandlePaidNotify(res, req, func(ntf PaidNotify) (bool, string) {
+		if err := VerifyNotifyToken(req, secret, ntf.OutTradeNo); err != nil {
+			return false, err.Error()
+		}
+
+		return fn(ntf)
+	})
+}
+
+// HandleRefundedNotifyWithToken 处理退款结果通知, 并校验通知地址令牌
+func HandleRefundedNotifyWithToken(res http.ResponseWriter, req *http.Request, key, secret string, fn func(RefundedNotify) (bool, string)) error {
+	return HandleRefundedNotify(res, req, key, func(ntf RefundedNotify) (bool, string) {
+		if err := VerifyNotifyToken(req, secret, ntf.OutTradeNo); err != nil {
+			return false, err.Error()
+		}
+
+		return fn(ntf)
+	})
+}
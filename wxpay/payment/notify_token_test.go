@@ -0,0 +1,61 @@
+package payment
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandlePaidNotifyWithToken(t *testing.T) {
+	const secret = "notify-secret"
+	ntf := PaidNotify{
+		AppID:         "wxd930ea5d5a258f4f",
+		MchID:         "10000100",
+		TotalFee:      100,
+		CashFee:       100,
+		TransactionID: "4200000000000000000000000000",
+		OutTradeNo:    "20150806125346",
+	}
+
+	notifyURL, err := SignNotifyURL("https://example.com/notify", secret, ntf.OutTradeNo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		signKey string
+		called  bool
+	}{
+		{"valid", notifyURL, testKey, true},
+		{"valid token bad signature", notifyURL, "forged", false},
+		{"bad token", "https://example.com/notify/00000000000000000000000000000000", testKey, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := Simulator{Key: tt.signKey}.PaidNotifyBody(ntf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var called bool
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tt.url, bytes.NewReader(body))
+			HandlePaidNotifyWithToken(rec, req, testKey, secret, func(PaidNotify) (bool, string) {
+				called = true
+				return true, "OK"
+			})
+
+			if called != tt.called {
+				t.Fatalf("called = %v, want %v", called, tt.called)
+			}
+			if got := strings.Contains(rec.Body.String(), "SUCCESS"); got != tt.called {
+				t.Fatalf("replay = %s", rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package payment
+
+import (
+	"bytes"
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// 通知去重使用的 SQL, 按微信订单号去重
+// 默认使用 ? 占位符(MySQL/SQLite), PostgreSQL 需要改为 $1, $2。去重表需要事先创建:
+//
+//	CREATE TABLE wxpay_notify (id VARCHAR(64) PRIMARY KEY, created_at TIMESTAMP NOT NULL)
+var (
+	NotifyDedupeQuery  = "SELECT 1 FROM wxpay_notify WHERE id = ?"
+	NotifyDedupeInsert = "INSERT INTO wxpay_notify (id, created_at) VALUES (?, ?)"
+)
+
+// HandlePaidNotifyTx 在数据库事务中处理支付结果通知
+// 校验签名后开启事务, 写入去重记录并调用 fn, 事务提交成功才应答 SUCCESS;
+// fn 返回错误或提交失败时回滚并应答 FAIL, 微信会重新发送通知。
+// 已处理过的通知(去重表中已有该微信订单号)直接应答 SUCCESS, 不再调用 fn。
+// 并发收到同一通知时, 后提交的事务因主键冲突失败, 重发时按已处理应答
+//
+// @key 微信支付 KEY, 用于校验签名
+func HandlePaidNotifyTx(res http.ResponseWriter, req *http.Request, db *sql.DB, key string, fn func(*sql.Tx, PaidNotify) error) error {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	raw, err := parseRawFields(body)
+	if err != nil {
+		return err
+	}
+
+	return HandlePaidNotify(res, req, func(ntf PaidNotify) (bool, string) {
+		if err := verifySign(raw, key); err != nil {
+			return false, err.Error()
+		}
+
+		if err := paidNotifyTx(req, db, ntf, fn); err != nil {
+			return false, err.Error()
+		}
+
+		return true, "OK"
+	})
+}
+
+func paidNotifyTx(req *http.Request, db *sql.DB, ntf PaidNotify, fn func(*sql.Tx, PaidNotify) error) (err error) {
+	tx, err := db.BeginTx(req.Context(), nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var exists int
+	switch err = tx.QueryRow(NotifyDedupeQuery, ntf.TransactionID).Scan(&exists); err {
+	case nil:
+		// 已处理过
+		return tx.Rollback()
+	case sql.ErrNoRows:
+	default:
+		return
+	}
+
+	if _, err = tx.Exec(NotifyDedupeInsert, ntf.TransactionID, time.Now()); err != nil {
+		return
+	}
+
+	if err = fn(tx, ntf); err != nil {
+		return
+	}
+
+	return tx.Commit()
+}
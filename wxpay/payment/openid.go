@@ -0,0 +1,103 @@
+package payment
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+// openid 由数字、大小写字母、- 和 _ 组成, 通常为28位
+var openIDPattern = regexp.MustCompile(`^[0-9A-Za-z_-]{16,64}$`)
+
+// ErrOpenIDMismatch openid 不属于下单使用的 APPID
+// 常见于小程序下单时使用了公众号的 openid, 或服务商模式混用 openid 和 sub_openid
+var ErrOpenIDMismatch = errors.New("openid 与 appid 不匹配")
+
+// OpenIDMismatchError openid 与 APPID 不匹配的详细信息
+type OpenIDMismatchError struct {
+	AppID  string
+	OpenID string // 已遮盖
+	Cause  error  // 微信返回的错误, 下单前检查出时为空
+}
+
+func (e *OpenIDMismatchError) Error() string {
+	return "openid " + e.OpenID + " 不属于 APPID " + e.AppID + ", 请检查是否使用了公众号或其他小程序的 openid"
+}
+
+// Unwrap 返回 ErrOpenIDMismatch
+func (e *OpenIDMismatchError) Unwrap() error {
+	return ErrOpenIDMismatch
+}
+
+// IsOpenIDMismatch 是否为 openid 与 APPID 不匹配错误
+func IsOpenIDMismatch(err error) bool {
+	if err == ErrOpenIDMismatch {
+		return true
+	}
+
+	_, ok := err.(*OpenIDMismatchError)
+	return ok
+}
+
+// ValidateOpenID 检查 openid 或 sub_openid 格式
+// 错误信息中的 openid 已遮盖, 可以直接写入日志
+func ValidateOpenID(openID string) error {
+	if openID == "" {
+		return errors.New("openid 不能为空")
+	}
+
+	if !openIDPattern.MatchString(openID) {
+		return errors.New("openid 格式错误: " + util.MaskOpenID(openID))
+	}
+
+	return nil
+}
+
+// Validate 检查用户标识格式
+func (p PartnerPayer) Validate() error {
+	if _, err := p.SignAppID(); err != nil {
+		return err
+	}
+
+	if p.SubOpenID != "" {
+		return ValidateOpenID(p.SubOpenID)
+	}
+
+	return ValidateOpenID(p.OpenID)
+}
+
+// 下单前检查 openid
+// 同一 APPID 下的 openid 前缀相同, 配置了 Config.OpenIDPrefixes 时按前缀判断所属 APPID
+func (cfg *Config) checkOpenID(appID, openID string) error {
+	if openID == "" {
+		return nil
+	}
+
+	if err := ValidateOpenID(openID); err != nil {
+		return err
+	}
+
+	if prefix, ok := cfg.OpenIDPrefixes[appID]; ok && !strings.HasPrefix(openID, prefix) {
+		return &OpenIDMismatchError{AppID: appID, OpenID: util.MaskOpenID(openID)}
+	}
+
+	return nil
+}
+
+// 把微信返回的 appid 和 openid 不匹配错误转换为 *OpenIDMismatchError
+func openIDMismatch(err error, appID, openID string) error {
+	e, ok := err.(*Error)
+	if !ok {
+		return err
+	}
+
+	for _, msg := range []string{e.ReturnMsg, e.ErrCodeDes} {
+		if strings.Contains(msg, "openid") && strings.Contains(msg, "不匹配") {
+			return &OpenIDMismatchError{AppID: appID, OpenID: util.MaskOpenID(openID), Cause: err}
+		}
+	}
+
+	return err
+}
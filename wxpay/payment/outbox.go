@@ -0,0 +1,284 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+// 外发请求状态
+const (
+	OutboxPending = "PENDING" // 已记录, 结果未确认
+	OutboxDone    = "DONE"    // 已确认成功
+	OutboxFailed  = "FAILED"  // 已确认失败, 重试也无法成功
+)
+
+// OutboxEntry 外发请求记录
+// 退款和企业付款在发送前记录, 结果确认后更新状态
+type OutboxEntry struct {
+	ID        string      // 退款为商户退款单号, 企业付款为商户订单号
+	Operation string      // AuditRefund 或 AuditTransfer
+	Refund    *Refunder   `json:",omitempty"`
+	Transfer  *Transferer `json:",omitempty"`
+	State     string
+	Attempts  int
+	Error     string // 最近一次失败原因
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// OutboxStore 外发请求存储
+type OutboxStore interface {
+	// SaveOutbox 保存记录, 已存在时覆盖
+	SaveOutbox(OutboxEntry) error
+	// GetOutbox 读取记录, 不存在时返回 nil
+	GetOutbox(id string) (*OutboxEntry, error)
+	// PendingOutbox 读取所有未确认结果的记录, 按创建时间升序
+	PendingOutbox() ([]OutboxEntry, error)
+}
+
+// Outbox 退款和企业付款的先存后发
+// 请求发送前先记录, 调用中进程崩溃或网络超时时, 启动后通过 Recover 重新确认结果。
+// 退款和企业付款使用相同单号重复请求只会出款一次, 因此恢复时可以安全重发
+type Outbox struct {
+	Client *Client
+	Store  OutboxStore
+
+	// Run 的恢复间隔, 默认1分钟
+	Interval time.Duration
+	// OnRecover Run 每次恢复后调用, 为空则忽略
+	OnRecover func(OutboxReport, error)
+
+	loop util.Loop
+}
+
+// NewOutbox 新建先存后发
+func NewOutbox(c *Client, store OutboxStore) *Outbox {
+	return &Outbox{Client: c, Store: store}
+}
+
+// OutboxReport 恢复结果
+type OutboxReport struct {
+	Done    int // 已确认成功
+	Failed  int // 已确认失败
+	Pending int // 仍未确认, 下次恢复时继续
+}
+
+// Refund 记录后申请退款
+func (o *Outbox) Refund(ctx context.Context, r Refunder, opts ...CallOption) (res RefundedResponse, err error) {
+	if r.OutRefundNo == "" {
+		err = errors.New("out_refund_no 不能为空")
+		return
+	}
+
+	e, err := o.begin(r.OutRefundNo, OutboxEntry{Operation: AuditRefund, Refund: &r})
+	if err != nil {
+		return
+	}
+
+	res, err = o.Client.Refund(ctx, r, opts...)
+	err = o.finish(e, err)
+	return
+}
+
+// Transfer 记录后企业付款
+func (o *Outbox) Transfer(ctx context.Context, t Transferer, opts ...CallOption) (res TransferResponse, err error) {
+	if t.OutTradeNo == "" {
+		err = errors.New("partner_trade_no 不能为空")
+		return
+	}
+
+	e, err := o.begin(t.OutTradeNo, OutboxEntry{Operation: AuditTransfer, Transfer: &t})
+	if err != nil {
+		return
+	}
+
+	res, err = o.Client.Transfer(ctx, t, opts...)
+	err = o.finish(e, err)
+	return
+}
+
+// Recover 重新确认未完成的请求, 应在启动时调用
+// 企业付款先查询结果, 查不到时重发; 退款直接使用原单号重发
+func (o *Outbox) Recover(ctx context.Context) (report OutboxReport, err error) {
+	list, err := o.Store.PendingOutbox()
+	if err != nil {
+		return
+	}
+
+	for _, e := range list {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		var callErr error
+		switch {
+		case e.Refund != nil:
+			_, callErr = o.Client.Refund(ctx, *e.Refund)
+		case e.Transfer != nil:
+			callErr = o.recoverTransfer(ctx, *e.Transfer)
+		default:
+			callErr = &Error{ReturnCode: "SUCCESS", ResultCode: "FAIL", ErrCode: "PARAM_ERROR", ErrCodeDes: "记录中没有请求数据"}
+		}
+
+		// 保存失败时记录仍为未确认, 下次恢复时重发同一单号是安全的
+		e.Attempts++
+		o.finish(&e, callErr)
+
+		switch e.State {
+		case OutboxDone:
+			report.Done++
+		case OutboxFailed:
+			report.Failed++
+		default:
+			report.Pending++
+		}
+	}
+
+	return
+}
+
+// Run 按间隔持续恢复未完成的请求, 直到 ctx 取消, 实现 util.Runner
+func (o *Outbox) Run(ctx context.Context) error {
+	o.loop.Interval = o.Interval
+
+	return o.loop.Run(ctx, func(ctx context.Context) error {
+		report, err := o.Recover(ctx)
+		if o.OnRecover != nil {
+			o.OnRecover(report, err)
+		}
+		return err
+	}, nil)
+}
+
+// Report 运行情况, 每次恢复计为一次执行
+func (o *Outbox) Report() util.RunReport {
+	return o.loop.Report()
+}
+
+// 企业付款先查询, 处理中视为未确认, 查不到时重发
+func (o *Outbox) recoverTransfer(ctx context.Context, t Transferer) error {
+	info, err := o.Client.TransferInfo(ctx, TransferInfo{AppID: t.AppID, MchID: t.MchID, OutTradeNo: t.OutTradeNo})
+	switch {
+	case ErrCodeOf(err) == "NOT_FOUND":
+		_, err = o.Client.Transfer(ctx, t)
+		return err
+	case err != nil:
+		return err
+	}
+
+	switch info.Status {
+	case "SUCCESS":
+		return nil
+	case "FAILED":
+		return &Error{ReturnCode: "SUCCESS", ResultCode: "FAIL", ErrCode: "TRANSFER_FAILED", ErrCodeDes: info.Reason}
+	default:
+		return errors.New("企业付款处理中")
+	}
+}
+
+// 发送前记录, 已确认的请求直接返回错误, 避免重复出款
+func (o *Outbox) begin(id string, e OutboxEntry) (*OutboxEntry, error) {
+	old, err := o.Store.GetOutbox(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if old != nil {
+		if old.State != OutboxPending {
+			return nil, errors.New("单号 " + id + " 已处理, 状态为 " + old.State)
+		}
+		e.CreatedAt = old.CreatedAt
+		e.Attempts = old.Attempts
+	} else {
+		e.CreatedAt = now
+	}
+
+	e.ID = id
+	e.State = OutboxPending
+	e.Attempts++
+	e.UpdatedAt = now
+
+	return &e, o.Store.SaveOutbox(e)
+}
+
+// 根据调用结果更新状态
+// 只有确定失败(重试也无法成功或审批拒绝)才标记为失败, 网络错误等保持未确认
+func (o *Outbox) finish(e *OutboxEntry, callErr error) error {
+	e.UpdatedAt = time.Now()
+	e.Error = ""
+
+	_, denied := callErr.(*DeniedError)
+	switch {
+	case callErr == nil:
+		e.State = OutboxDone
+	case IsPermanent(callErr) || denied || ErrCodeOf(callErr) == "TRANSFER_FAILED":
+		e.State = OutboxFailed
+		e.Error = callErr.Error()
+	default:
+		e.Error = callErr.Error()
+	}
+
+	if err := o.Store.SaveOutbox(*e); err != nil && callErr == nil {
+		return err
+	}
+
+	return callErr
+}
+
+// MemoryOutboxStore 基于内存的外发请求存储, 适用于单机测试
+type MemoryOutboxStore struct {
+	mu      sync.RWMutex
+	entries map[string]OutboxEntry
+}
+
+// NewMemoryOutboxStore 新建基于内存的外发请求存储
+func NewMemoryOutboxStore() *MemoryOutboxStore {
+	return &MemoryOutboxStore{entries: make(map[string]OutboxEntry)}
+}
+
+// SaveOutbox 保存记录
+func (s *MemoryOutboxStore) SaveOutbox(e OutboxEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[e.ID] = e
+	return nil
+}
+
+// GetOutbox 读取记录
+func (s *MemoryOutboxStore) GetOutbox(id string) (*OutboxEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.entries[id]
+	if !ok {
+		return nil, nil
+	}
+
+	return &e, nil
+}
+
+// PendingOutbox 读取未确认结果的记录
+func (s *MemoryOutboxStore) PendingOutbox() ([]OutboxEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var list []OutboxEntry
+	for _, e := range s.entries {
+		if e.State == OutboxPending {
+			list = append(list, e)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+
+	return list, nil
+}
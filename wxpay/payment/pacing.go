@@ -0,0 +1,143 @@
+package payment
+
+import (
+	"context"
+	"encoding/xml"
+	"sync"
+	"time"
+)
+
+// 限频后的请求间隔
+const (
+	pacingMin = time.Second // 首次限频后的间隔
+	pacingMax = time.Minute // 间隔上限
+)
+
+// 表示请求频率超限的错误代码
+var freqLimitCodes = map[string]bool{
+	"FREQ_LIMIT":         true,
+	"FREQUENCY_LIMITED":  true,
+	"RATELIMIT":          true,
+	"RATELIMIT_EXCEEDED": true,
+}
+
+// PacingStats 接口限频节流状态
+type PacingStats struct {
+	Interval time.Duration // 当前请求间隔, 为0表示未节流
+	Limited  int64         // 收到限频错误的次数
+	Waited   time.Duration // 因节流累计等待的时间
+}
+
+// 按接口的请求节流
+// 收到 FREQ_LIMIT 等错误后对该接口设置最小请求间隔, 此后每次限频间隔加倍(最长1分钟),
+// 每次成功的请求间隔减半, 低于1秒时取消节流
+type apiPacer struct {
+	mu    sync.Mutex
+	apis  map[string]*apiPace
+	stats map[string]PacingStats
+}
+
+type apiPace struct {
+	interval time.Duration
+	next     time.Time // 下次允许请求的时间
+}
+
+// 等待到允许请求的时间
+func (p *apiPacer) wait(ctx context.Context, api string) error {
+	p.mu.Lock()
+	a := p.apis[api]
+	if a == nil || a.interval == 0 {
+		p.mu.Unlock()
+		return nil
+	}
+
+	now := time.Now()
+	at := a.next
+	if at.Before(now) {
+		at = now
+	}
+	a.next = at.Add(a.interval)
+	d := at.Sub(now)
+
+	st := p.stats[api]
+	st.Waited += d
+	p.stats[api] = st
+	p.mu.Unlock()
+
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// 根据应答调整请求间隔
+func (p *apiPacer) observe(api string, limited bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	a := p.apis[api]
+	if !limited && (a == nil || a.interval == 0) {
+		return
+	}
+
+	if p.apis == nil {
+		p.apis = make(map[string]*apiPace)
+		p.stats = make(map[string]PacingStats)
+	}
+	if a == nil {
+		a = new(apiPace)
+		p.apis[api] = a
+	}
+
+	st := p.stats[api]
+	if limited {
+		st.Limited++
+		a.interval *= 2
+		if a.interval < pacingMin {
+			a.interval = pacingMin
+		}
+		if a.interval > pacingMax {
+			a.interval = pacingMax
+		}
+		a.next = time.Now().Add(a.interval)
+	} else {
+		a.interval /= 2
+		if a.interval < pacingMin {
+			a.interval = 0
+		}
+	}
+	st.Interval = a.interval
+	p.stats[api] = st
+}
+
+// Pacing 返回各接口的限频节流状态, 只包含曾经被限频的接口
+func (c *Client) Pacing() map[string]PacingStats {
+	c.pacer.mu.Lock()
+	defer c.pacer.mu.Unlock()
+
+	res := make(map[string]PacingStats, len(c.pacer.stats))
+	for api, st := range c.pacer.stats {
+		res[api] = st
+	}
+
+	return res
+}
+
+// 应答是否为限频错误
+func isFreqLimited(data []byte) bool {
+	var v struct {
+		ErrCode string `xml:"err_code"`
+	}
+	xml.Unmarshal(data, &v)
+
+	return freqLimitCodes[v.ErrCode]
+}
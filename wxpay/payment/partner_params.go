@@ -0,0 +1,49 @@
+package payment
+
+import "errors"
+
+// PartnerPayer 服务商模式下单使用的 APPID 和用户标识
+// 前端调起支付时签名使用的 APPID 必须与用户标识所属的 APPID 一致, 否则前端会报签名错误
+type PartnerPayer struct {
+	AppID     string // 服务商 APPID (appid)
+	SubAppID  string // 子商户 APPID (sub_appid)
+	OpenID    string // 用户在服务商 APPID 下的 openid
+	SubOpenID string // 用户在子商户 APPID 下的 sub_openid
+}
+
+// SignAppID 返回前端调起支付时签名使用的 APPID
+// 使用 sub_openid 下单时为 sub_appid, 使用 openid 下单时为服务商 appid
+func (p PartnerPayer) SignAppID() (string, error) {
+	switch {
+	case p.OpenID != "" && p.SubOpenID != "":
+		return "", errors.New("openid 和 sub_openid 只能填写一个")
+	case p.SubOpenID != "":
+		if p.SubAppID == "" {
+			return "", errors.New("使用 sub_openid 下单时必须填写 sub_appid")
+		}
+		return p.SubAppID, nil
+	case p.OpenID != "":
+		if p.AppID == "" {
+			return "", errors.New("使用 openid 下单时必须填写服务商 appid")
+		}
+		return p.AppID, nil
+	default:
+		return "", errors.New("openid 和 sub_openid 必须填写一个")
+	}
+}
+
+// GetPartnerParams 服务商模式获取支付参数
+// 按下单时的用户标识选择签名 APPID, 签名密钥为服务商的支付密钥
+//
+// @p 下单时使用的 APPID 和用户标识
+// @key 服务商支付密钥
+// @nonceStr 统一下单得到的 nonceStr
+// @prepayID 统一下单得到的 prepayID
+func GetPartnerParams(p PartnerPayer, key, nonceStr, prepayID string) (Params, error) {
+	appID, err := p.SignAppID()
+	if err != nil {
+		return Params{}, err
+	}
+
+	return GetParams(appID, key, nonceStr, prepayID)
+}
@@ -6,8 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/beevik/etree"
-	"github.com/medivhzhan/weapp/util"
-	"io/ioutil"
+	"github.com/wanghuobo/weapp/util"
 	"net/http"
 	"strconv"
 	"time"
@@ -33,124 +32,117 @@ type Params struct {
 // Order 商户统一订单
 type Order struct {
 	// 必填 ...
-	AppID      string `xml:"appid"`        // 小程序ID
-	MchID      string `xml:"mch_id"`       // 商户号
-	TotalFee   int    `xml:"total_fee"`    // 标价金额
-	NotifyURL  string `xml:"notify_url"`   // 异步接收微信支付结果通知的回调地址，通知url必须为外网可访问的url，不能携带参数。
-	OpenID     string `xml:"openid"`       // 下单用户ID
-	Body       string `xml:"body"`         // 商品描述
-	OutTradeNo string `xml:"out_trade_no"` // 商户订单号
+	AppID      string `sign:"appid"`              // 小程序ID
+	MchID      string `sign:"mch_id"`             // 商户号
+	TotalFee   int    `sign:"total_fee"`          // 标价金额
+	NotifyURL  string `sign:"notify_url"`         // 异步接收微信支付结果通知的回调地址，通知url必须为外网可访问的url，不能携带参数。
+	OpenID     string `sign:"openid,omitzero"`    // 下单用户ID, JSAPI 必填
+	Body       string `sign:"body,cdata,max=128"` // 商品描述, 不超过128字节
+	OutTradeNo string `sign:"out_trade_no"`       // 商户订单号
 
 	// 选填 ...
-	IP        string    `xml:"spbill_create_ip,omitempty"` // 终端IP
-	NoCredit  bool      `xml:"-"`                          // 上传此参数 no_credit 可限制用户不能使用信用卡支付
-	StartedAt time.Time `xml:"-"`                          // 交易起始时间 格式为yyyyMMddHHmmss
-	ExpiredAt time.Time `xml:"-"`                          // 交易结束时间 订单失效时间 格式为yyyyMMddHHmmss
-	Tag       string    `xml:"goods_tag,omitempty"`        // 订单优惠标记，使用代金券或立减优惠功能时需要的参数，
-	Detail    string    `xml:"detail,omitempty"`           // 商品详情
-	Attach    string    `xml:"attach,omitempty"`           // 附加数据
-}
-
-// 下单所需所有数据
-type order struct {
-	XMLName xml.Name `xml:"xml"`
-	Order
-	Sign      string `xml:"sign"`                // 签名
-	NonceStr  string `xml:"nonce_str"`           // 随机字符串
-	TradeType string `xml:"trade_type"`          // 小程序取值如下: JSAPI
-	SignType  string `xml:"sign_type,omitempty"` // 签名类型: 目前支持HMAC-SHA256和MD5，默认为MD5
-
-	NoCredit  string `xml:"limit_pay,omitempty"`   // 上传此参数 no_credit 可限制用户不能使用信用卡支付
-	StartedAt string `xml:"time_start,omitempty"`  // 交易起始时间 格式为yyyyMMddHHmmss
-	ExpiredAt string `xml:"time_expire,omitempty"` // 交易结束时间 订单失效时间 格式为yyyyMMddHHmmss
+	IP        string    `sign:"spbill_create_ip"`               // 终端IP, 为空时使用本机IP
+	NoCredit  bool      `sign:"-"`                              // 上传此参数 no_credit 可限制用户不能使用信用卡支付
+	StartedAt time.Time `sign:"time_start,omitzero"`            // 交易起始时间 格式为yyyyMMddHHmmss
+	ExpiredAt time.Time `sign:"time_expire,omitzero"`           // 交易结束时间 订单失效时间 格式为yyyyMMddHHmmss
+	Tag       string    `sign:"goods_tag,omitzero"`             // 订单优惠标记，使用代金券或立减优惠功能时需要的参数，
+	Detail    string    `sign:"detail,omitzero,cdata,max=6000"` // 商品详情, 不超过6000字节
+	Attach    string    `sign:"attach,omitzero,cdata,max=127"`  // 附加数据, 不超过127字节
+	TradeType string    `sign:"trade_type"`                     // 交易类型: JSAPI(默认) | MWEB
+	ProductID string    `sign:"product_id,omitzero"`            // 商品ID, 扫码支付(NATIVE)必填
+	// 场景信息: H5 支付必填, JSON 格式, 如 {"h5_info": {"type":"Wap","wap_url": "https://pay.qq.com","wap_name": "腾讯充值"}}
+	SceneInfo string `sign:"scene_info,omitzero"`
+
+	// 服务商模式的子商户 APPID 和子商户号, 此时 AppID 和 MchID 为服务商的
+	SubAppID string `sign:"sub_appid,omitzero"`
+	SubMchID string `sign:"sub_mch_id,omitzero"`
+
+	// 订单有效期, 下单时按北京时间设置 time_start 为当前时间, time_expire 为当前时间加有效期
+	// 付款码支付不少于1分钟, 其余不少于5分钟, 且不超过2小时, 不能与 StartedAt/ExpiredAt 同时设置
+	ExpireIn time.Duration `sign:"-"`
 }
 
 // 请求前准备
-func (o *Order) prepare(key string) (order, error) {
-
-	od := order{
-		Order:     *o,
-		TradeType: "JSAPI",
-		SignType:  "MD5",
-		NonceStr:  util.RandomString(32),
+// 请求参数由 Order 字段的 sign 标签生成, 新增参数只需要在 Order 中添加带标签的字段
+func (o *Order) prepare(key, signType string) (fields, error) {
+	od := *o
+	if od.TradeType == "" {
+		od.TradeType = TradeTypeJSAPI
 	}
 
-	signData := map[string]string{
-		"appid":        od.AppID,
-		"body":         od.Body,
-		"mch_id":       od.MchID,
-		"nonce_str":    od.NonceStr,
-		"notify_url":   od.NotifyURL,
-		"openid":       od.OpenID,
-		"out_trade_no": od.OutTradeNo,
-		"total_fee":    strconv.Itoa(od.TotalFee),
-		"trade_type":   od.TradeType,
-		"sign_type":    od.SignType,
-	}
-
-	if o.IP == "" {
-		ip, err := util.FetchIP()
+	if od.IP == "" {
+		ip, err := localIP()
 		if err != nil {
-			return od, err
+			return nil, err
 		}
 
-		od.IP = ip.String()
+		od.IP = ip
 	}
-	signData["spbill_create_ip"] = od.IP
 
-	if !o.StartedAt.IsZero() {
-		od.StartedAt = o.StartedAt.Format(paymentTimeFormat)
-		signData["time_start"] = od.StartedAt
+	if err := od.applyExpireIn(time.Now()); err != nil {
+		return nil, err
 	}
 
-	if !o.ExpiredAt.IsZero() {
-		od.ExpiredAt = o.ExpiredAt.Format(paymentTimeFormat)
-		signData["time_expire"] = od.ExpiredAt
+	var extra []field
+	if od.NoCredit {
+		extra = append(extra, field{name: "limit_pay", value: "no_credit"})
 	}
 
-	if o.Attach != "" {
-		signData["attach"] = od.Attach
-	}
+	return signedFields(od, key, signType, extra...)
+}
 
-	if o.Detail != "" {
-		signData["detail"] = od.Detail
+// 订单有效期限制
+const (
+	minExpireIn         = 5 * time.Minute
+	minMicropayExpireIn = time.Minute
+	maxExpireIn         = 2 * time.Hour // prepay_id 有效期为2小时, 更长的有效期没有意义
+)
+
+// 微信支付使用北京时间
+var cst = time.FixedZone("CST", 8*3600)
+
+// 按有效期设置交易起止时间
+func (o *Order) applyExpireIn(now time.Time) error {
+	if o.ExpireIn == 0 {
+		return nil
 	}
 
-	if o.Tag != "" {
-		signData["goods_tag"] = od.Tag
+	if !o.StartedAt.IsZero() || !o.ExpiredAt.IsZero() {
+		return errors.New("ExpireIn 不能与 StartedAt/ExpiredAt 同时设置")
 	}
 
-	if o.NoCredit {
-		od.NoCredit = "no_credit"
-		signData["limit_pay"] = od.NoCredit
+	min := minExpireIn
+	if o.TradeType == TradeTypeMicropay {
+		min = minMicropayExpireIn
 	}
 
-	sign, err := util.SignByMD5(signData, key)
-	if err != nil {
-		return od, err
+	switch {
+	case o.ExpireIn < min:
+		return fmt.Errorf("订单有效期不能少于%v", min)
+	case o.ExpireIn > maxExpireIn:
+		return fmt.Errorf("订单有效期不能超过%v", maxExpireIn)
 	}
-	od.Sign = sign
 
-	return od, nil
+	o.StartedAt = now.In(cst)
+	o.ExpiredAt = o.StartedAt.Add(o.ExpireIn)
+
+	return nil
 }
 
 // response 基础返回数据
 type response struct {
-	ReturnCode string `xml:"return_code"` // 返回状态码: SUCCESS/FAIL
-	ReturnMsg  string `xml:"return_msg"`  // 返回信息: 返回信息，如非空，为错误原因
-	ResultCode string `xml:"result_code"`
-	ErrCode    string `xml:"err_code"`
-	ErrCodeDes string `xml:"err_code_des"`
+	ReturnCode string `xml:"return_code" json:"return_code"` // 返回状态码: SUCCESS/FAIL
+	ReturnMsg  string `xml:"return_msg" json:"return_msg"`   // 返回信息: 返回信息，如非空，为错误原因
+	ResultCode string `xml:"result_code" json:"result_code"`
+	ErrCode    string `xml:"err_code" json:"err_code"`
+	ErrCodeDes string `xml:"err_code_des" json:"err_code_des"`
 }
 
 // Check 检测返回信息是否包含错误
+// 返回的错误类型为 *Error
 func (res response) Check() error {
-	if res.ReturnCode != "SUCCESS" {
-		return errors.New("交易失败: " + res.ReturnMsg)
-	}
-
-	if res.ResultCode != "SUCCESS" {
-		return errors.New("发生错误: " + res.ErrCodeDes)
+	if res.ReturnCode != "SUCCESS" || res.ResultCode != "SUCCESS" {
+		return newError(res)
 	}
 
 	return nil
@@ -158,11 +150,15 @@ func (res response) Check() error {
 
 // PaidResponse 支付返回面向用户的集合
 type PaidResponse struct {
-	AppID    string `xml:"appid"` // 小程序ID
-	MchID    string `xml:"mch_id"`
-	PrePayID string `xml:"prepay_id"`
-	Sign     string `xml:"sign"`
-	NonceStr string `xml:"nonce_str"`
+	AppID    string `xml:"appid" json:"appid"` // 小程序ID
+	MchID    string `xml:"mch_id" json:"mch_id"`
+	PrePayID string `xml:"prepay_id" json:"prepay_id"`
+	MWebURL  string `xml:"mweb_url,omitempty" json:"mweb_url,omitempty"` // H5 支付跳转链接, 使用 MWebRedirectURL 追加回跳地址
+	Sign     string `xml:"sign" json:"sign"`
+	NonceStr string `xml:"nonce_str" json:"nonce_str"`
+
+	SubAppID string `xml:"sub_appid,omitempty" json:"sub_appid,omitempty"`   // 服务商模式子商户 APPID
+	SubMchID string `xml:"sub_mch_id,omitempty" json:"sub_mch_id,omitempty"` // 服务商模式子商户号
 }
 
 // paidResponse 支付返回集合
@@ -205,7 +201,7 @@ func GetParams(appID, key, nonceStr, prepayID string) (p Params, err error) {
 // @key payment secret key
 func (o Order) Unify(key string) (pres PaidResponse, err error) {
 
-	reqData, err := o.prepare(key)
+	reqData, err := o.prepare(key, SignTypeMD5)
 	if err != nil {
 		return
 	}
@@ -215,8 +211,13 @@ func (o Order) Unify(key string) (pres PaidResponse, err error) {
 		return
 	}
 
+	return parsePaidResponse(XMLCodec, data, o)
+}
+
+// 解析下单结果, 校验回传的商户标识与订单一致
+func parsePaidResponse(cd Codec, data []byte, o Order) (pres PaidResponse, err error) {
 	var res paidResponse
-	if err = xml.Unmarshal(data, &res); err != nil {
+	if err = cd.Unmarshal(data, &res); err != nil {
 		return
 	}
 
@@ -224,36 +225,50 @@ func (o Order) Unify(key string) (pres PaidResponse, err error) {
 		return
 	}
 
+	if err = checkEcho(o.AppID, o.MchID, res.AppID, res.MchID); err != nil {
+		return
+	}
+
+	if err = checkSubEcho(o.SubAppID, o.SubMchID, res.SubAppID, res.SubMchID); err != nil {
+		return
+	}
+
 	pres = res.PaidResponse
 	return
 }
 
 // PaidNotify 支付结果返回数据
 type PaidNotify struct {
-	AppID         string  `xml:"appid"`               // 小程序ID
-	MchID         string  `xml:"mch_id"`              // 商户号
-	TotalFee      int     `xml:"total_fee"`           // 标价金额
-	NonceStr      string  `xml:"nonce_str"`           // 随机字符串
-	Sign          string  `xml:"sign"`                // 签名
-	SignType      string  `xml:"sign_type,omitempty"` // 签名类型: 目前支持HMAC-SHA256和MD5，默认为MD5
-	OpenID        string  `xml:"openid"`
-	TradeType     string  `xml:"trade_type"`                     // 交易类型 JSAPI
-	Bank          string  `xml:"bank_type"`                      // 银行类型，采用字符串类型的银行标识
-	Settlement    float64 `xml:"settlement_total_fee,omitempty"` // 应结订单金额=订单金额-非充值代金券金额，应结订单金额<=订单金额。
-	FeeType       string  `xml:"fee_type,omitempty"`             // 货币种类: 符合ISO4217标准的三位字母代码，默认人民币: CNY
-	CashFee       float64 `xml:"cash_fee"`                       // 现金支付金额订单的现金支付金额
-	CashFeeType   string  `xml:"cash_fee_type,omitempty"`        // 现金支付货币类型: 符合ISO4217标准的三位字母代码，默认人民币: CNY
-	CouponFee     float64 `xml:"coupon_fee,omitempty"`           // 总代金券金额: 代金券金额<=订单金额，订单金额-代金券金额=现金支付金额
-	CouponCount   int     `xml:"coupon_count,omitempty"`         // 代金券使用数量
-	TransactionID string  `xml:"transaction_id"`                 // 微信支付订单号
-	Attach        string  `xml:"attach,omitempty"`               // 商家数据包，原样返回
-	IsSubscribe   string  `xml:"is_subscribe"`
+	AppID         string  `xml:"appid" json:"appid"`                               // 小程序ID
+	MchID         string  `xml:"mch_id" json:"mch_id"`                             // 商户号
+	SubAppID      string  `xml:"sub_appid,omitempty" json:"sub_appid,omitempty"`   // 服务商模式子商户 APPID
+	SubMchID      string  `xml:"sub_mch_id,omitempty" json:"sub_mch_id,omitempty"` // 服务商模式子商户号
+	TotalFee      int     `xml:"total_fee" json:"total_fee"`                       // 标价金额
+	NonceStr      string  `xml:"nonce_str" json:"nonce_str"`                       // 随机字符串
+	Sign          string  `xml:"sign" json:"sign"`                                 // 签名
+	SignType      string  `xml:"sign_type,omitempty" json:"sign_type,omitempty"`   // 签名类型: 目前支持HMAC-SHA256和MD5，默认为MD5
+	OpenID        string  `xml:"openid" json:"openid"`
+	TradeType     string  `xml:"trade_type" json:"trade_type"`                                         // 交易类型 JSAPI
+	Bank          string  `xml:"bank_type" json:"bank_type"`                                           // 银行类型，采用字符串类型的银行标识
+	Settlement    float64 `xml:"settlement_total_fee,omitempty" json:"settlement_total_fee,omitempty"` // 应结订单金额=订单金额-非充值代金券金额，应结订单金额<=订单金额。
+	FeeType       string  `xml:"fee_type,omitempty" json:"fee_type,omitempty"`                         // 货币种类: 符合ISO4217标准的三位字母代码，默认人民币: CNY
+	CashFee       float64 `xml:"cash_fee" json:"cash_fee"`                                             // 现金支付金额订单的现金支付金额
+	CashFeeType   string  `xml:"cash_fee_type,omitempty" json:"cash_fee_type,omitempty"`               // 现金支付货币类型: 符合ISO4217标准的三位字母代码，默认人民币: CNY
+	CouponFee     float64 `xml:"coupon_fee,omitempty" json:"coupon_fee,omitempty"`                     // 总代金券金额: 代金券金额<=订单金额，订单金额-代金券金额=现金支付金额
+	CouponCount   int     `xml:"coupon_count,omitempty" json:"coupon_count,omitempty"`                 // 代金券使用数量
+	TransactionID string  `xml:"transaction_id" json:"transaction_id"`                                 // 微信支付订单号
+	Attach        string  `xml:"attach,omitempty" json:"attach,omitempty"`                             // 商家数据包，原样返回
+	IsSubscribe   string  `xml:"is_subscribe" json:"is_subscribe"`
 	// 商户系统内部订单号: 要求32个字符内，只能是数字、大小写字母_-|*@ ，且在同一个商户号下唯一。
-	OutTradeNo string `xml:"out_trade_no"`
+	OutTradeNo string `xml:"out_trade_no" json:"out_trade_no"`
 	// 支付完成时间，格式为yyyyMMddHHmmss，如2009年12月25日9点10分10秒表示为20091225091010
-	Timeend string `xml:"time_end"`
+	Timeend string `xml:"time_end" json:"time_end"`
 	// 使用coupon_count的序号生成的优惠券项
-	Coupons []CouponResponseModel `xml:"-"`
+	Coupons []CouponResponseModel `xml:"-" json:"coupons,omitempty"`
+	// 通知原文的 SHA-256 摘要(十六进制), 由 HandlePaidNotify 填写
+	BodySHA256 string `xml:"-" json:"body_sha256,omitempty"`
+	// 未收录的参数, 如微信新增的字段
+	Extra map[string]string `xml:"-" json:"extra,omitempty"`
 }
 
 type paidNotify struct {
@@ -286,7 +301,7 @@ func newReplay(ok bool, msg string) replay {
 
 // HandlePaidNotify 处理支付结果通知
 func HandlePaidNotify(res http.ResponseWriter, req *http.Request, fuck func(PaidNotify) (bool, string)) error {
-	body, err := ioutil.ReadAll(req.Body)
+	body, sum, err := readBody(req.Body)
 	if err != nil {
 		return err
 	}
@@ -305,6 +320,10 @@ func HandlePaidNotify(res http.ResponseWriter, req *http.Request, fuck func(Paid
 		root := doc.SelectElement("xml")
 		for i := 0; i < ntf.CouponCount; i++ {
 			m := NewCouponResponseModel(root, "coupon_id_%d", "coupon_fee_%d", i)
+			// 开通免充值券功能后才返回券类型
+			if e := root.SelectElement(fmt.Sprintf("coupon_type_%d", i)); e != nil {
+				m.CouponType = e.Text()
+			}
 			ntf.Coupons = append(ntf.Coupons, m)
 		}
 	}
@@ -312,6 +331,8 @@ func HandlePaidNotify(res http.ResponseWriter, req *http.Request, fuck func(Paid
 	if err := ntf.Check(); err != nil {
 		return err
 	}
+	ntf.BodySHA256 = sum
+	ntf.Extra = extraFields(body, ntf)
 
 	replay := newReplay(fuck(ntf.PaidNotify))
 
@@ -328,16 +349,19 @@ func HandlePaidNotify(res http.ResponseWriter, req *http.Request, fuck func(Paid
 
 // 返回结果中的优惠券条目信息
 type CouponResponseModel struct {
-	CouponId string // 代金券或立减优惠ID
+	CouponId string `json:"coupon_id"` // 代金券或立减优惠ID
 	//CouponType string // CASH-充值代金券 NO_CASH-非充值优惠券 开通免充值券功能，并且订单使用了优惠券后有返回
-	CouponFee int64 // 单个代金券或立减优惠支付金额
+	CouponFee int64 `json:"coupon_fee"` // 单个代金券或立减优惠支付金额
+
+	// CASH-充值代金券 NO_CASH-免充值优惠券, 开通免充值券功能并且订单使用了优惠券后返回
+	CouponType string `json:"coupon_type,omitempty"`
 }
 
 // 在XML节点树中，查找labels对应的
 func NewCouponResponseModel(
 	doc *etree.Element,
 	idFormat string,
-//typeFormat string,
+	//typeFormat string,
 	feeFormat string,
 	numbers ...interface{},
 ) (m CouponResponseModel) {
@@ -0,0 +1,50 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+// 本机时间与微信服务器时间允许的最大偏差
+// v2 签名不含时间戳, 但偏差过大时 time_start/time_expire 会被拒绝
+const maxClockSkew = 5 * time.Minute
+
+// PingResult 连通性检查结果
+type PingResult struct {
+	Latency   time.Duration // 请求耗时
+	ClockSkew time.Duration // 本机时间减去微信服务器时间, 应答没有 Date 头时为0
+}
+
+// Ping 检查网络、商户配置和本机时间, 可用于就绪探针
+// 查询一个不存在的订单, 返回 ORDERNOTEXIST 说明请求已通过微信的签名校验;
+// 密钥错误、商户号与 appid 不匹配等配置问题返回对应的错误。
+// 本机时间偏差超过5分钟时同样返回错误
+func (c *Client) Ping(ctx context.Context, opts ...CallOption) (res PingResult, err error) {
+	var info CallInfo
+	opts = append(opts, WithCallInfo(&info))
+
+	_, err = c.QueryOrder(ctx, "ping"+util.RandomString(26), opts...)
+	res.Latency = info.Duration
+	if !info.ServerTime.IsZero() {
+		// Date 头只精确到秒
+		res.ClockSkew = time.Now().Add(-info.Duration / 2).Truncate(time.Second).Sub(info.ServerTime)
+	}
+
+	if ErrCodeOf(err) != "ORDERNOTEXIST" {
+		if err == nil {
+			err = errors.New("ping: 订单查询返回了结果, 应为 ORDERNOTEXIST")
+		}
+		return
+	}
+	err = nil
+
+	if res.ClockSkew > maxClockSkew || res.ClockSkew < -maxClockSkew {
+		err = fmt.Errorf("ping: 本机时间与微信服务器相差 %v", res.ClockSkew)
+	}
+
+	return
+}
@@ -0,0 +1,95 @@
+package payment
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// 运行环境
+const (
+	ProfileProd    = "prod"    // 生产环境
+	ProfileSandbox = "sandbox" // 仿真测试环境
+	ProfileMock    = "mock"    // 本地模拟服务, 需要设置 BaseURL
+)
+
+// 仿真测试环境接口地址
+const (
+	sandboxURL  = baseURL + "/sandboxnew"
+	sandboxPath = "/sandboxnew"
+)
+
+// 生产接口域名, 非生产环境不允许请求
+// 包括 BaseURLBackup 和 BaseURLHK, 新增接入点时需要同时添加
+var productionHosts = map[string]bool{
+	"api.mch.weixin.qq.com":   true,
+	"api2.mch.weixin.qq.com":  true,
+	"apihk.mch.weixin.qq.com": true,
+}
+
+var (
+	profilesMu sync.RWMutex
+	profiles   = make(map[string]Config)
+)
+
+// RegisterProfile 注册环境配置
+// 同一个进程可以注册多套环境, 通过 NewFromProfile 按名称创建客户端
+//
+// @name 环境名称, 可以是 ProfileProd/ProfileSandbox/ProfileMock 或自定义名称
+// @cfg 环境配置, cfg.Profile 为空时使用 name
+func RegisterProfile(name string, cfg Config) {
+	if cfg.Profile == "" {
+		cfg.Profile = name
+	}
+
+	profilesMu.Lock()
+	profiles[name] = cfg
+	profilesMu.Unlock()
+}
+
+// NewFromProfile 使用已注册的环境配置创建客户端
+func NewFromProfile(name string) (*Client, error) {
+	profilesMu.RLock()
+	cfg, ok := profiles[name]
+	profilesMu.RUnlock()
+
+	if !ok {
+		return nil, errors.New("未注册的环境: " + name)
+	}
+
+	return NewClient(cfg)
+}
+
+// 各环境默认接口地址
+func profileBaseURL(profile string) string {
+	if profile == ProfileSandbox {
+		return sandboxURL
+	}
+
+	return baseURL
+}
+
+// 检查请求地址是否属于当前环境
+// 非生产环境的配置(仿真测试密钥等)不允许请求生产接口
+func checkProfileURL(profile, uri string) error {
+	if profile == "" || profile == ProfileProd {
+		return nil
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+
+	if !productionHosts[strings.ToLower(u.Hostname())] {
+		return nil
+	}
+
+	// 仿真测试接口与生产接口使用相同域名
+	if u.Path == sandboxPath || strings.HasPrefix(u.Path, sandboxPath+"/") {
+		return nil
+	}
+
+	return errors.New("环境 " + profile + " 不允许请求生产接口: " + uri)
+}
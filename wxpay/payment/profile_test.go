@@ -0,0 +1,51 @@
+package payment
+
+import "testing"
+
+func TestCheckProfileURL(t *testing.T) {
+	tests := []struct {
+		profile string
+		uri     string
+		ok      bool
+	}{
+		{ProfileProd, baseURL + unifyAPI, true},
+		{"", baseURL + unifyAPI, true},
+		{ProfileSandbox, sandboxURL + unifyAPI, true},
+		{ProfileSandbox, sandboxURL, true},
+		{ProfileSandbox, baseURL + unifyAPI, false},
+		{ProfileSandbox, baseURL, false},
+		{ProfileSandbox, "https://API.MCH.weixin.qq.com/pay/unifiedorder", false},
+		{ProfileSandbox, "https://api.mch.weixin.qq.com:443/pay/unifiedorder", false},
+		{ProfileSandbox, baseURL + "/sandboxnewx/pay/unifiedorder", false},
+		{ProfileSandbox, BaseURLBackup + unifyAPI, false},
+		{ProfileSandbox, BaseURLHK + unifyAPI, false},
+		{ProfileSandbox, BaseURLBackup + "/sandboxnew" + unifyAPI, true},
+		{ProfileMock, BaseURLBackup, false},
+		{ProfileMock, BaseURLHK + orderQueryAPI, false},
+		{ProfileMock, "http://127.0.0.1:8080" + unifyAPI, true},
+		// 前缀相同但不是生产域名
+		{ProfileMock, "https://api.mch.weixin.qq.com.example.com" + unifyAPI, true},
+	}
+
+	for _, tt := range tests {
+		err := checkProfileURL(tt.profile, tt.uri)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkProfileURL(%q, %q) = %v, want ok = %v", tt.profile, tt.uri, err, tt.ok)
+		}
+	}
+}
+
+// 非生产环境不能使用生产接入点作为接口地址或候选地址
+func TestProfileProductionEndpoints(t *testing.T) {
+	for _, u := range []string{BaseURLBackup, BaseURLHK} {
+		_, err := NewClient(Config{AppID: "wx", MchID: "1", Key: "k", Profile: ProfileMock, BaseURL: u})
+		if err == nil {
+			t.Errorf("BaseURL %s 应返回错误", u)
+		}
+
+		_, err = NewClient(Config{AppID: "wx", MchID: "1", Key: "k", Profile: ProfileSandbox, Endpoints: []string{sandboxURL, u}})
+		if err == nil {
+			t.Errorf("Endpoints %s 应返回错误", u)
+		}
+	}
+}
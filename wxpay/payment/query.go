@@ -0,0 +1,227 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+
+	"github.com/wanghuobo/weapp/payment/types"
+	"github.com/wanghuobo/weapp/util"
+)
+
+const orderQueryAPI = "/pay/orderquery"
+
+// 交易状态
+const (
+	TradeStateSuccess    = string(types.TradeStateSuccess)    // 支付成功
+	TradeStateRefund     = string(types.TradeStateRefund)     // 转入退款
+	TradeStateNotPay     = string(types.TradeStateNotPay)     // 未支付
+	TradeStateClosed     = string(types.TradeStateClosed)     // 已关闭
+	TradeStateRevoked    = string(types.TradeStateRevoked)    // 已撤销(付款码支付)
+	TradeStateUserPaying = string(types.TradeStateUserPaying) // 用户支付中(付款码支付)
+	TradeStatePayError   = string(types.TradeStatePayError)   // 支付失败
+)
+
+// QueryResult 订单查询结果
+type QueryResult struct {
+	AppID       string `xml:"appid" json:"appid"`
+	MchID       string `xml:"mch_id" json:"mch_id"`
+	NonceStr    string `xml:"nonce_str" json:"nonce_str"`
+	OpenID      string `xml:"openid" json:"openid"`
+	IsSubscribe string `xml:"is_subscribe" json:"is_subscribe"`
+	TradeType   string `xml:"trade_type" json:"trade_type"`
+	TradeState  string `xml:"trade_state" json:"trade_state"` // 交易状态, 见 TradeState 常量
+	BankType    string `xml:"bank_type" json:"bank_type"`
+	TotalFee    int    `xml:"total_fee" json:"total_fee"`                                           // 订单金额
+	Settlement  int    `xml:"settlement_total_fee,omitempty" json:"settlement_total_fee,omitempty"` // 应结订单金额
+	FeeType     string `xml:"fee_type,omitempty" json:"fee_type,omitempty"`
+	CashFee     int    `xml:"cash_fee" json:"cash_fee"`                               // 现金支付金额
+	CashFeeType string `xml:"cash_fee_type,omitempty" json:"cash_fee_type,omitempty"` // 现金支付货币类型
+	CouponFee   int    `xml:"coupon_fee,omitempty" json:"coupon_fee,omitempty"`       // 代金券金额
+	CouponCount int    `xml:"coupon_count,omitempty" json:"coupon_count,omitempty"`   // 代金券使用数量
+	// 微信支付订单号
+	TransactionID string `xml:"transaction_id" json:"transaction_id"`
+	OutTradeNo    string `xml:"out_trade_no" json:"out_trade_no"`
+	Attach        string `xml:"attach,omitempty" json:"attach,omitempty"`
+	// 支付完成时间, 格式为yyyyMMddHHmmss
+	TimeEnd        string `xml:"time_end" json:"time_end"`
+	TradeStateDesc string `xml:"trade_state_desc" json:"trade_state_desc"`
+}
+
+// Paid 订单是否已支付
+// 转入退款的订单同样视为已支付
+func (r QueryResult) Paid() bool {
+	return r.TradeState == TradeStateSuccess || r.TradeState == TradeStateRefund
+}
+
+type queryResult struct {
+	response
+	QueryResult
+}
+
+// 订单查询请求
+type orderQuery struct {
+	AppID         string `sign:"appid"`
+	MchID         string `sign:"mch_id"`
+	TransactionID string `sign:"transaction_id,omitzero"` // 微信订单号, 和商户订单号二选一
+	OutTradeNo    string `sign:"out_trade_no,omitzero"`   // 商户订单号
+}
+
+// 请求前准备
+func (q orderQuery) prepare(key, signType string) (fields, error) {
+	if q.TransactionID == "" && q.OutTradeNo == "" {
+		return nil, errors.New("out_trade_no 和 transaction_id 必须填写一个")
+	}
+
+	return signedFields(q, key, signType)
+}
+
+func parseQueryResult(cd Codec, data []byte, appID, mchID string) (res QueryResult, err error) {
+	var qres queryResult
+	if err = cd.Unmarshal(data, &qres); err != nil {
+		return
+	}
+
+	if err = qres.Check(); err != nil {
+		return
+	}
+
+	if err = checkEcho(appID, mchID, qres.AppID, qres.MchID); err != nil {
+		return
+	}
+
+	res = qres.QueryResult
+	return
+}
+
+// Query 通过商户订单号查询订单, 支付通知丢失时用于确认支付状态
+// 使用订单的 appid、mch_id 和 out_trade_no, 签名方式与 Unify 一致
+//
+// @key payment secret key
+func (o Order) Query(key string) (QueryResult, error) {
+	return queryOrder(orderQuery{AppID: o.AppID, MchID: o.MchID, OutTradeNo: o.OutTradeNo}, key)
+}
+
+// QueryByTransactionID 通过微信订单号查询订单
+// 使用订单的 appid 和 mch_id
+//
+// @key payment secret key
+// @transactionID 微信订单号
+func (o Order) QueryByTransactionID(key, transactionID string) (QueryResult, error) {
+	return queryOrder(orderQuery{AppID: o.AppID, MchID: o.MchID, TransactionID: transactionID}, key)
+}
+
+func queryOrder(q orderQuery, key string) (res QueryResult, err error) {
+	reqData, err := q.prepare(key, SignTypeMD5)
+	if err != nil {
+		return
+	}
+
+	data, err := util.PostXML(baseURL+orderQueryAPI, reqData)
+	if err != nil {
+		return
+	}
+
+	return parseQueryResult(XMLCodec, data, q.AppID, q.MchID)
+}
+
+// QueryOrder 通过商户订单号查询订单
+func (c *Client) QueryOrder(ctx context.Context, outTradeNo string, opts ...CallOption) (QueryResult, error) {
+	return c.queryOrder(ctx, orderQuery{OutTradeNo: outTradeNo}, opts)
+}
+
+// QueryOrderByTransactionID 通过微信订单号查询订单
+func (c *Client) QueryOrderByTransactionID(ctx context.Context, transactionID string, opts ...CallOption) (QueryResult, error) {
+	return c.queryOrder(ctx, orderQuery{TransactionID: transactionID}, opts)
+}
+
+// QueryOrderRaw 通过商户订单号查询订单, 同时返回应答中的全部参数
+// raw 包含 QueryResult 未收录的参数(如微信新增的字段和 coupon_id_$n 等), 用于排查问题。
+// 应答为业务错误时 raw 同样会返回
+func (c *Client) QueryOrderRaw(ctx context.Context, outTradeNo string, opts ...CallOption) (QueryResult, map[string]string, error) {
+	return c.queryOrderRaw(ctx, orderQuery{OutTradeNo: outTradeNo}, opts)
+}
+
+// QueryOrderByTransactionIDRaw 通过微信订单号查询订单, 同时返回应答中的全部参数
+func (c *Client) QueryOrderByTransactionIDRaw(ctx context.Context, transactionID string, opts ...CallOption) (QueryResult, map[string]string, error) {
+	return c.queryOrderRaw(ctx, orderQuery{TransactionID: transactionID}, opts)
+}
+
+func (c *Client) queryOrder(ctx context.Context, q orderQuery, opts []CallOption) (res QueryResult, err error) {
+	res, _, err = c.queryOrderData(ctx, q, opts)
+	return
+}
+
+func (c *Client) queryOrderRaw(ctx context.Context, q orderQuery, opts []CallOption) (res QueryResult, raw map[string]string, err error) {
+	res, data, err := c.queryOrderData(ctx, q, opts)
+	if data != nil {
+		raw, _ = parseRawFields(data)
+	}
+	return
+}
+
+// 查询订单, 同时返回应答原文
+func (c *Client) queryOrderData(ctx context.Context, q orderQuery, opts []CallOption) (res QueryResult, data []byte, err error) {
+	if err = c.begin(); err != nil {
+		return
+	}
+	defer c.end()
+
+	if err = c.allowQuery(q); err != nil {
+		return
+	}
+
+	opt := c.options(opts)
+	cfg := opt.conf
+	q.AppID = cfg.AppID
+	q.MchID = cfg.MchID
+	reqData, err := q.prepare(cfg.Key, opt.signType)
+	if err != nil {
+		return
+	}
+
+	if data, err = c.post(ctx, opt, orderQueryAPI, reqData, false); err != nil {
+		return
+	}
+
+	res, err = parseQueryResult(cfg.codec(), data, q.AppID, q.MchID)
+	return
+}
+
+// 把应答 XML 根节点下的参数读取为 map
+func parseRawFields(data []byte) (map[string]string, error) {
+	raw := make(map[string]string)
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	depth := 0
+	var name string
+	var value []byte
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return raw, nil
+		}
+		if err != nil {
+			return raw, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 {
+				name, value = t.Name.Local, value[:0]
+			}
+		case xml.CharData:
+			if depth == 2 {
+				value = append(value, t...)
+			}
+		case xml.EndElement:
+			if depth == 2 {
+				raw[name] = string(value)
+			}
+			depth--
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package payment
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// 同一订单查询频率限制
+const (
+	defaultQueryLimit = 6           // 每分钟最多查询次数
+	queryWindow       = time.Minute // 统计窗口
+	queryCacheSize    = 4096        // 最多记录的订单数
+)
+
+// ErrTooFrequent 同一订单查询过于频繁
+// 在客户端拒绝, 避免微信对整个商户限频
+var ErrTooFrequent = errors.New("订单查询过于频繁, 请稍后再试")
+
+// 按订单记录最近的查询时间
+type queryLimiter struct {
+	mu     sync.Mutex
+	orders map[string][]time.Time
+}
+
+// 检查并记录一次查询
+//
+// @limit 每分钟最多查询次数
+func (l *queryLimiter) allow(id string, limit int) bool {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.orders == nil {
+		l.orders = make(map[string][]time.Time)
+	}
+
+	recent := l.orders[id][:0:0]
+	for _, t := range l.orders[id] {
+		if now.Sub(t) < queryWindow {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= limit {
+		l.orders[id] = recent
+		return false
+	}
+
+	if _, ok := l.orders[id]; !ok && len(l.orders) >= queryCacheSize {
+		l.prune(now)
+	}
+	l.orders[id] = append(recent, now)
+
+	return true
+}
+
+// 删除窗口外的记录, 仍然超过容量时全部清空
+func (l *queryLimiter) prune(now time.Time) {
+	for id, ts := range l.orders {
+		if len(ts) == 0 || now.Sub(ts[len(ts)-1]) >= queryWindow {
+			delete(l.orders, id)
+		}
+	}
+
+	if len(l.orders) >= queryCacheSize {
+		l.orders = make(map[string][]time.Time)
+	}
+}
+
+// 检查订单查询频率
+func (c *Client) allowQuery(q orderQuery) error {
+	limit := c.conf().QueryLimit
+	if limit < 0 {
+		return nil
+	}
+	if limit == 0 {
+		limit = defaultQueryLimit
+	}
+
+	id := q.OutTradeNo
+	if id == "" {
+		id = q.TransactionID
+	}
+	if id == "" {
+		return nil
+	}
+
+	if !c.queries.allow(id, limit) {
+		return ErrTooFrequent
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package payment
+
+import (
+	"sync"
+	"time"
+)
+
+// QuotaKey 调用次数的统计维度
+type QuotaKey struct {
+	MchID string // 商户号
+	API   string // 接口路径, 如 /pay/orderquery
+	Date  string // 日期 yyyy-MM-dd, 按北京时间
+}
+
+// QuotaStore 调用次数存储
+// 多实例部署时使用共享存储(如 Redis INCRBY), 以统计商户的全部调用
+type QuotaStore interface {
+	// Incr 增加调用次数, 返回增加后的次数
+	Incr(key QuotaKey, n int64) (int64, error)
+	// Get 读取调用次数, 不存在时返回0
+	Get(key QuotaKey) (int64, error)
+}
+
+// QuotaPolicy 调用次数统计及告警
+type QuotaPolicy struct {
+	Store QuotaStore
+
+	// Limits 各接口每日调用次数上限, 用于告警, 不会拒绝调用
+	Limits map[string]int64
+	// Threshold 达到上限的比例时告警, 默认0.8
+	Threshold float64
+	// OnAlert 当天调用次数首次达到告警比例及达到上限时调用
+	OnAlert func(key QuotaKey, count, limit int64)
+	// OnError 记录失败时调用, 为空则忽略, 统计失败不影响请求
+	OnError func(error)
+}
+
+// 记录一次调用
+func (p *QuotaPolicy) record(mchID, api string) {
+	if p == nil || p.Store == nil {
+		return
+	}
+
+	key := QuotaKey{MchID: mchID, API: api, Date: time.Now().In(cst).Format("2006-01-02")}
+	count, err := p.Store.Incr(key, 1)
+	if err != nil {
+		if p.OnError != nil {
+			p.OnError(err)
+		}
+		return
+	}
+
+	limit := p.Limits[api]
+	if limit <= 0 || p.OnAlert == nil {
+		return
+	}
+
+	threshold := p.Threshold
+	if threshold <= 0 || threshold > 1 {
+		threshold = 0.8
+	}
+
+	// 只在跨过阈值的那次调用时告警
+	if warn := int64(float64(limit) * threshold); count == warn || count == limit {
+		p.OnAlert(key, count, limit)
+	}
+}
+
+// Usage 当天指定接口的调用次数, 未设置 Quota 时返回0
+//
+// @api 接口路径, 如 /pay/orderquery
+func (c *Client) Usage(api string, date time.Time) (int64, error) {
+	p := c.conf().Quota
+	if p == nil || p.Store == nil {
+		return 0, nil
+	}
+
+	return p.Store.Get(QuotaKey{MchID: c.conf().MchID, API: api, Date: date.In(cst).Format("2006-01-02")})
+}
+
+// MemoryQuotaStore 基于内存的调用次数存储, 适用于单实例部署
+// 不会清理历史数据, 长期运行时定期调用 Prune
+type MemoryQuotaStore struct {
+	mu     sync.Mutex
+	counts map[QuotaKey]int64
+}
+
+// NewMemoryQuotaStore 新建基于内存的调用次数存储
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{counts: make(map[QuotaKey]int64)}
+}
+
+// Incr 增加调用次数
+func (s *MemoryQuotaStore) Incr(key QuotaKey, n int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counts[key] += n
+	return s.counts[key], nil
+}
+
+// Get 读取调用次数
+func (s *MemoryQuotaStore) Get(key QuotaKey) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.counts[key], nil
+}
+
+// All 全部调用次数
+func (s *MemoryQuotaStore) All() map[QuotaKey]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make(map[QuotaKey]int64, len(s.counts))
+	for k, v := range s.counts {
+		res[k] = v
+	}
+
+	return res
+}
+
+// Prune 删除指定日期之前的统计
+func (s *MemoryQuotaStore) Prune(before time.Time) {
+	date := before.In(cst).Format("2006-01-02")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k := range s.counts {
+		if k.Date < date {
+			delete(s.counts, k)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package payment
+
+import "github.com/wanghuobo/weapp/util"
+
+// Clone 复制订单, 可以把订单作为模板修改后发起请求
+func (o Order) Clone() Order {
+	return o
+}
+
+// Redacted 返回遮盖了用户信息的副本, 用于打印日志
+func (o Order) Redacted() Order {
+	o.OpenID = util.MaskOpenID(o.OpenID)
+	return o
+}
+
+// Clone 深拷贝通知数据
+func (ntf PaidNotify) Clone() PaidNotify {
+	if ntf.Coupons != nil {
+		ntf.Coupons = append([]CouponResponseModel(nil), ntf.Coupons...)
+	}
+
+	return ntf
+}
+
+// Redacted 返回遮盖了用户信息的副本, 用于打印日志
+func (ntf PaidNotify) Redacted() PaidNotify {
+	ntf = ntf.Clone()
+	ntf.OpenID = util.MaskOpenID(ntf.OpenID)
+	return ntf
+}
+
+// Redacted 返回遮盖了用户信息的副本, 用于打印日志
+func (r QueryResult) Redacted() QueryResult {
+	r.OpenID = util.MaskOpenID(r.OpenID)
+	return r
+}
+
+// Clone 复制退款请求
+func (r Refunder) Clone() Refunder {
+	return r
+}
+
+// Redacted 返回遮盖了退款入账账户的副本, 用于打印日志
+func (ntf RefundedNotify) Redacted() RefundedNotify {
+	ntf.ReceiveAccount = util.MaskBankNo(ntf.ReceiveAccount)
+	return ntf
+}
+
+// Clone 复制转账请求
+func (t Transferer) Clone() Transferer {
+	return t
+}
+
+// Redacted 返回遮盖了收款用户信息的副本, 用于打印日志
+func (t Transferer) Redacted() Transferer {
+	t.ToUser = util.MaskOpenID(t.ToUser)
+	t.RealName = util.MaskName(t.RealName)
+	return t
+}
+
+// Redacted 返回遮盖了收款用户信息的副本, 用于打印日志
+func (res TransferInfoResponse) Redacted() TransferInfoResponse {
+	res.ToUser = util.MaskOpenID(res.ToUser)
+	res.RealName = util.MaskName(res.RealName)
+	return res
+}
+
+// Clone 复制红包请求
+// 频率控制 Guard 在副本间共享
+func (r Redpacker) Clone() Redpacker {
+	return r
+}
+
+// Redacted 返回遮盖了用户信息的副本, 用于打印日志
+func (r Redpacker) Redacted() Redpacker {
+	r.ToUser = util.MaskOpenID(r.ToUser)
+	return r
+}
+
+// Redacted 返回遮盖了用户信息的副本, 用于打印日志
+func (res RedpackResponse) Redacted() RedpackResponse {
+	res.ToUser = util.MaskOpenID(res.ToUser)
+	return res
+}
+
+// Clone 复制押金支付请求
+func (d Deposit) Clone() Deposit {
+	return d
+}
+
+// Redacted 返回遮盖了付款码的副本, 用于打印日志
+func (d Deposit) Redacted() Deposit {
+	d.AuthCode = util.Mask(d.AuthCode, 2, 2)
+	return d
+}
+
+// Redacted 返回遮盖了用户信息的副本, 用于打印日志
+func (res DepositResponse) Redacted() DepositResponse {
+	res.OpenID = util.MaskOpenID(res.OpenID)
+	return res
+}
@@ -0,0 +1,364 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+const (
+	redpackAPI = "/mmpaymkttransfers/sendredpack"
+
+	redpackMinAmount      = 100    // 普通红包最小金额(分)
+	redpackMaxAmount      = 20000  // 普通红包最大金额(分)
+	redpackSceneMinAmount = 30     // 指定场景时红包最小金额(分)
+	redpackSceneMaxAmount = 499900 // 指定场景时红包最大金额(分)
+)
+
+// 红包使用场景
+const (
+	RedpackSceneProduct1 = "PRODUCT_1" // 商品促销
+	RedpackSceneProduct2 = "PRODUCT_2" // 抽奖
+	RedpackSceneProduct3 = "PRODUCT_3" // 虚拟物品兑奖
+	RedpackSceneProduct4 = "PRODUCT_4" // 企业内部福利
+	RedpackSceneProduct5 = "PRODUCT_5" // 渠道分润
+	RedpackSceneProduct6 = "PRODUCT_6" // 保险回馈
+	RedpackSceneProduct7 = "PRODUCT_7" // 彩票派奖
+	RedpackSceneProduct8 = "PRODUCT_8" // 税务刮奖
+)
+
+var redpackScenes = map[string]bool{
+	RedpackSceneProduct1: true,
+	RedpackSceneProduct2: true,
+	RedpackSceneProduct3: true,
+	RedpackSceneProduct4: true,
+	RedpackSceneProduct5: true,
+	RedpackSceneProduct6: true,
+	RedpackSceneProduct7: true,
+	RedpackSceneProduct8: true,
+}
+
+// Redpacker 现金红包
+type Redpacker struct {
+	// 必填 ...
+	AppID    string `sign:"wxappid"`      // 公众账号appid
+	MchID    string `sign:"mch_id"`       // 商户号
+	BillNo   string `sign:"mch_billno"`   // 商户订单号: 每个订单号必须唯一, 最长28位
+	ToUser   string `sign:"re_openid"`    // 接受红包的用户openid
+	Amount   int    `sign:"total_amount"` // 付款金额(分)
+	SendName string `sign:"send_name"`    // 红包发送者名称, 最长32个字符
+	Wishing  string `sign:"wishing"`      // 红包祝福语, 最长128个字符
+	ActName  string `sign:"act_name"`     // 活动名称, 最长32个字符
+	Remark   string `sign:"remark"`       // 备注信息, 最长256个字符
+
+	// 选填 ...
+	IP string `sign:"client_ip"` // 调用接口的机器Ip地址, 为空时使用本机IP
+	// 场景id: 发放红包使用场景, 红包金额大于200元或者小于1元时必传
+	SceneID string `sign:"scene_id,omitzero"`
+	// 活动信息: urlencode 后的用户操作信息
+	RiskInfo string `sign:"risk_info,omitzero"`
+
+	// 发放频率控制, 为空则不做本地限制
+	Guard *RedpackGuard `sign:"-"`
+}
+
+// RedpackResponse 发送红包返回数据
+type RedpackResponse struct {
+	AppID  string `xml:"wxappid" json:"wxappid"`
+	MchID  string `xml:"mch_id" json:"mch_id"`
+	BillNo string `xml:"mch_billno" json:"mch_billno"`
+	ToUser string `xml:"re_openid" json:"re_openid"`
+	Amount int    `xml:"total_amount" json:"total_amount"`
+	// 微信单号: 红包订单的微信单号
+	ListID string `xml:"send_listid" json:"send_listid"`
+}
+
+type redpackResponse struct {
+	response
+	RedpackResponse
+}
+
+// Validate 检查红包参数
+// 本地拦截金额及文案长度错误, 避免微信返回难以排查的 PARAM_ERROR
+func (r Redpacker) Validate() error {
+	if r.BillNo == "" || len(r.BillNo) > 28 {
+		return errors.New("mch_billno 不能为空且不能超过28位")
+	}
+
+	if r.ToUser == "" {
+		return errors.New("re_openid 不能为空")
+	}
+
+	if r.SceneID != "" && !redpackScenes[r.SceneID] {
+		return fmt.Errorf("未知的红包场景: %s", r.SceneID)
+	}
+
+	if r.SceneID == "" {
+		if r.Amount < redpackMinAmount || r.Amount > redpackMaxAmount {
+			return fmt.Errorf("红包金额需在 %d-%d 分之间, 超出范围时必须指定 scene_id", redpackMinAmount, redpackMaxAmount)
+		}
+	} else if r.Amount < redpackSceneMinAmount || r.Amount > redpackSceneMaxAmount {
+		return fmt.Errorf("红包金额需在 %d-%d 分之间", redpackSceneMinAmount, redpackSceneMaxAmount)
+	}
+
+	return RedpackTemplate{
+		SendName: r.SendName,
+		Wishing:  r.Wishing,
+		ActName:  r.ActName,
+		Remark:   r.Remark,
+	}.Validate()
+}
+
+// 请求前准备
+func (r *Redpacker) prepare(key string) (fields, error) {
+	red := *r
+	if r.IP == "" {
+		ip, err := localIP()
+		if err != nil {
+			return nil, err
+		}
+
+		red.IP = ip
+	}
+
+	// 红包发放总人数, 现金红包固定为1
+	return signedFields(red, key, "", field{name: "total_num", value: "1"})
+}
+
+// Send 发放现金红包
+func (r Redpacker) Send(key, certPath, keyPath string) (res RedpackResponse, err error) {
+	if err = r.Validate(); err != nil {
+		return
+	}
+
+	if r.Guard != nil {
+		var cancel func()
+		if cancel, err = r.Guard.Reserve(r.ToUser); err != nil {
+			return
+		}
+		defer func() {
+			if err != nil {
+				cancel()
+			}
+		}()
+	}
+
+	reqData, err := r.prepare(key)
+	if err != nil {
+		return
+	}
+
+	resData, err := util.TSLPostXML(baseURL+redpackAPI, reqData, certPath, keyPath)
+	if err != nil {
+		return
+	}
+
+	res, err = parseRedpackResponse(XMLCodec, resData, r.AppID, r.MchID)
+	return
+}
+
+func parseRedpackResponse(cd Codec, resData []byte, appID, mchID string) (res RedpackResponse, err error) {
+	var rres redpackResponse
+	if err = cd.Unmarshal(resData, &rres); err != nil {
+		return
+	}
+
+	if err = rres.Check(); err != nil {
+		return
+	}
+
+	if err = checkEcho(appID, mchID, rres.AppID, rres.MchID); err != nil {
+		return
+	}
+
+	res = rres.RedpackResponse
+	return
+}
+
+// RedpackTemplate 红包文案模板
+// 同一活动的红包通常使用相同的文案, 可以通过模板批量生成红包
+type RedpackTemplate struct {
+	SendName string // 红包发送者名称, 最长32个字符
+	Wishing  string // 红包祝福语, 最长128个字符
+	ActName  string // 活动名称, 最长32个字符
+	Remark   string // 备注信息, 最长256个字符
+	SceneID  string // 场景id
+}
+
+// Validate 检查模板文案长度
+func (t RedpackTemplate) Validate() error {
+	fields := []struct {
+		name  string
+		value string
+		max   int
+	}{
+		{"send_name", t.SendName, 32},
+		{"wishing", t.Wishing, 128},
+		{"act_name", t.ActName, 32},
+		{"remark", t.Remark, 256},
+	}
+
+	for _, f := range fields {
+		n := utf8.RuneCountInString(f.value)
+		if n == 0 {
+			return fmt.Errorf("%s 不能为空", f.name)
+		}
+		if n > f.max {
+			return fmt.Errorf("%s 不能超过%d个字符, 当前%d个", f.name, f.max, n)
+		}
+	}
+
+	return nil
+}
+
+// New 根据模板生成红包
+//
+// @billNo 商户订单号
+// @openID 接受红包的用户openid
+// @amount 红包金额(分)
+func (t RedpackTemplate) New(appID, mchID, billNo, openID string, amount int) (Redpacker, error) {
+	r := Redpacker{
+		AppID:    appID,
+		MchID:    mchID,
+		BillNo:   billNo,
+		ToUser:   openID,
+		Amount:   amount,
+		SendName: t.SendName,
+		Wishing:  t.Wishing,
+		ActName:  t.ActName,
+		Remark:   t.Remark,
+		SceneID:  t.SceneID,
+	}
+
+	return r, r.Validate()
+}
+
+// RedpackGuard 红包发放频率控制
+// 微信对同一商户每分钟发放数量和同一用户每天领取数量都有限制,
+// 超限时返回 FREQ_LIMIT, 在本地提前拦截可以避免无效请求。
+type RedpackGuard struct {
+	PerMinute  int           // 商户每分钟最多发放个数, 0 表示不限制
+	PerUserDay int           // 单个用户每天最多领取个数, 0 表示不限制
+	Interval   time.Duration // 同一用户两次领取的最小间隔
+
+	mu    sync.Mutex
+	sent  []time.Time
+	users map[string][]time.Time
+}
+
+// NewRedpackGuard 使用微信默认限制创建频率控制
+func NewRedpackGuard() *RedpackGuard {
+	return &RedpackGuard{
+		PerMinute:  1800,
+		PerUserDay: 10,
+	}
+}
+
+// Allow 检查是否可以给用户发放红包
+// 检查与 Record 之间没有加锁, 并发发放时使用 Reserve
+func (g *RedpackGuard) Allow(openID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.check(openID, time.Now())
+}
+
+// Reserve 检查并占用一次发放额度, 检查和计数在同一次加锁中完成
+// 发放失败时调用返回的 cancel 归还额度
+func (g *RedpackGuard) Reserve(openID string) (cancel func(), err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	if err = g.check(openID, now); err != nil {
+		return
+	}
+	g.record(openID, now)
+
+	cancel = func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		g.sent = removeTime(g.sent, now)
+		if list := removeTime(g.users[openID], now); len(list) > 0 {
+			g.users[openID] = list
+		} else {
+			delete(g.users, openID)
+		}
+	}
+	return
+}
+
+// 检查发放限制, 调用前需要加锁
+func (g *RedpackGuard) check(openID string, now time.Time) error {
+	g.prune(now)
+
+	if g.PerMinute > 0 && len(g.sent) >= g.PerMinute {
+		return errors.New("红包发放过于频繁, 请稍后再试")
+	}
+
+	list := g.users[openID]
+	if g.PerUserDay > 0 && len(list) >= g.PerUserDay {
+		return errors.New("该用户今日领取红包次数已达上限")
+	}
+
+	if g.Interval > 0 && len(list) > 0 && now.Sub(list[len(list)-1]) < g.Interval {
+		return errors.New("该用户领取红包过于频繁, 请稍后再试")
+	}
+
+	return nil
+}
+
+// Record 记录一次成功发放
+func (g *RedpackGuard) Record(openID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.record(openID, time.Now())
+}
+
+func (g *RedpackGuard) record(openID string, now time.Time) {
+	if g.users == nil {
+		g.users = make(map[string][]time.Time)
+	}
+	g.sent = append(g.sent, now)
+	g.users[openID] = append(g.users[openID], now)
+}
+
+// 删除列表中的一个时间
+func removeTime(list []time.Time, t time.Time) []time.Time {
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i].Equal(t) {
+			return append(list[:i], list[i+1:]...)
+		}
+	}
+
+	return list
+}
+
+// 清理过期记录
+func (g *RedpackGuard) prune(now time.Time) {
+	i := 0
+	for i < len(g.sent) && now.Sub(g.sent[i]) >= time.Minute {
+		i++
+	}
+	g.sent = g.sent[i:]
+
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	for id, list := range g.users {
+		j := 0
+		for j < len(list) && list[j].Before(today) {
+			j++
+		}
+		if j == len(list) {
+			delete(g.users, id)
+		} else {
+			g.users[id] = list[j:]
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package payment
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRedpackGuardReserve(t *testing.T) {
+	g := &RedpackGuard{PerUserDay: 3}
+
+	var ok int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := g.Reserve("openid"); err == nil {
+				atomic.AddInt32(&ok, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if ok != 3 {
+		t.Fatalf("reserved = %d, want 3", ok)
+	}
+	if err := g.Allow("openid"); err == nil {
+		t.Fatal("额度已用完时 Allow 应返回错误")
+	}
+
+	// 发放失败归还额度
+	g = &RedpackGuard{PerUserDay: 1, PerMinute: 1}
+	cancel, err := g.Reserve("openid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = g.Reserve("other"); err == nil {
+		t.Fatal("超过 PerMinute 时应返回错误")
+	}
+	cancel()
+	if _, err = g.Reserve("openid"); err != nil {
+		t.Fatalf("归还后应可以再次占用: %v", err)
+	}
+}
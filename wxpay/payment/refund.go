@@ -4,12 +4,10 @@ import (
 	"encoding/base64"
 	"encoding/xml"
 	"errors"
-	"io/ioutil"
 	"net/http"
-	"strconv"
 	"strings"
 
-	"github.com/medivhzhan/weapp/util"
+	"github.com/wanghuobo/weapp/util"
 )
 
 const (
@@ -19,107 +17,75 @@ const (
 // Refunder 退款表单数据
 type Refunder struct {
 	// 必填 ...
-	AppID         string `xml:"appid"`  // 小程序ID
-	MchID         string `xml:"mch_id"` // 商户号
-	TotalFee      int    `xml:"total_fee"`
-	RefundFee     int    `xml:"refund_fee"`               // 退款金额: 退款总金额，订单总金额，单位为分，只能为整数
-	TransactionID string `xml:"transaction_id,omitempty"` // 微信订单号: 微信生成的订单号，在支付通知中有返回。和商户订单号二选一
-	OutTradeNo    string `xml:"out_trade_no,omitempty"`   // 商户订单号: 商户系统内部订单号，要求32个字符内，只能是数字、大小写字母_-|*@ ，且在同一个商户号下唯一。 和微信订单号二选一
-	OutRefundNo   string `xml:"out_refund_no"`            // 商户退款单号: 商户系统内部的退款单号，商户系统内部唯一，只能是数字、大小写字母_-|*@ ，同一退款单号多次请求只退一笔。
+	AppID         string `sign:"appid"`  // 小程序ID
+	MchID         string `sign:"mch_id"` // 商户号
+	TotalFee      int    `sign:"total_fee"`
+	RefundFee     int    `sign:"refund_fee"`              // 退款金额: 退款总金额，订单总金额，单位为分，只能为整数
+	TransactionID string `sign:"transaction_id,omitzero"` // 微信订单号: 微信生成的订单号，在支付通知中有返回。和商户订单号二选一
+	OutTradeNo    string `sign:"out_trade_no,omitzero"`   // 商户订单号: 商户系统内部订单号，要求32个字符内，只能是数字、大小写字母_-|*@ ，且在同一个商户号下唯一。 和微信订单号二选一
+	OutRefundNo   string `sign:"out_refund_no"`           // 商户退款单号: 商户系统内部的退款单号，商户系统内部唯一，只能是数字、大小写字母_-|*@ ，同一退款单号多次请求只退一笔。
 
 	// 选填 ...
 	// RefundFeeType string `xml:"refund_fee_type,omitempty"` // 货币种类: 货币类型，符合ISO 4217标准的三位字母代码，默认人民币: CNY
-	RefundDesc string `xml:"refund_desc,omitempty"` // 退款原因: 若商户传入，会在下发给用户的退款消息中体现退款原因
+	RefundDesc string `sign:"refund_desc,omitzero"` // 退款原因: 若商户传入，会在下发给用户的退款消息中体现退款原因
 
 	// 退款结果通知url: 异步接收微信支付退款结果通知的回调地址
 	// 通知 URL 必须为外网可访问且不允许带参数
 	// 如果参数中传了notify_url，则商户平台上配置的回调地址将不会生效。
-	NotifyURL string `xml:"notify_url,omitempty"`
+	NotifyURL string `sign:"notify_url,omitzero"`
 
 	// 退款资金来源: 仅针对老资金流商户使用
 	// REFUND_SOURCE_UNSETTLED_FUNDS---未结算资金退款（默认使用未结算资金退款）
 	// REFUND_SOURCE_RECHARGE_FUNDS---可用余额退款
 	// RefundAccount string `xml:"refund_account,omitempty"`
-}
 
-type refunder struct {
-	XMLName xml.Name `xml:"xml"`
-	Refunder
-	Sign     string `xml:"sign"`                // 签名
-	NonceStr string `xml:"nonce_str"`           // 随机字符串
-	SignType string `xml:"sign_type,omitempty"` // 签名类型: 目前支持HMAC-SHA256和MD5，默认为MD5
+	// 服务商模式的子商户 APPID 和子商户号, 此时 AppID 和 MchID 为服务商的
+	SubAppID string `sign:"sub_appid,omitzero"`
+	SubMchID string `sign:"sub_mch_id,omitzero"`
 }
 
 // 请求前准备
-func (r Refunder) prepare(key string) (refunder, error) {
-	ref := refunder{
-		Refunder: r,
-		SignType: "MD5",
-		NonceStr: util.RandomString(32),
-	}
-
-	signData := map[string]string{
-		"appid":         ref.AppID,
-		"mch_id":        ref.MchID,
-		"nonce_str":     ref.NonceStr,
-		"out_refund_no": ref.OutRefundNo,
-		"total_fee":     strconv.Itoa(ref.TotalFee),
-		"refund_fee":    strconv.Itoa(ref.RefundFee),
-		"sign_type":     ref.SignType,
-	}
-
+func (r Refunder) prepare(key, signType string) (fields, error) {
 	switch {
-	case r.TransactionID == "" && r.OutRefundNo == "":
-		return ref, errors.New("out_trade_no 和 transition_id 必须填写一个")
-	case r.TransactionID != "" && r.OutRefundNo != "":
-		return ref, errors.New("out_trade_no 和 transition_id 只能填写一个")
-	case r.TransactionID != "":
-		signData["transaction_id"] = r.TransactionID
-	case r.OutRefundNo != "":
-		signData["out_trade_no"] = r.OutTradeNo
+	case r.TransactionID == "" && r.OutTradeNo == "":
+		return nil, errors.New("out_trade_no 和 transition_id 必须填写一个")
+	case r.TransactionID != "" && r.OutTradeNo != "":
+		return nil, errors.New("out_trade_no 和 transition_id 只能填写一个")
 	}
 
-	if r.RefundDesc != "" {
-		signData["refund_desc"] = r.RefundDesc
-	}
-
-	if r.NotifyURL != "" {
-		signData["notify_url"] = r.NotifyURL
-	}
-
-	sign, err := util.SignByMD5(signData, key)
-	ref.Sign = sign
-
-	return ref, err
+	return signedFields(r, key, signType)
 }
 
 // RefundedResponse 请求退款返回数据
 type RefundedResponse struct {
-	AppID         string `xml:"appid"`
-	MchID         string `xml:"mch_id"`
-	TransactionID string `xml:"transaction_id"` // 微信订单号: 微信生成的订单号，在支付通知中有返回。和商户订单号二选一
-	OutTradeNo    string `xml:"out_trade_no"`   // 商户订单号: 商户系统内部订单号，要求32个字符内，只能是数字、大小写字母_-|*@ ，且在同一个商户号下唯一。 和微信订单号二选一
-	OutRefundNo   string `xml:"out_refund_no"`  // 商户退款单号: 商户系统内部的退款单号，商户系统内部唯一，只能是数字、大小写字母_-|*@ ，同一退款单号多次请求只退一笔。
+	AppID         string `xml:"appid" json:"appid"`
+	MchID         string `xml:"mch_id" json:"mch_id"`
+	TransactionID string `xml:"transaction_id" json:"transaction_id"` // 微信订单号: 微信生成的订单号，在支付通知中有返回。和商户订单号二选一
+	OutTradeNo    string `xml:"out_trade_no" json:"out_trade_no"`     // 商户订单号: 商户系统内部订单号，要求32个字符内，只能是数字、大小写字母_-|*@ ，且在同一个商户号下唯一。 和微信订单号二选一
+	OutRefundNo   string `xml:"out_refund_no" json:"out_refund_no"`   // 商户退款单号: 商户系统内部的退款单号，商户系统内部唯一，只能是数字、大小写字母_-|*@ ，同一退款单号多次请求只退一笔。
 	// 微信退款单号
-	RefundID string `xml:"refund_id"`
+	RefundID string `xml:"refund_id" json:"refund_id"`
 	// 退款总金额,单位为分,可以做部分退款
-	RefundFee int `xml:"refund_fee"`
+	RefundFee int `xml:"refund_fee" json:"refund_fee"`
 	// 应结退款金额
 	// 去掉非充值代金券退款金额后的退款金额，退款金额=申请退款金额-非充值代金券退款金额，退款金额<=申请退款金额
-	SettlementRefundFee int `xml:"settlement_refund_fee"`
+	SettlementRefundFee int `xml:"settlement_refund_fee" json:"settlement_refund_fee"`
 	// 标价金额
 	// 订单总金额，单位为分，只能为整数
-	TotalFee int `xml:"total_fee"`
+	TotalFee int `xml:"total_fee" json:"total_fee"`
 	// 应结订单金额
 	// 去掉非充值代金券金额后的订单总金额，应结订单金额=订单金额-非充值代金券金额，应结订单金额<=订单金额。
-	SettlementTotalFee int `xml:"settlement_total_fee"`
+	SettlementTotalFee int `xml:"settlement_total_fee" json:"settlement_total_fee"`
 	// 标价币种
 	// FeeType            int `xml:"fee_type"`
 	// 现金支付金额
-	CashFee       int    `xml:"cash_fee"`
-	CashRefundFee int    `xml:"cash_refund_fee"`
-	Sign          string `xml:"sign"`
-	NonceStr      string `xml:"nonce_str"`
+	CashFee       int    `xml:"cash_fee" json:"cash_fee"`
+	CashRefundFee int    `xml:"cash_refund_fee" json:"cash_refund_fee"`
+	Sign          string `xml:"sign" json:"sign"`
+	NonceStr      string `xml:"nonce_str" json:"nonce_str"`
+
+	SubAppID string `xml:"sub_appid,omitempty" json:"sub_appid,omitempty"`   // 服务商模式子商户 APPID
+	SubMchID string `xml:"sub_mch_id,omitempty" json:"sub_mch_id,omitempty"` // 服务商模式子商户号
 
 	// TODO: ...
 	// coupon_type_$n
@@ -137,7 +103,7 @@ type refundedResponse struct {
 
 // Refund 发起退款请求
 func (r Refunder) Refund(key, certPath, keyPath string) (rres RefundedResponse, err error) {
-	data, err := r.prepare(key)
+	data, err := r.prepare(key, SignTypeMD5)
 	if err != nil {
 		return
 	}
@@ -147,8 +113,13 @@ func (r Refunder) Refund(key, certPath, keyPath string) (rres RefundedResponse,
 		return
 	}
 
+	return parseRefundedResponse(XMLCodec, resData, r)
+}
+
+// 解析退款结果, 校验回传的商户标识与退款请求一致
+func parseRefundedResponse(cd Codec, resData []byte, r Refunder) (rres RefundedResponse, err error) {
 	var res refundedResponse
-	if err = xml.Unmarshal(resData, &res); err != nil {
+	if err = cd.Unmarshal(resData, &res); err != nil {
 		return
 	}
 	err = res.Check()
@@ -156,14 +127,24 @@ func (r Refunder) Refund(key, certPath, keyPath string) (rres RefundedResponse,
 		return
 	}
 
+	if err = checkEcho(r.AppID, r.MchID, res.AppID, res.MchID); err != nil {
+		return
+	}
+
+	if err = checkSubEcho(r.SubAppID, r.SubMchID, res.SubAppID, res.SubMchID); err != nil {
+		return
+	}
+
 	rres = res.RefundedResponse
 	return
 }
 
-//  退款结果通知
+// 退款结果通知
 type refundNotify struct {
 	AppID      string `xml:"appid"`       // 小程序 APPID
 	MchID      string `xml:"mch_id"`      // 商户号
+	SubAppID   string `xml:"sub_appid"`   // 服务商模式子商户 APPID
+	SubMchID   string `xml:"sub_mch_id"`  // 服务商模式子商户号
 	NonceStr   string `xml:"nonce_str"`   // 随机字符串
 	Ciphertext string `xml:"req_info"`    // 加密信息
 	ReturnCode string `xml:"return_code"` // 返回状态码: SUCCESS/FAIL
@@ -182,48 +163,69 @@ func (res refundNotify) Check() error {
 
 // RefundedNotify 解密后的退款通知消息体
 type RefundedNotify struct {
-	AppID         string // 小程序ID
-	MchID         string // 商户号
-	NonceStr      string // 随机字符串
-	TransactionID string `xml:"transaction_id"` // 微信支付订单号
+	AppID         string `json:"appid"`                               // 小程序ID
+	MchID         string `json:"mch_id"`                              // 商户号
+	SubAppID      string `json:"sub_appid,omitempty"`                 // 服务商模式子商户 APPID
+	SubMchID      string `json:"sub_mch_id,omitempty"`                // 服务商模式子商户号
+	NonceStr      string `json:"nonce_str"`                           // 随机字符串
+	TransactionID string `xml:"transaction_id" json:"transaction_id"` // 微信支付订单号
 	// 商户系统内部订单号: 要求32个字符内，只能是数字、大小写字母_-|*@ ，且在同一个商户号下唯一。
-	OutTradeNo  string  `xml:"out_trade_no"`
-	RefundID    string  `xml:"refund_id"`     // 微信退款单号
-	OutRefundNo string  `xml:"out_refund_no"` // 商户退款单号
-	TotalFee    float64 `xml:"total_fee"`     // 标价金额
+	OutTradeNo  string  `xml:"out_trade_no" json:"out_trade_no"`
+	RefundID    string  `xml:"refund_id" json:"refund_id"`         // 微信退款单号
+	OutRefundNo string  `xml:"out_refund_no" json:"out_refund_no"` // 商户退款单号
+	TotalFee    float64 `xml:"total_fee" json:"total_fee"`         // 标价金额
 	// 当该订单有使用非充值券时，返回此字段。
 	// 应结订单金额=订单金额-非充值代金券金额，应结订单金额<=订单金额。
-	Settlement float64 `xml:"settlement_total_fee,omitempty"`
-	RefundFee  float64 `xml:"refund_fee"` // 退款总金额,单位为分
+	Settlement float64 `xml:"settlement_total_fee,omitempty" json:"settlement_total_fee,omitempty"`
+	RefundFee  float64 `xml:"refund_fee" json:"refund_fee"` // 退款总金额,单位为分
 	// 退款金额
 	// 退款金额=申请退款金额-非充值代金券退款金额，退款金额<=申请退款金额
-	SettlementRefund float64 `xml:"settlement_refund_fee"`
+	SettlementRefund float64 `xml:"settlement_refund_fee" json:"settlement_refund_fee"`
 	// 退款状态
 	// SUCCESS 退款成功 | CHANGE 退款异常 | REFUNDCLOSE 退款关闭
-	RefundStatus string `xml:"refund_status"`
+	RefundStatus string `xml:"refund_status" json:"refund_status"`
 	// 退款成功时间
 	// 资金退款至用户帐号的时间，格式2017-12-15 09:46:01
-	SuccessTime string `xml:"success_time,omitempty"`
+	SuccessTime string `xml:"success_time,omitempty" json:"success_time,omitempty"`
 	// 退款入账账户:取当前退款单的退款入账方
 	// 1）退回银行卡:  {银行名称}{卡类型}{卡尾号}
 	// 2）退回支付用户零钱: 支付用户零钱
 	// 3）退还商户: 商户基本账户 商户结算银行账户
 	// 4）退回支付用户零钱通: 支付用户零钱通
-	ReceiveAccount string `xml:"refund_recv_accout"`
+	ReceiveAccount string `xml:"refund_recv_accout" json:"refund_recv_accout"`
 	// 退款资金来源
 	// REFUND_SOURCE_RECHARGE_FUNDS 可用余额退款/基本账户
 	// REFUND_SOURCE_UNSETTLED_FUNDS 未结算资金退款
-	RefundAccount string `xml:"refund_account"`
+	RefundAccount string `xml:"refund_account" json:"refund_account"`
 	// 退款发起来源
 	// API接口
 	// VENDOR_PLATFORM商户平台
-	Source string `xml:"refund_request_source"`
+	Source string `xml:"refund_request_source" json:"refund_request_source"`
+
+	// 通知原文的 SHA-256 摘要(十六进制), 由 HandleRefundedNotify 填写
+	BodySHA256 string `xml:"-" json:"body_sha256,omitempty"`
+	// 加密信息中未收录的参数, 如微信新增的字段
+	Extra map[string]string `xml:"-" json:"extra,omitempty"`
+}
+
+// 解密退款通知中的 req_info, 密钥为支付密钥 MD5 的小写十六进制
+func decryptRefundInfo(reqInfo, key string) ([]byte, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(reqInfo)
+	if err != nil {
+		return nil, err
+	}
+	key, err = util.MD5(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return util.AesECBDecrypt(ciphertext, []byte(strings.ToLower(key)))
 }
 
 // HandleRefundedNotify 处理退款结果通知
 // key: 微信支付 KEY
 func HandleRefundedNotify(res http.ResponseWriter, req *http.Request, key string, fuck func(RefundedNotify) (bool, string)) error {
-	body, err := ioutil.ReadAll(req.Body)
+	body, sum, err := readBody(req.Body)
 	if err != nil {
 		return err
 	}
@@ -238,17 +240,7 @@ func HandleRefundedNotify(res http.ResponseWriter, req *http.Request, key string
 		return err
 	}
 
-	ciphertext, err := base64.StdEncoding.DecodeString(ref.Ciphertext)
-	if err != nil {
-		return err
-	}
-	key, err = util.MD5(key)
-	if err != nil {
-		return err
-	}
-	key = strings.ToLower(key)
-
-	bts, err := util.AesECBDecrypt(ciphertext, []byte(key))
+	bts, err := decryptRefundInfo(ref.Ciphertext, key)
 	if err != nil {
 		return err
 	}
@@ -257,11 +249,16 @@ func HandleRefundedNotify(res http.ResponseWriter, req *http.Request, key string
 		AppID:    ref.AppID,
 		NonceStr: ref.NonceStr,
 		MchID:    ref.MchID,
+		SubAppID: ref.SubAppID,
+		SubMchID: ref.SubMchID,
+
+		BodySHA256: sum,
 	}
 
 	if err := xml.Unmarshal(bts, &ntf); err != nil {
 		return err
 	}
+	ntf.Extra = extraFields(bts, ntf)
 
 	pr := newReplay(fuck(ntf))
 
@@ -0,0 +1,174 @@
+package payment
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BatchOptions 批量退款参数
+type BatchOptions struct {
+	Concurrency int           // 并发数, 默认4
+	Interval    time.Duration // 两次请求的最小间隔, 所有并发共用, 默认不限制
+	MaxAttempts int           // 单笔退款最多尝试次数, 仅对 FREQ_LIMIT 等可重试的错误重试, 默认3次
+
+	// Backoff 收到 FREQ_LIMIT 后全部并发暂停的时间, 默认1秒,
+	// 连续出现时翻倍, 最长30秒, 请求成功后恢复
+	Backoff time.Duration
+
+	CallOptions []CallOption // 每笔退款使用的调用参数
+}
+
+// RefundResult 单笔退款结果
+type RefundResult struct {
+	Refunder Refunder
+	Response RefundedResponse
+	Err      error
+	Attempts int // 请求次数
+}
+
+// RefundReport 批量退款结果, Results 与提交的退款顺序一致
+type RefundReport struct {
+	Results   []RefundResult
+	Succeeded int
+	Failed    int
+}
+
+// 批量请求节奏控制
+type pacer struct {
+	interval time.Duration
+	backoff  time.Duration
+
+	mu      sync.Mutex
+	next    time.Time     // 下次允许请求的时间
+	penalty time.Duration // 当前频率限制暂停时间
+}
+
+// 等待到允许请求的时间
+func (p *pacer) wait(ctx context.Context) error {
+	p.mu.Lock()
+	now := time.Now()
+	at := p.next
+	if at.Before(now) {
+		at = now
+	}
+	p.next = at.Add(p.interval)
+	p.mu.Unlock()
+
+	d := time.Until(at)
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// 收到频率限制, 推迟所有并发的下次请求
+func (p *pacer) limited() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.penalty == 0 {
+		p.penalty = p.backoff
+	} else if p.penalty *= 2; p.penalty > 30*time.Second {
+		p.penalty = 30 * time.Second
+	}
+
+	if at := time.Now().Add(p.penalty); at.After(p.next) {
+		p.next = at
+	}
+}
+
+func (p *pacer) ok() {
+	p.mu.Lock()
+	p.penalty = 0
+	p.mu.Unlock()
+}
+
+// 批量请求中可以重试的错误
+func retryableBatchError(err error) bool {
+	switch ErrCodeOf(err) {
+	case "FREQ_LIMIT", "SYSTEMERROR", "BIZERR_NEED_RETRY":
+		return true
+	}
+
+	return false
+}
+
+// RefundBatch 批量退款
+// 以有限的并发提交退款, 遇到 FREQ_LIMIT 时全部并发暂停后重试, 每笔退款的结果记录在报告中。
+// ctx 取消后未提交的退款以 ctx.Err() 作为结果返回
+func (c *Client) RefundBatch(ctx context.Context, items []Refunder, opts BatchOptions) RefundReport {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+
+	p := &pacer{interval: opts.Interval, backoff: opts.Backoff}
+	report := RefundReport{Results: make([]RefundResult, len(items))}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				report.Results[idx] = c.refundWithPacing(ctx, p, items[idx], opts)
+			}
+		}()
+	}
+
+	for i := range items {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, r := range report.Results {
+		if r.Err == nil {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+
+	return report
+}
+
+func (c *Client) refundWithPacing(ctx context.Context, p *pacer, r Refunder, opts BatchOptions) (res RefundResult) {
+	res.Refunder = r
+	for {
+		if res.Err = p.wait(ctx); res.Err != nil {
+			return
+		}
+
+		res.Attempts++
+		res.Response, res.Err = c.Refund(ctx, r, opts.CallOptions...)
+		if res.Err == nil {
+			p.ok()
+			return
+		}
+
+		if !retryableBatchError(res.Err) || res.Attempts >= opts.MaxAttempts {
+			return
+		}
+
+		if ErrCodeOf(res.Err) == "FREQ_LIMIT" {
+			p.limited()
+		}
+	}
+}
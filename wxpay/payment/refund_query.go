@@ -0,0 +1,214 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+const refundQueryAPI = "/pay/refundquery"
+
+// 退款状态
+const (
+	RefundStatusSuccess    = "SUCCESS"     // 退款成功
+	RefundStatusClose      = "REFUNDCLOSE" // 退款关闭
+	RefundStatusProcessing = "PROCESSING"  // 退款处理中
+	RefundStatusChange     = "CHANGE"      // 退款异常, 需要到商户平台手动处理
+)
+
+// RefundQuery 退款查询参数
+// 微信订单号、商户订单号、商户退款单号和微信退款单号四选一, 同时填写时只发送优先级最高的一个:
+// RefundID > OutRefundNo > TransactionID > OutTradeNo
+type RefundQuery struct {
+	AppID         string `sign:"appid"`
+	MchID         string `sign:"mch_id"`
+	TransactionID string `sign:"transaction_id,omitzero"` // 微信订单号
+	OutTradeNo    string `sign:"out_trade_no,omitzero"`   // 商户订单号
+	OutRefundNo   string `sign:"out_refund_no,omitzero"`  // 商户退款单号
+	RefundID      string `sign:"refund_id,omitzero"`      // 微信退款单号
+
+	// 偏移量, 按订单查询且退款超过10笔时使用, 每次返回10笔, 从 Offset 开始
+	Offset int `sign:"offset,omitzero"`
+}
+
+// 请求前准备
+func (q RefundQuery) prepare(key, signType string) (fields, error) {
+	if q.TransactionID == "" && q.OutTradeNo == "" && q.OutRefundNo == "" && q.RefundID == "" {
+		return nil, errors.New("transaction_id, out_trade_no, out_refund_no 和 refund_id 必须填写一个")
+	}
+
+	// 按优先级只保留一个单号
+	switch {
+	case q.RefundID != "":
+		q.OutRefundNo, q.TransactionID, q.OutTradeNo = "", "", ""
+	case q.OutRefundNo != "":
+		q.TransactionID, q.OutTradeNo = "", ""
+	case q.TransactionID != "":
+		q.OutTradeNo = ""
+	}
+
+	return signedFields(q, key, signType)
+}
+
+// RefundQueryResult 退款查询结果
+type RefundQueryResult struct {
+	AppID         string `xml:"appid" json:"appid"`
+	MchID         string `xml:"mch_id" json:"mch_id"`
+	NonceStr      string `xml:"nonce_str" json:"nonce_str"`
+	TransactionID string `xml:"transaction_id" json:"transaction_id"` // 微信订单号
+	OutTradeNo    string `xml:"out_trade_no" json:"out_trade_no"`     // 商户订单号
+	TotalFee      int    `xml:"total_fee" json:"total_fee"`           // 订单金额
+	// 应结订单金额, 使用免充值券时返回
+	Settlement  int    `xml:"settlement_total_fee,omitempty" json:"settlement_total_fee,omitempty"`
+	FeeType     string `xml:"fee_type,omitempty" json:"fee_type,omitempty"`
+	CashFee     int    `xml:"cash_fee" json:"cash_fee"`         // 现金支付金额
+	RefundCount int    `xml:"refund_count" json:"refund_count"` // 本次返回的退款笔数
+	// 订单的退款总笔数, 请求中设置了 Offset 时返回
+	TotalRefundCount int `xml:"total_refund_count,omitempty" json:"total_refund_count,omitempty"`
+
+	// 使用 refund_count 的序号生成的退款项
+	Refunds []RefundItem `xml:"-" json:"refunds,omitempty"`
+}
+
+// RefundItem 一笔退款, 由应答中的 refund_fee_$n 等参数生成
+type RefundItem struct {
+	OutRefundNo         string `json:"out_refund_no"`         // 商户退款单号
+	RefundID            string `json:"refund_id"`             // 微信退款单号
+	RefundChannel       string `json:"refund_channel"`        // 退款渠道: ORIGINAL 原路退款 | BALANCE 退回到余额 | OTHER_BALANCE | OTHER_BANKCARD
+	RefundFee           int    `json:"refund_fee"`            // 申请退款金额
+	SettlementRefundFee int    `json:"settlement_refund_fee"` // 退款金额 = 申请退款金额 - 免充值券退款金额
+	CouponRefundFee     int    `json:"coupon_refund_fee"`     // 代金券退款总金额
+	RefundStatus        string `json:"refund_status"`         // 退款状态, 见 RefundStatus 常量
+	RefundAccount       string `json:"refund_account"`        // 退款资金来源
+	RefundRecvAccount   string `json:"refund_recv_accout"`    // 退款入账账户, 如 "招商银行信用卡0403"、"支付用户零钱"
+	RefundSuccessTime   string `json:"refund_success_time"`   // 退款成功时间, 格式为 2016-07-25 15:26:26
+
+	// 使用 coupon_refund_count_$n 的序号生成的代金券退款项
+	Coupons []CouponResponseModel `json:"coupons,omitempty"`
+}
+
+// Find 按商户退款单号查找退款项
+func (r RefundQueryResult) Find(outRefundNo string) (RefundItem, bool) {
+	for _, item := range r.Refunds {
+		if item.OutRefundNo == outRefundNo {
+			return item, true
+		}
+	}
+
+	return RefundItem{}, false
+}
+
+type refundQueryResult struct {
+	response
+	RefundQueryResult
+}
+
+func parseRefundQueryResult(cd Codec, data []byte, appID, mchID string) (res RefundQueryResult, err error) {
+	var qres refundQueryResult
+	if err = cd.Unmarshal(data, &qres); err != nil {
+		return
+	}
+
+	if err = qres.Check(); err != nil {
+		return
+	}
+
+	if err = checkEcho(appID, mchID, qres.AppID, qres.MchID); err != nil {
+		return
+	}
+
+	res = qres.RefundQueryResult
+
+	raw, err := parseRawFields(data)
+	if err != nil {
+		return
+	}
+	for i := 0; i < res.RefundCount; i++ {
+		res.Refunds = append(res.Refunds, newRefundItem(raw, strconv.Itoa(i)))
+	}
+
+	return
+}
+
+// 读取序号为 n 的退款项
+func newRefundItem(raw map[string]string, n string) RefundItem {
+	atoi := func(name string) int {
+		v, _ := strconv.Atoi(raw[name+n])
+		return v
+	}
+
+	item := RefundItem{
+		OutRefundNo:         raw["out_refund_no_"+n],
+		RefundID:            raw["refund_id_"+n],
+		RefundChannel:       raw["refund_channel_"+n],
+		RefundFee:           atoi("refund_fee_"),
+		SettlementRefundFee: atoi("settlement_refund_fee_"),
+		CouponRefundFee:     atoi("coupon_refund_fee_"),
+		RefundStatus:        raw["refund_status_"+n],
+		RefundAccount:       raw["refund_account_"+n],
+		RefundRecvAccount:   raw["refund_recv_accout_"+n],
+		RefundSuccessTime:   raw["refund_success_time_"+n],
+	}
+
+	for m := 0; m < atoi("coupon_refund_count_"); m++ {
+		suffix := n + "_" + strconv.Itoa(m)
+		fee, _ := strconv.ParseInt(raw["coupon_refund_fee_"+suffix], 10, 64)
+		item.Coupons = append(item.Coupons, CouponResponseModel{
+			CouponId:   raw["coupon_refund_id_"+suffix],
+			CouponFee:  fee,
+			CouponType: raw["coupon_type_"+suffix],
+		})
+	}
+
+	return item
+}
+
+// Query 查询退款
+//
+// @key payment secret key
+func (q RefundQuery) Query(key string) (res RefundQueryResult, err error) {
+	reqData, err := q.prepare(key, SignTypeMD5)
+	if err != nil {
+		return
+	}
+
+	data, err := util.PostXML(baseURL+refundQueryAPI, reqData)
+	if err != nil {
+		return
+	}
+
+	return parseRefundQueryResult(XMLCodec, data, q.AppID, q.MchID)
+}
+
+// QueryRefund 查询退款
+// 按订单查询时返回该订单的全部退款(最多10笔, 更多时使用 Offset 分页),
+// 按退款单号查询时只返回该笔退款。退款不存在时返回错误代码 REFUNDNOTEXIST
+func (c *Client) QueryRefund(ctx context.Context, q RefundQuery, opts ...CallOption) (res RefundQueryResult, err error) {
+	if err = c.begin(); err != nil {
+		return
+	}
+	defer c.end()
+
+	opt := c.options(opts)
+	cfg := opt.conf
+	if q.AppID == "" {
+		q.AppID = cfg.AppID
+	}
+	if q.MchID == "" {
+		q.MchID = cfg.MchID
+	}
+
+	reqData, err := q.prepare(cfg.Key, opt.signType)
+	if err != nil {
+		return
+	}
+
+	data, err := c.post(ctx, opt, refundQueryAPI, reqData, false)
+	if err != nil {
+		return
+	}
+
+	return parseRefundQueryResult(cfg.codec(), data, q.AppID, q.MchID)
+}
@@ -0,0 +1,38 @@
+package payment
+
+import "testing"
+
+func TestRefundQueryPriority(t *testing.T) {
+	tests := []struct {
+		name string
+		q    RefundQuery
+		want string // 发送的单号参数
+	}{
+		{"all", RefundQuery{TransactionID: "t", OutTradeNo: "o", OutRefundNo: "r", RefundID: "w"}, "refund_id"},
+		{"out_refund_no", RefundQuery{TransactionID: "t", OutTradeNo: "o", OutRefundNo: "r"}, "out_refund_no"},
+		{"transaction_id", RefundQuery{TransactionID: "t", OutTradeNo: "o"}, "transaction_id"},
+		{"out_trade_no", RefundQuery{OutTradeNo: "o"}, "out_trade_no"},
+	}
+
+	ids := []string{"transaction_id", "out_trade_no", "out_refund_no", "refund_id"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.q.AppID, tt.q.MchID = "wxd930ea5d5a258f4f", "10000100"
+			fs, err := tt.q.prepare(testKey, SignTypeMD5)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data := fs.signData()
+			for _, id := range ids {
+				if _, ok := data[id]; ok != (id == tt.want) {
+					t.Errorf("%s sent = %v, want only %s", id, ok, tt.want)
+				}
+			}
+		})
+	}
+
+	if _, err := (RefundQuery{AppID: "wxd930ea5d5a258f4f", MchID: "10000100"}).prepare(testKey, SignTypeMD5); err == nil {
+		t.Fatal("未填写单号时应返回错误")
+	}
+}
@@ -0,0 +1,143 @@
+package payment
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// refund_desc 最大长度(字符)
+const maxRefundDescLength = 80
+
+// 退款原因分类
+const (
+	RefundReasonCustomer  = "CUSTOMER"  // 用户申请
+	RefundReasonOutStock  = "OUT_STOCK" // 商品缺货
+	RefundReasonQuality   = "QUALITY"   // 质量问题
+	RefundReasonDuplicate = "DUPLICATE" // 重复支付
+	RefundReasonPrice     = "PRICE"     // 价格调整
+	RefundReasonCanceled  = "CANCELED"  // 订单取消
+	RefundReasonOther     = "OTHER"     // 其他
+)
+
+var (
+	refundReasonsMu sync.RWMutex
+	refundReasons   = map[string]string{
+		RefundReasonCustomer:  "用户申请",
+		RefundReasonOutStock:  "商品缺货",
+		RefundReasonQuality:   "质量问题",
+		RefundReasonDuplicate: "重复支付",
+		RefundReasonPrice:     "价格调整",
+		RefundReasonCanceled:  "订单取消",
+		RefundReasonOther:     "其他",
+	}
+)
+
+// RegisterRefundReason 注册自定义退款原因分类
+// 名称会出现在用户收到的退款消息中, 不同分类的名称不能重复
+//
+// @code 分类代码
+// @label 分类名称, 为空时删除分类
+func RegisterRefundReason(code, label string) error {
+	refundReasonsMu.Lock()
+	defer refundReasonsMu.Unlock()
+
+	if label == "" {
+		delete(refundReasons, code)
+		return nil
+	}
+
+	if strings.Contains(label, refundReasonSep) {
+		return errors.New("退款原因名称不能包含 " + refundReasonSep)
+	}
+	for c, l := range refundReasons {
+		if l == label && c != code {
+			return errors.New("退款原因名称已被 " + c + " 使用")
+		}
+	}
+	refundReasons[code] = label
+
+	return nil
+}
+
+// 分类名称与说明的分隔符
+const refundReasonSep = ": "
+
+// RefundReason 结构化的退款原因
+// 以 "分类名称: 说明" 的格式写入 refund_desc, 账单和通知中的退款原因可以通过 ParseRefundReason 还原分类
+type RefundReason struct {
+	Code string // 分类代码, 见 RefundReason 常量
+	Text string // 补充说明, 可以为空, 超长部分会被截断
+}
+
+// Desc 生成 refund_desc, 总长度不超过80个字符
+func (r RefundReason) Desc() (string, error) {
+	refundReasonsMu.RLock()
+	label, ok := refundReasons[r.Code]
+	refundReasonsMu.RUnlock()
+
+	if !ok {
+		return "", errors.New("未知的退款原因: " + r.Code)
+	}
+
+	text := strings.TrimSpace(r.Text)
+	if text == "" {
+		return label, nil
+	}
+
+	desc := label + refundReasonSep + text
+	if utf8.RuneCountInString(desc) <= maxRefundDescLength {
+		return desc, nil
+	}
+
+	return string([]rune(desc)[:maxRefundDescLength]), nil
+}
+
+// ParseRefundReason 解析 refund_desc 中的退款原因
+// 不是 RefundReason 生成的退款原因返回 false
+func ParseRefundReason(desc string) (r RefundReason, ok bool) {
+	label := desc
+	if i := strings.Index(desc, refundReasonSep); i >= 0 {
+		label, r.Text = desc[:i], desc[i+len(refundReasonSep):]
+	}
+
+	refundReasonsMu.RLock()
+	defer refundReasonsMu.RUnlock()
+
+	for code, l := range refundReasons {
+		if l == label {
+			r.Code = code
+			return r, true
+		}
+	}
+
+	return RefundReason{}, false
+}
+
+// SetReason 设置退款原因
+func (r *Refunder) SetReason(reason RefundReason) (err error) {
+	r.RefundDesc, err = reason.Desc()
+	return
+}
+
+// Reason 解析退款原因, 见 ParseRefundReason
+func (r Refunder) Reason() (RefundReason, bool) {
+	return ParseRefundReason(r.RefundDesc)
+}
+
+// 校验退款原因
+// strict 为 true 时必须使用 RefundReason 生成的退款原因
+func checkRefundDesc(desc string, strict bool) error {
+	if utf8.RuneCountInString(desc) > maxRefundDescLength {
+		return errors.New("退款原因超过80个字符")
+	}
+
+	if strict {
+		if _, ok := ParseRefundReason(desc); !ok {
+			return errors.New("退款原因必须使用 RefundReason 设置")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 微信支付接口域名
+const (
+	BaseURLBackup = "https://api2.mch.weixin.qq.com"  // 境内备用域名
+	BaseURLHK     = "https://apihk.mch.weixin.qq.com" // 香港接入点, 境外服务器就近接入
+)
+
+// EndpointLatency 接口地址探测结果
+type EndpointLatency struct {
+	URL     string
+	Latency time.Duration // 探测耗时, 失败时为0
+	Err     error         // 探测失败的原因
+}
+
+// 多个接口地址的选择
+// 按探测延迟排序后使用最快的地址, 请求出现网络错误或5xx时切换到下一个,
+// 切换后一直使用(粘滞), 直到下次探测或新地址也失败
+type endpoints struct {
+	mu      sync.Mutex
+	urls    []string // 按延迟排序
+	current int
+}
+
+func newEndpoints(urls []string) *endpoints {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	return &endpoints{urls: append([]string(nil), urls...)}
+}
+
+// 当前使用的地址
+func (e *endpoints) get() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.urls[e.current]
+}
+
+// 地址请求失败, 正在使用该地址时切换到下一个
+func (e *endpoints) fail(u string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.urls[e.current] == u {
+		e.current = (e.current + 1) % len(e.urls)
+	}
+}
+
+// 按探测结果重新排序, 探测失败的地址排在最后
+func (e *endpoints) reorder(results []EndpointLatency) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if (results[i].Err == nil) != (results[j].Err == nil) {
+			return results[i].Err == nil
+		}
+		return results[i].Latency < results[j].Latency
+	})
+
+	urls := make([]string, len(results))
+	for i, r := range results {
+		urls[i] = r.URL
+	}
+
+	e.mu.Lock()
+	e.urls = urls
+	e.current = 0
+	e.mu.Unlock()
+}
+
+// Endpoint 当前使用的接口地址
+// 未配置 Endpoints 时返回 BaseURL
+func (c *Client) Endpoint() string {
+	if c.endpoints == nil {
+		return c.conf().BaseURL
+	}
+
+	return c.endpoints.get()
+}
+
+// ProbeEndpoints 探测各接口地址的延迟, 并切换到最快的地址
+// 未配置 Endpoints 时不做任何处理。探测请求不签名, 只测量建立连接并收到应答的耗时,
+// 可以在启动时及定期(如每10分钟)调用
+func (c *Client) ProbeEndpoints(ctx context.Context) ([]EndpointLatency, error) {
+	if c.endpoints == nil {
+		return nil, nil
+	}
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	urls := c.conf().Endpoints
+	results := make([]EndpointLatency, len(urls))
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			results[i] = c.probe(ctx, u)
+		}(i, u)
+	}
+	wg.Wait()
+
+	c.endpoints.reorder(append([]EndpointLatency(nil), results...))
+
+	return results, nil
+}
+
+func (c *Client) probe(ctx context.Context, u string) (r EndpointLatency) {
+	r.URL = u
+
+	ctx, cancel := context.WithTimeout(ctx, c.conf().Timeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodHead, u, nil)
+	if err != nil {
+		r.Err = err
+		return
+	}
+	req = req.WithContext(ctx)
+
+	// 使用独立连接, 避免复用已有连接导致测量结果偏小
+	req.Close = true
+
+	start := time.Now()
+	res, err := c.http.Do(req)
+	if err != nil {
+		r.Err = err
+		return
+	}
+	res.Body.Close()
+	r.Latency = time.Since(start)
+
+	return
+}
+
+// 请求失败时切换接口地址
+//
+// @ctx 调用方的 ctx, 已取消时不切换
+func (c *Client) endpointFailed(ctx context.Context, u string) {
+	if c.endpoints != nil && ctx.Err() == nil {
+		c.endpoints.fail(u)
+	}
+}
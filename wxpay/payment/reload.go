@@ -0,0 +1,69 @@
+package payment
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+// Reload 热加载配置, 用于不重启服务轮换支付密钥、商户证书和通知令牌密钥
+// 新配置校验通过后整体替换旧配置(copy-on-write), 之后开始的请求使用新配置,
+// 已经开始的请求可能使用旧配置完成。
+// 商户证书变化时先加载新证书, 加载失败则不替换配置;
+// Transport 和 Endpoints 在新建客户端时确定, 修改不会生效。
+// 不能修改 appid 和 mch_id, 更换商户需要新建客户端
+func (c *Client) Reload(cfg Config) error {
+	if err := cfg.normalize(); err != nil {
+		return err
+	}
+
+	c.tlsMu.Lock()
+	defer c.tlsMu.Unlock()
+
+	old := c.conf()
+	if cfg.AppID != old.AppID || cfg.MchID != old.MchID {
+		return errors.New("热加载不能修改 appid 和 mch_id")
+	}
+	cfg.Transport, cfg.Endpoints = old.Transport, old.Endpoints
+
+	if !certChanged(old, &cfg) {
+		c.config.Store(&cfg)
+		return nil
+	}
+
+	// 没有配置证书时清空, 下次使用时报错
+	var cli *http.Client
+	if cfg.hasCertificate() {
+		cert, err := cfg.certificate()
+		if err != nil {
+			return err
+		}
+		cli = &http.Client{Transport: util.NewTransport(cfg.Transport, cert)}
+	}
+
+	prev := c.tls
+	c.tls = cli
+	c.config.Store(&cfg)
+
+	if prev != nil {
+		prev.CloseIdleConnections()
+	}
+
+	return nil
+}
+
+// 商户证书配置是否变化
+// 证书路径不变而文件内容变化时同样需要重新加载
+func certChanged(old, cfg *Config) bool {
+	if len(cfg.CertPEM) > 0 || len(old.CertPEM) > 0 {
+		return !bytes.Equal(old.CertPEM, cfg.CertPEM) || !bytes.Equal(old.KeyPEM, cfg.KeyPEM)
+	}
+
+	if len(cfg.PKCS12) > 0 || len(old.PKCS12) > 0 {
+		return !bytes.Equal(old.PKCS12, cfg.PKCS12) || old.PKCS12Password != cfg.PKCS12Password
+	}
+
+	return cfg.hasCertificate() || old.hasCertificate()
+}
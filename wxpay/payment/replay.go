@@ -0,0 +1,97 @@
+package payment
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// ReplayDeadLetter 把死信中的原始通知重新交给 handler 处理
+// handler 应当是业务系统中接收通知的处理器(包含解析、校验及业务回调),
+// 返回 SUCCESS 时视为重放成功
+func ReplayDeadLetter(h http.Handler, letter DeadLetter) error {
+	path := letter.Path
+	if path == "" {
+		path = "/"
+	}
+
+	req, err := http.NewRequest(http.MethodPost, path, bytes.NewReader(letter.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+
+	rec := &replayRecorder{header: make(http.Header)}
+	h.ServeHTTP(rec, req)
+
+	if rec.code != 0 && rec.code != http.StatusOK {
+		return errors.New("重放失败: HTTP " + strconv.Itoa(rec.code))
+	}
+
+	var ret replay
+	if err = xml.Unmarshal(rec.body.Bytes(), &ret); err != nil {
+		return errors.New("重放失败: 无法解析返回内容")
+	}
+
+	if ret.Code != "SUCCESS" {
+		return errors.New("重放失败: " + ret.Msg)
+	}
+
+	return nil
+}
+
+// ReplayResult 批量重放结果
+type ReplayResult struct {
+	Succeeded int
+	Failed    map[string]error // 重放失败的死信ID及原因
+}
+
+// ReplayDeadLetters 重放存储中的全部死信, 重放成功的死信会被删除
+func ReplayDeadLetters(store DeadLetterStore, h http.Handler) (res ReplayResult, err error) {
+	letters, err := store.List()
+	if err != nil {
+		return
+	}
+
+	res.Failed = make(map[string]error)
+	for _, letter := range letters {
+		if e := ReplayDeadLetter(h, letter); e != nil {
+			res.Failed[letter.ID] = e
+			continue
+		}
+
+		if err = store.Delete(letter.ID); err != nil {
+			return
+		}
+		res.Succeeded++
+	}
+
+	return
+}
+
+// 记录重放时 handler 的返回
+type replayRecorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (r *replayRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *replayRecorder) Write(b []byte) (int, error) {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+
+	return r.body.Write(b)
+}
+
+func (r *replayRecorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+}
@@ -0,0 +1,31 @@
+package payment
+
+import (
+	"context"
+	"time"
+)
+
+// RetryPolicy 重试策略
+type RetryPolicy struct {
+	MaxAttempts int           // 最多尝试次数, 包含第一次请求
+	Backoff     time.Duration // 首次重试前的等待时间, 之后每次翻倍
+}
+
+// Retry 按策略执行 fn 直到成功
+// 权限不足, 金额超限等重试也无法成功的错误会直接返回, 不再重试
+func Retry(ctx context.Context, p RetryPolicy, fn func() error) error {
+	wait := p.Backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || IsPermanent(err) || attempt >= p.MaxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
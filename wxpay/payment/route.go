@@ -0,0 +1,73 @@
+package payment
+
+import "sync"
+
+// NotifyRouter 服务商模式按子商户号分发通知
+// Paid 和 Refunded 可直接作为 HandlePaidNotify 和 HandleRefundedNotify 的处理函数
+type NotifyRouter struct {
+	// 未注册子商户的处理函数, 为空时应答 FAIL, 微信会按策略重新发送通知
+	DefaultPaid     func(PaidNotify) (bool, string)
+	DefaultRefunded func(RefundedNotify) (bool, string)
+
+	mu       sync.RWMutex
+	paid     map[string]func(PaidNotify) (bool, string)
+	refunded map[string]func(RefundedNotify) (bool, string)
+}
+
+// HandlePaid 注册子商户支付结果通知处理函数
+//
+// @subMchID 子商户号
+func (r *NotifyRouter) HandlePaid(subMchID string, fn func(PaidNotify) (bool, string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.paid == nil {
+		r.paid = make(map[string]func(PaidNotify) (bool, string))
+	}
+	r.paid[subMchID] = fn
+}
+
+// HandleRefunded 注册子商户退款结果通知处理函数
+//
+// @subMchID 子商户号
+func (r *NotifyRouter) HandleRefunded(subMchID string, fn func(RefundedNotify) (bool, string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.refunded == nil {
+		r.refunded = make(map[string]func(RefundedNotify) (bool, string))
+	}
+	r.refunded[subMchID] = fn
+}
+
+// Paid 按子商户号分发支付结果通知
+func (r *NotifyRouter) Paid(ntf PaidNotify) (bool, string) {
+	r.mu.RLock()
+	fn, ok := r.paid[ntf.SubMchID]
+	r.mu.RUnlock()
+
+	if !ok {
+		fn = r.DefaultPaid
+	}
+	if fn == nil {
+		return false, "未知子商户: " + ntf.SubMchID
+	}
+
+	return fn(ntf)
+}
+
+// Refunded 按子商户号分发退款结果通知
+func (r *NotifyRouter) Refunded(ntf RefundedNotify) (bool, string) {
+	r.mu.RLock()
+	fn, ok := r.refunded[ntf.SubMchID]
+	r.mu.RUnlock()
+
+	if !ok {
+		fn = r.DefaultRefunded
+	}
+	if fn == nil {
+		return false, "未知子商户: " + ntf.SubMchID
+	}
+
+	return fn(ntf)
+}
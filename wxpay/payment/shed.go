@@ -0,0 +1,111 @@
+package payment
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNotifyBusy 通知处理已饱和, 已直接应答失败, 微信会稍后重新发送
+var ErrNotifyBusy = errors.New("通知处理繁忙")
+
+// NotifyLimiter 通知处理并发限制
+// 秒杀等流量高峰时通知集中到达, 超过并发数的通知进入等待队列,
+// 队列已满或等待超时则直接应答失败, 由微信按重试策略重新发送, 避免堆积大量 goroutine
+type NotifyLimiter struct {
+	slots chan struct{}
+	queue chan struct{}
+	wait  time.Duration
+	shed  int64
+}
+
+// NewNotifyLimiter 新建通知处理并发限制
+//
+// @concurrency 同时处理的通知数
+// @queue 等待处理的通知数, 为0时超过并发数立即拒绝
+// @wait 排队等待的最长时间, 为0时不限制(仍受请求上下文限制)
+func NewNotifyLimiter(concurrency, queue int, wait time.Duration) *NotifyLimiter {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	return &NotifyLimiter{
+		slots: make(chan struct{}, concurrency),
+		queue: make(chan struct{}, queue),
+		wait:  wait,
+	}
+}
+
+// 获取处理名额, 失败时计入拒绝数
+func (l *NotifyLimiter) acquire(req *http.Request) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	select {
+	case l.queue <- struct{}{}:
+	default:
+		atomic.AddInt64(&l.shed, 1)
+		return false
+	}
+	defer func() { <-l.queue }()
+
+	var timeout <-chan time.Time
+	if l.wait > 0 {
+		timer := time.NewTimer(l.wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timeout:
+	case <-req.Context().Done():
+	}
+
+	atomic.AddInt64(&l.shed, 1)
+	return false
+}
+
+func (l *NotifyLimiter) release() {
+	<-l.slots
+}
+
+// Shed 被拒绝的通知数
+func (l *NotifyLimiter) Shed() int64 {
+	return atomic.LoadInt64(&l.shed)
+}
+
+// Wrap 为任意通知处理器增加并发限制, 饱和时返回 503
+// 适用于 APIv3 等非 XML 应答的通知地址, 微信收到非成功状态码后同样会重新发送
+func (l *NotifyLimiter) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if !l.acquire(req) {
+			http.Error(res, ErrNotifyBusy.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		defer l.release()
+
+		h.ServeHTTP(res, req)
+	})
+}
+
+// 饱和时应答 FAIL
+func writeBusy(res http.ResponseWriter) error {
+	b, err := xml.Marshal(newReplay(false, ErrNotifyBusy.Error()))
+	if err != nil {
+		return err
+	}
+
+	res.WriteHeader(http.StatusOK)
+	if _, err = res.Write(b); err != nil {
+		return err
+	}
+
+	return ErrNotifyBusy
+}
@@ -0,0 +1,146 @@
+package payment
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+// 签名类型
+const (
+	SignTypeMD5        = "MD5"
+	SignTypeHMACSHA256 = "HMAC-SHA256"
+)
+
+// 按签名类型签名
+func sign(signType string, data map[string]string, key string) (string, error) {
+	if signType == SignTypeHMACSHA256 {
+		return util.SignByHMACSHA256(data, key)
+	}
+
+	return util.SignByMD5(data, key)
+}
+
+// 签名使用的临时数据, 高并发下单时复用以减少内存分配
+type signer struct {
+	fields byName
+	buf    []byte
+	md5    hash.Hash
+}
+
+var signerPool = sync.Pool{
+	New: func() interface{} { return &signer{md5: md5.New()} },
+}
+
+// 按参数名排序
+type byName []field
+
+func (s byName) Len() int           { return len(s) }
+func (s byName) Less(i, j int) bool { return s[i].name < s[j].name }
+func (s byName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// 把待签名串写入 s.buf
+// 参数按参数名的 ASCII 码排序后以 a=1&b=2 的形式连接, 最后追加 key=密钥
+func (s *signer) write(fs fields, key string) {
+	s.fields = s.fields[:0]
+	for _, f := range fs {
+		if f.name != "sign" && !f.nosign && f.value != "" {
+			s.fields = append(s.fields, f)
+		}
+	}
+	sort.Sort(&s.fields)
+
+	s.buf = s.buf[:0]
+	for _, f := range s.fields {
+		s.buf = append(s.buf, f.name...)
+		s.buf = append(s.buf, '=')
+		s.buf = append(s.buf, f.value...)
+		s.buf = append(s.buf, '&')
+	}
+	s.buf = append(s.buf, "key="...)
+	s.buf = append(s.buf, key...)
+}
+
+// 待签名串, 用于排查签名错误
+func (fs fields) signString(key string) string {
+	s := signerPool.Get().(*signer)
+	defer signerPool.Put(s)
+
+	s.write(fs, key)
+	return string(s.buf)
+}
+
+// 直接对参数列表签名, 参数按参数名的 ASCII 码排序
+// 不创建中间 map, 待签名串写入复用的缓冲
+func (fs fields) sign(signType, key string) string {
+	s := signerPool.Get().(*signer)
+	defer signerPool.Put(s)
+
+	s.write(fs, key)
+
+	var h hash.Hash
+	if signType == SignTypeHMACSHA256 {
+		h = hmac.New(sha256.New, []byte(key))
+	} else {
+		h = s.md5
+		h.Reset()
+	}
+	h.Write(s.buf)
+
+	// 签名为大写十六进制
+	var sum [sha256.Size]byte
+	digest := h.Sum(sum[:0])
+	out := make([]byte, len(digest)*2)
+	for i, b := range digest {
+		out[i*2] = upperHex[b>>4]
+		out[i*2+1] = upperHex[b&0x0f]
+	}
+
+	return string(out)
+}
+
+const upperHex = "0123456789ABCDEF"
+
+// 校验支付结果通知的签名, 读取后恢复请求体, 之后可以交给 HandlePaidNotify 处理
+func verifyPaidRequest(req *http.Request, key string) error {
+	if key == "" {
+		return errors.New("未设置支付密钥, 无法校验通知签名")
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	raw, err := parseRawFields(body)
+	if err != nil {
+		return err
+	}
+
+	return verifySign(raw, key)
+}
+
+// 校验通知或应答参数中的签名
+// 签名类型由参数中的 sign_type 决定, 未设置时为 MD5
+func verifySign(raw map[string]string, key string) error {
+	fs := make(fields, 0, len(raw))
+	for name, value := range raw {
+		fs = append(fs, field{name: name, value: value})
+	}
+
+	if !hmac.Equal([]byte(raw["sign"]), []byte(fs.sign(raw["sign_type"], key))) {
+		return errors.New("签名校验失败")
+	}
+
+	return nil
+}
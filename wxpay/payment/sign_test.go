@@ -0,0 +1,220 @@
+package payment
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+const (
+	testKey   = "192006250b4c09247ec02edce69f6a2d"
+	testNonce = "ibuaiVcKdpRxkhJA"
+)
+
+func testOrder() Order {
+	return Order{
+		AppID:      "wxd930ea5d5a258f4f",
+		MchID:      "10000100",
+		TotalFee:   1,
+		NotifyURL:  "https://example.com/notify",
+		OpenID:     "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o",
+		Body:       "测试商品<a&b>",
+		OutTradeNo: "20150806125346",
+		IP:         "123.12.12.123",
+	}
+}
+
+func TestSignCanonical(t *testing.T) {
+	noCredit := testOrder()
+	noCredit.NoCredit = true
+	noCredit.StartedAt = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	noCredit.ExpiredAt = time.Date(2024, 1, 1, 12, 15, 0, 0, time.UTC)
+
+	redpacker := Redpacker{
+		AppID:    "wxd930ea5d5a258f4f",
+		MchID:    "10000100",
+		BillNo:   "10000100201501010000000001",
+		ToUser:   "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o",
+		Amount:   100,
+		SendName: "商户",
+		Wishing:  "新年快乐",
+		ActName:  "新年活动",
+		IP:       "123.12.12.123",
+	}
+
+	tests := []struct {
+		name     string
+		signType string
+		prepare  func() (fields, error)
+		want     string // 待签名串
+		sign     string
+	}{
+		{
+			name:     "order md5",
+			signType: SignTypeMD5,
+			prepare: func() (fields, error) {
+				o := testOrder()
+				return o.prepare(testKey, SignTypeMD5)
+			},
+			want: "appid=wxd930ea5d5a258f4f&body=测试商品<a&b>&mch_id=10000100&nonce_str=ibuaiVcKdpRxkhJA" +
+				"&notify_url=https://example.com/notify&openid=oUpF8uMuAJO_M2pxb1Q9zNjWeS6o&out_trade_no=20150806125346" +
+				"&sign_type=MD5&spbill_create_ip=123.12.12.123&total_fee=1&trade_type=JSAPI&key=" + testKey,
+			sign: "06780939F6549ECC7C4F6D1DFFCB9917",
+		},
+		{
+			name:     "order hmac-sha256 with limit_pay and time range",
+			signType: SignTypeHMACSHA256,
+			prepare: func() (fields, error) {
+				return noCredit.prepare(testKey, SignTypeHMACSHA256)
+			},
+			want: "appid=wxd930ea5d5a258f4f&body=测试商品<a&b>&limit_pay=no_credit&mch_id=10000100&nonce_str=ibuaiVcKdpRxkhJA" +
+				"&notify_url=https://example.com/notify&openid=oUpF8uMuAJO_M2pxb1Q9zNjWeS6o&out_trade_no=20150806125346" +
+				"&sign_type=HMAC-SHA256&spbill_create_ip=123.12.12.123&time_expire=20240101121500&time_start=20240101120000" +
+				"&total_fee=1&trade_type=JSAPI&key=" + testKey,
+			sign: "24ABB8C2865A2E39190F9A18F4F70BDEC44370160E53E4D537306AF5C0420565",
+		},
+		{
+			name:     "refunder hmac-sha256",
+			signType: SignTypeHMACSHA256,
+			prepare: func() (fields, error) {
+				return Refunder{
+					AppID:       "wxd930ea5d5a258f4f",
+					MchID:       "10000100",
+					TotalFee:    100,
+					RefundFee:   50,
+					OutTradeNo:  "20150806125346",
+					OutRefundNo: "R20150806125346",
+				}.prepare(testKey, SignTypeHMACSHA256)
+			},
+			want: "appid=wxd930ea5d5a258f4f&mch_id=10000100&nonce_str=ibuaiVcKdpRxkhJA&out_refund_no=R20150806125346" +
+				"&out_trade_no=20150806125346&refund_fee=50&sign_type=HMAC-SHA256&total_fee=100&key=" + testKey,
+			sign: "84C4E452A8B43A866B0B4148508A9346C2140887DC42E8CA12535BB29470BB8A",
+		},
+		{
+			// 红包不发送 sign_type, 空的 remark 发送但不参与签名
+			name:     "redpacker md5",
+			signType: "",
+			prepare: func() (fields, error) {
+				return redpacker.prepare(testKey)
+			},
+			want: "act_name=新年活动&client_ip=123.12.12.123&mch_billno=10000100201501010000000001&mch_id=10000100" +
+				"&nonce_str=ibuaiVcKdpRxkhJA&re_openid=oUpF8uMuAJO_M2pxb1Q9zNjWeS6o&send_name=商户&total_amount=100" +
+				"&total_num=1&wishing=新年快乐&wxappid=wxd930ea5d5a258f4f&key=" + testKey,
+			sign: "A8C8FDC701B1A0FEB05A0652EF8AA9EA",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs, err := tt.prepare()
+			if err != nil {
+				t.Fatal(err)
+			}
+			fs.set("nonce_str", testNonce)
+
+			if got := fs.signString(testKey); got != tt.want {
+				t.Errorf("signString\n got: %s\nwant: %s", got, tt.want)
+			}
+
+			if got := fs.sign(tt.signType, testKey); got != tt.sign {
+				t.Errorf("sign = %s, want %s", got, tt.sign)
+			}
+
+			// 与 util 中基于 map 的实现一致
+			want, err := sign(tt.signType, fs.signData(), testKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want != tt.sign {
+				t.Errorf("util sign = %s, want %s", want, tt.sign)
+			}
+		})
+	}
+}
+
+type tagRules struct {
+	Required string `sign:"required"`
+	Omitted  string `sign:"omitted,omitzero"`
+	ZeroInt  int    `sign:"zero_int,omitzero"`
+	NoSign   string `sign:"no_sign,nosign"`
+	CDATA    string `sign:"cdata,cdata"`
+	Empty    string `sign:"empty"`
+	Ignored  string `sign:"-"`
+}
+
+func TestSignTagRules(t *testing.T) {
+	fs, err := encodeFields(tagRules{
+		Required: "1",
+		NoSign:   "skip",
+		CDATA:    "<x&y>",
+		Ignored:  "ignored",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// omitzero 的零值和 - 不发送, 必填参数为空时同样发送
+	data, err := xml.Marshal(fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantXML := "<xml><required>1</required><no_sign>skip</no_sign><cdata><![CDATA[<x&y>]]></cdata><empty></empty></xml>"
+	if string(data) != wantXML {
+		t.Errorf("xml\n got: %s\nwant: %s", data, wantXML)
+	}
+
+	// nosign 和空值不参与签名, CDATA 参数按原值签名
+	want := "cdata=<x&y>&required=1&key=" + testKey
+	if got := fs.signString(testKey); got != want {
+		t.Errorf("signString\n got: %s\nwant: %s", got, want)
+	}
+
+	md5Sign, err := util.SignByMD5(map[string]string{"required": "1", "cdata": "<x&y>"}, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fs.sign(SignTypeMD5, testKey); got != md5Sign {
+		t.Errorf("sign = %s, want %s", got, md5Sign)
+	}
+
+	// 已有的 sign 参数不参与签名
+	fs.set("sign", "whatever")
+	if got := fs.signString(testKey); got != want {
+		t.Errorf("signString with sign\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func benchmarkSign(b *testing.B, signType string, byMap func(map[string]string, string) (string, error)) {
+	o := testOrder()
+	fs, err := o.prepare(testKey, signType)
+	if err != nil {
+		b.Fatal(err)
+	}
+	data := fs.signData()
+
+	b.Run("fields", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			fs.sign(signType, testKey)
+		}
+	})
+
+	b.Run("util", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := byMap(data, testKey); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func Benchmark_SignMD5(b *testing.B) {
+	benchmarkSign(b, SignTypeMD5, util.SignByMD5)
+}
+
+func Benchmark_SignHMACSHA256(b *testing.B) {
+	benchmarkSign(b, SignTypeHMACSHA256, util.SignByHMACSHA256)
+}
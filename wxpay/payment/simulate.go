@@ -0,0 +1,163 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+// Simulator 支付通知模拟器
+// 本地开发联调时生成格式与签名都和微信支付一致的通知, 并投递到业务系统的通知地址,
+// 上线前即可验证通知处理逻辑
+type Simulator struct {
+	Key      string       // 微信支付密钥, 与业务系统配置一致
+	SignType string       // 签名类型, 为空时使用 MD5
+	Client   *http.Client // 为空时使用 http.DefaultClient
+}
+
+// PaidNotifyBody 生成支付结果通知内容
+// 未设置 NonceStr 时随机生成, Coupons 不为空时按序号生成 coupon_id_$n、coupon_fee_$n 和 coupon_type_$n
+func (s Simulator) PaidNotifyBody(ntf PaidNotify) ([]byte, error) {
+	if ntf.NonceStr == "" {
+		ntf.NonceStr = util.RandomString(32)
+	}
+	if s.SignType != "" {
+		ntf.SignType = s.SignType
+	}
+	if ntf.CouponCount == 0 {
+		ntf.CouponCount = len(ntf.Coupons)
+	}
+
+	fs, err := encodeTag(ntf, "xml")
+	if err != nil {
+		return nil, err
+	}
+
+	fs.set("return_code", "SUCCESS")
+	fs.set("result_code", "SUCCESS")
+	for i, c := range ntf.Coupons {
+		fs.set("coupon_id_"+strconv.Itoa(i), c.CouponId)
+		fs.set("coupon_fee_"+strconv.Itoa(i), strconv.FormatInt(c.CouponFee, 10))
+		if c.CouponType != "" {
+			fs.set("coupon_type_"+strconv.Itoa(i), c.CouponType)
+		}
+	}
+
+	sign, err := sign(ntf.SignType, fs.signData(), s.Key)
+	if err != nil {
+		return nil, err
+	}
+	fs.set("sign", sign)
+
+	return xml.Marshal(fs)
+}
+
+// RefundedNotifyBody 生成退款结果通知内容
+// 退款信息使用商户密钥加密后放入 req_info
+func (s Simulator) RefundedNotifyBody(ntf RefundedNotify) ([]byte, error) {
+	info, err := encodeTag(ntf, "xml")
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := xml.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := util.MD5(s.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := util.AesECBEncrypt(plaintext, []byte(strings.ToLower(key)))
+	if err != nil {
+		return nil, err
+	}
+
+	nonceStr := ntf.NonceStr
+	if nonceStr == "" {
+		nonceStr = util.RandomString(32)
+	}
+
+	fs := fields{
+		{name: "return_code", value: "SUCCESS"},
+		{name: "appid", value: ntf.AppID},
+		{name: "mch_id", value: ntf.MchID},
+		{name: "nonce_str", value: nonceStr},
+		{name: "req_info", value: base64.StdEncoding.EncodeToString(ciphertext)},
+	}
+
+	return xml.Marshal(fs)
+}
+
+// SendPaidNotify 投递支付结果通知
+// 业务系统返回 SUCCESS 以外的结果时返回错误
+func (s Simulator) SendPaidNotify(ctx context.Context, url string, ntf PaidNotify) error {
+	body, err := s.PaidNotifyBody(ntf)
+	if err != nil {
+		return err
+	}
+
+	return s.send(ctx, url, body)
+}
+
+// SendRefundedNotify 投递退款结果通知
+// 业务系统返回 SUCCESS 以外的结果时返回错误
+func (s Simulator) SendRefundedNotify(ctx context.Context, url string, ntf RefundedNotify) error {
+	body, err := s.RefundedNotifyBody(ntf)
+	if err != nil {
+		return err
+	}
+
+	return s.send(ctx, url, body)
+}
+
+func (s Simulator) send(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "text/xml")
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("通知处理失败: HTTP %d %s", res.StatusCode, data)
+	}
+
+	var ret replay
+	if err = xml.Unmarshal(data, &ret); err != nil {
+		return errors.New("通知处理失败: 无法解析返回内容")
+	}
+
+	if ret.Code != "SUCCESS" {
+		return errors.New("通知处理失败: " + ret.Msg)
+	}
+
+	return nil
+}
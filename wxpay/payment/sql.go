@@ -0,0 +1,76 @@
+package payment
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// 以 JSON 格式存入数据库
+func valueJSON(v interface{}) (driver.Value, error) {
+	return json.Marshal(v)
+}
+
+// 从数据库读取 JSON 格式数据
+func scanJSON(src, v interface{}) error {
+	switch data := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(data, v)
+	case string:
+		return json.Unmarshal([]byte(data), v)
+	default:
+		return errors.New("不支持的数据库字段类型")
+	}
+}
+
+// Value 实现 driver.Valuer, 以 JSON 格式存入数据库
+func (res PaidResponse) Value() (driver.Value, error) {
+	return valueJSON(res)
+}
+
+// Scan 实现 sql.Scanner
+func (res *PaidResponse) Scan(src interface{}) error {
+	return scanJSON(src, res)
+}
+
+// Value 实现 driver.Valuer, 以 JSON 格式存入数据库
+func (ntf PaidNotify) Value() (driver.Value, error) {
+	return valueJSON(ntf)
+}
+
+// Scan 实现 sql.Scanner
+func (ntf *PaidNotify) Scan(src interface{}) error {
+	return scanJSON(src, ntf)
+}
+
+// Value 实现 driver.Valuer, 以 JSON 格式存入数据库
+func (r QueryResult) Value() (driver.Value, error) {
+	return valueJSON(r)
+}
+
+// Scan 实现 sql.Scanner
+func (r *QueryResult) Scan(src interface{}) error {
+	return scanJSON(src, r)
+}
+
+// Value 实现 driver.Valuer, 以 JSON 格式存入数据库
+func (res RefundedResponse) Value() (driver.Value, error) {
+	return valueJSON(res)
+}
+
+// Scan 实现 sql.Scanner
+func (res *RefundedResponse) Scan(src interface{}) error {
+	return scanJSON(src, res)
+}
+
+// Value 实现 driver.Valuer, 以 JSON 格式存入数据库
+func (ntf RefundedNotify) Value() (driver.Value, error) {
+	return valueJSON(ntf)
+}
+
+// Scan 实现 sql.Scanner
+func (ntf *RefundedNotify) Scan(src interface{}) error {
+	return scanJSON(src, ntf)
+}
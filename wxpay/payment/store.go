@@ -0,0 +1,125 @@
+package payment
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// OrderRecord 本地订单记录
+type OrderRecord struct {
+	Order      Order
+	PrepayID   string
+	TradeState string // 最近一次得知的交易状态, 见 TradeState 常量
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+
+	PayFailures []PayFailure // 前端调起支付失败记录
+
+	// PrepayAt prepay_id 生成时间, prepay_id 有效期为2小时
+	PrepayAt time.Time
+}
+
+// OrderStore 本地订单存储
+// 设置到 Config.OrderStore 后, 客户端下单成功时自动保存订单
+type OrderStore interface {
+	// SaveOrder 保存订单, 已存在时覆盖
+	SaveOrder(OrderRecord) error
+	// GetOrder 通过商户订单号读取订单, 不存在时返回 nil
+	GetOrder(outTradeNo string) (*OrderRecord, error)
+}
+
+// PendingOrderStore 可以读取未支付订单的订单存储, 供 CloseWorker 使用
+type PendingOrderStore interface {
+	OrderStore
+	// PendingOrders 读取最多 limit 个未支付(NOTPAY)的订单, 按创建时间升序
+	PendingOrders(limit int) ([]OrderRecord, error)
+}
+
+// MemoryOrderStore 基于内存的订单存储, 适用于单机测试
+type MemoryOrderStore struct {
+	mu     sync.RWMutex
+	orders map[string]OrderRecord
+}
+
+// NewMemoryOrderStore 新建基于内存的订单存储
+func NewMemoryOrderStore() *MemoryOrderStore {
+	return &MemoryOrderStore{orders: make(map[string]OrderRecord)}
+}
+
+// SaveOrder 保存订单
+func (s *MemoryOrderStore) SaveOrder(r OrderRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.PayFailures = append([]PayFailure(nil), r.PayFailures...)
+	s.orders[r.Order.OutTradeNo] = r
+
+	return nil
+}
+
+// GetOrder 读取订单
+func (s *MemoryOrderStore) GetOrder(outTradeNo string) (*OrderRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.orders[outTradeNo]
+	if !ok {
+		return nil, nil
+	}
+	r.PayFailures = append([]PayFailure(nil), r.PayFailures...)
+
+	return &r, nil
+}
+
+// PendingOrders 读取未支付的订单
+func (s *MemoryOrderStore) PendingOrders(limit int) ([]OrderRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var list []OrderRecord
+	for _, r := range s.orders {
+		if r.TradeState == TradeStateNotPay {
+			r.PayFailures = append([]PayFailure(nil), r.PayFailures...)
+			list = append(list, r)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+
+	return list, nil
+}
+
+// 下单成功后保存订单
+func saveOrder(store OrderStore, o Order, res PaidResponse) error {
+	if store == nil {
+		return nil
+	}
+
+	now := time.Now()
+	r := OrderRecord{
+		Order:      o,
+		PrepayID:   res.PrePayID,
+		TradeState: TradeStateNotPay,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		PrepayAt:   now,
+	}
+
+	old, err := store.GetOrder(o.OutTradeNo)
+	if err != nil {
+		return err
+	}
+	if old != nil {
+		r.CreatedAt = old.CreatedAt
+		r.PayFailures = old.PayFailures
+	}
+
+	return store.SaveOrder(r)
+}
@@ -0,0 +1,65 @@
+package payment
+
+import "errors"
+
+// IsJSAPI 是否为小程序/公众号支付
+func (ntf PaidNotify) IsJSAPI() bool {
+	return ntf.TradeType == TradeTypeJSAPI
+}
+
+// IsNative 是否为扫码支付
+func (ntf PaidNotify) IsNative() bool {
+	return ntf.TradeType == TradeTypeNative
+}
+
+// IsMWeb 是否为 H5 支付
+func (ntf PaidNotify) IsMWeb() bool {
+	return ntf.TradeType == TradeTypeMWEB
+}
+
+// IsMicropay 是否为付款码支付
+func (ntf PaidNotify) IsMicropay() bool {
+	return ntf.TradeType == TradeTypeMicropay
+}
+
+// IsApp 是否为 APP 支付
+func (ntf PaidNotify) IsApp() bool {
+	return ntf.TradeType == TradeTypeApp
+}
+
+// CheckTradeType 检查通知的交易类型是否与下单时一致
+//
+// @expected 下单时的交易类型, 为空时视为 JSAPI(与 Order 的默认值一致)
+func (ntf PaidNotify) CheckTradeType(expected string) error {
+	if expected == "" {
+		expected = TradeTypeJSAPI
+	}
+
+	if ntf.TradeType != expected {
+		return errors.New("交易类型不一致: 通知为 " + ntf.TradeType + ", 下单为 " + expected)
+	}
+
+	return nil
+}
+
+// CheckOrder 检查通知是否与本地订单一致
+// 比较商户订单号、金额及交易类型, JSAPI 订单同时比较 openid
+func (ntf PaidNotify) CheckOrder(o Order) error {
+	if ntf.OutTradeNo != o.OutTradeNo {
+		return errors.New("商户订单号不一致: " + ntf.OutTradeNo + " != " + o.OutTradeNo)
+	}
+
+	if ntf.TotalFee != o.TotalFee {
+		return errors.New("订单金额不一致")
+	}
+
+	if err := ntf.CheckTradeType(o.TradeType); err != nil {
+		return err
+	}
+
+	if ntf.IsJSAPI() && o.OpenID != "" && ntf.OpenID != o.OpenID {
+		return errors.New("支付用户与下单用户不一致")
+	}
+
+	return nil
+}
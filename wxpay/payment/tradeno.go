@@ -0,0 +1,150 @@
+package payment
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+)
+
+// 商户订单号最大长度
+const maxTradeNoLength = 32
+
+// TradeNoStore 保存原订单号与派生订单号的对应关系
+type TradeNoStore interface {
+	// Derived 返回原订单号当前使用的派生订单号, 没有记录时返回空
+	Derived(original string) (string, error)
+	// SaveDerived 记录原订单号与派生订单号的对应关系
+	SaveDerived(original, derived string) error
+	// Original 通过派生订单号查询原订单号, 没有记录时返回空
+	Original(derived string) (string, error)
+}
+
+// TradeNoPolicy 订单号重复时的处理策略
+// 同一订单号修改金额等参数后再次下单, 微信会返回 OUT_TRADE_NO_USED,
+// 此时按策略生成派生订单号(如 20200101001-R1)重新下单, 并记录对应关系。
+type TradeNoPolicy struct {
+	Store       TradeNoStore
+	MaxAttempts int // 最多派生次数, 默认3次
+
+	// Suffix 生成第 n 次派生使用的后缀, 默认为 -R{n}
+	Suffix func(n int) string
+}
+
+func (p *TradeNoPolicy) suffix(n int) string {
+	if p.Suffix != nil {
+		return p.Suffix(n)
+	}
+
+	return "-R" + strconv.Itoa(n)
+}
+
+// 生成第 n 个派生订单号
+func (p *TradeNoPolicy) derive(original string, n int) (string, error) {
+	no := original + p.suffix(n)
+	if len(no) > maxTradeNoLength {
+		return "", errors.New("派生订单号超过32个字符: " + no)
+	}
+
+	return no, nil
+}
+
+// UnifyWithPolicy 统一下单, 订单号重复时按策略生成新订单号重试
+// 返回结果中的订单号以 PaidResponse 对应的请求为准, 可以通过 Store.Original 找回原订单号
+// Client.Unify 通过 Config.TradeNoPolicy 或 WithTradeNoPolicy 使用同样的策略
+//
+// @key payment secret key
+func (o Order) UnifyWithPolicy(key string, p *TradeNoPolicy) (PaidResponse, error) {
+	return p.unify(o, func(o Order) (PaidResponse, error) {
+		return o.Unify(key)
+	})
+}
+
+// 按策略调用 fn 下单, 订单号重复时使用派生订单号重新调用
+// p 为 nil 或未设置 Store 时直接调用 fn
+func (p *TradeNoPolicy) unify(o Order, fn func(Order) (PaidResponse, error)) (PaidResponse, error) {
+	if p == nil || p.Store == nil {
+		return fn(o)
+	}
+
+	original := o.OutTradeNo
+	derived, err := p.Store.Derived(original)
+	if err != nil {
+		return PaidResponse{}, err
+	}
+
+	max := p.MaxAttempts
+	if max <= 0 {
+		max = 3
+	}
+
+	// 已经派生过的订单号继续使用, 并从对应的次数开始计数
+	n := 0
+	if derived != "" {
+		o.OutTradeNo = derived
+		n = max
+		for i := 1; i <= max; i++ {
+			if no, _ := p.derive(original, i); no == derived {
+				n = i
+				break
+			}
+		}
+	}
+
+	for {
+		res, err := fn(o)
+		if ErrCodeOf(err) != "OUT_TRADE_NO_USED" || n >= max {
+			return res, err
+		}
+
+		n++
+		if o.OutTradeNo, err = p.derive(original, n); err != nil {
+			return res, err
+		}
+
+		if err = p.Store.SaveDerived(original, o.OutTradeNo); err != nil {
+			return res, err
+		}
+	}
+}
+
+// MemoryTradeNoStore 基于内存的订单号对应关系, 适用于单机测试
+type MemoryTradeNoStore struct {
+	mu       sync.RWMutex
+	derived  map[string]string
+	original map[string]string
+}
+
+// NewMemoryTradeNoStore 新建基于内存的订单号对应关系
+func NewMemoryTradeNoStore() *MemoryTradeNoStore {
+	return &MemoryTradeNoStore{
+		derived:  make(map[string]string),
+		original: make(map[string]string),
+	}
+}
+
+// Derived 返回原订单号当前使用的派生订单号
+func (s *MemoryTradeNoStore) Derived(original string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.derived[original], nil
+}
+
+// SaveDerived 记录原订单号与派生订单号的对应关系
+func (s *MemoryTradeNoStore) SaveDerived(original, derived string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.derived[original] = derived
+	s.original[derived] = original
+
+	return nil
+}
+
+// Original 通过派生订单号查询原订单号
+func (s *MemoryTradeNoStore) Original(derived string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.original[derived], nil
+}
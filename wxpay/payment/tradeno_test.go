@@ -0,0 +1,73 @@
+package payment
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+func TestClientUnifyTradeNoPolicy(t *testing.T) {
+	// 原订单号已被使用, 派生订单号下单成功
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		raw, err := parseRawFields(body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		res := map[string]string{
+			"return_code": "SUCCESS",
+			"result_code": "SUCCESS",
+			"appid":       raw["appid"],
+			"mch_id":      raw["mch_id"],
+			"trade_type":  raw["trade_type"],
+			"prepay_id":   "wx" + raw["out_trade_no"],
+		}
+		if raw["out_trade_no"] == "20150806125346" {
+			res = map[string]string{"return_code": "SUCCESS", "result_code": "FAIL", "err_code": "OUT_TRADE_NO_USED", "err_code_des": "商户订单号重复"}
+		}
+		res["sign"], _ = util.SignByMD5(res, testKey)
+
+		io.WriteString(w, "<xml>")
+		for k, v := range res {
+			io.WriteString(w, "<"+k+">"+v+"</"+k+">")
+		}
+		io.WriteString(w, "</xml>")
+	}))
+	defer srv.Close()
+
+	store := NewMemoryTradeNoStore()
+	c, err := NewClient(Config{
+		AppID:         "wxd930ea5d5a258f4f",
+		MchID:         "10000100",
+		Key:           testKey,
+		Profile:       ProfileMock,
+		BaseURL:       srv.URL,
+		TradeNoPolicy: &TradeNoPolicy{Store: store},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.Unify(context.Background(), testOrder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.PrePayID != "wx20150806125346-R1" {
+		t.Fatalf("PrepayID = %s", res.PrePayID)
+	}
+	if original, _ := store.Original("20150806125346-R1"); original != "20150806125346" {
+		t.Fatalf("Original = %q", original)
+	}
+
+	// 本次调用不使用策略
+	if _, err = c.Unify(context.Background(), testOrder(), WithTradeNoPolicy(nil)); ErrCodeOf(err) != "OUT_TRADE_NO_USED" {
+		t.Fatalf("err = %v, want OUT_TRADE_NO_USED", err)
+	}
+}
@@ -1,12 +1,10 @@
 package payment
 
 import (
-	"encoding/xml"
 	"errors"
-	"strconv"
 	"time"
 
-	"github.com/medivhzhan/weapp/util"
+	"github.com/wanghuobo/weapp/util"
 )
 
 const (
@@ -17,105 +15,68 @@ const (
 // Transferer transfer params
 type Transferer struct {
 	// required
-	AppID      string `xml:"mch_appid"`
-	MchID      string `xml:"mchid"`            // 商户号
-	OutTradeNo string `xml:"partner_trade_no"` // 商户订单号
-	ToUser     string `xml:"openid"`
-	Amount     int    `xml:"amount"`
+	AppID      string `sign:"mch_appid"`
+	MchID      string `sign:"mchid"`            // 商户号
+	OutTradeNo string `sign:"partner_trade_no"` // 商户订单号
+	ToUser     string `sign:"openid"`
+	Amount     int    `sign:"amount"`
 	// 企业付款描述信息
-	Desc string `xml:"desc"`
+	Desc string `sign:"desc"`
 
 	// optional
-	IP string `xml:"spbill_create_ip,omitempty"`
+	IP string `sign:"spbill_create_ip"` // 为空时使用本机IP
 	// 校验用户姓名选项
-	CheckName bool   `xml:"-"`
-	Device    string `xml:"device_info,omitempty"`
+	CheckName bool   `sign:"-"`
+	Device    string `sign:"device_info,omitzero"`
 	// 收款用户真实姓名
 	// 如果check_name设置为FORCE_CHECK，则必填用户真实姓名
-	RealName string `xml:"re_user_name,omitempty"`
-}
-
-type transferer struct {
-	XMLName xml.Name `xml:"xml"`
-	Transferer
-	// 校验用户姓名选项
-	// NO_CHECK:不校验真实姓名
-	// FORCE_CHECK:强校验真实姓名
-	CheckName string `xml:"check_name"`
-	NonceStr  string `xml:"nonce_str"`
-	Sign      string `xml:"sign"` // 签名
+	RealName string `sign:"re_user_name,omitzero"`
 }
 
 type transferResponse struct {
 	response
-	AppID         string `xml:"mch_appid"` // 小程序ID
-	MchID         string `xml:"mchid"`
-	Device        string `xml:"device_info"`
-	NonceStr      string `xml:"nonce_str"`
-	OutTradeNo    string `xml:"partner_trade_no"` // 商户订单号
-	TransactionID string `xml:"payment_no"`
+	AppID         string `xml:"mch_appid" json:"mch_appid"` // 小程序ID
+	MchID         string `xml:"mchid" json:"mchid"`
+	Device        string `xml:"device_info" json:"device_info"`
+	NonceStr      string `xml:"nonce_str" json:"nonce_str"`
+	OutTradeNo    string `xml:"partner_trade_no" json:"partner_trade_no"` // 商户订单号
+	TransactionID string `xml:"payment_no" json:"payment_no"`
 	// 微信支付成功时间
 	// format: 2015-05-19 15:26:59
-	Datetime string `xml:"payment_time"`
+	Datetime string `xml:"payment_time" json:"payment_time"`
 }
 
 // TransferResponse 转账返回数据
 type TransferResponse struct {
 	transferResponse
-	Datetime time.Time
+	Datetime time.Time `json:"payment_time"`
 }
 
 // 请求前准备
-func (t *Transferer) prepare(key string) (transferer, error) {
-	tra := transferer{
-		Transferer: *t,
-		NonceStr:   util.RandomString(32),
-	}
-
-	signData := map[string]string{
-		"mch_appid":        tra.AppID,
-		"mchid":            tra.MchID,
-		"nonce_str":        tra.NonceStr,
-		"partner_trade_no": tra.OutTradeNo,
-		"openid":           tra.ToUser,
-		"check_name":       tra.CheckName,
-		"desc":             tra.Desc,
-		"amount":           strconv.Itoa(tra.Amount),
-	}
+func (t *Transferer) prepare(key string) (fields, error) {
+	tra := *t
 
+	// 校验用户姓名选项
+	// NO_CHECK:不校验真实姓名
+	// FORCE_CHECK:强校验真实姓名
+	checkName := "NO_CHECK"
 	if t.CheckName {
-		tra.CheckName = "FORCE_CHECK"
+		checkName = "FORCE_CHECK"
 		if t.RealName == "" {
-			return tra, errors.New("选择校验用户姓名时用户姓名不能为空")
+			return nil, errors.New("选择校验用户姓名时用户姓名不能为空")
 		}
-		signData["re_user_name"] = tra.RealName
-
-	} else {
-		tra.CheckName = "NO_CHECK"
 	}
-	signData["check_name"] = tra.CheckName
 
 	if t.IP == "" {
-		ip, err := util.FetchIP()
+		ip, err := localIP()
 		if err != nil {
-			return tra, err
+			return nil, err
 		}
 
-		tra.IP = ip.String()
+		tra.IP = ip
 	}
-	signData["spbill_create_ip"] = tra.IP
 
-	if t.Device != "" {
-		signData["device_info"] = tra.Device
-	}
-
-	sign, err := util.SignByMD5(signData, key)
-	if err != nil {
-		return tra, err
-	}
-	tra.Sign = sign
-
-	return tra, nil
+	return signedFields(tra, key, "", field{name: "check_name", value: checkName})
 }
 
 // Transfer 转账到微信用户零钱
@@ -129,8 +90,13 @@ func (t Transferer) Transfer(key string, certPath, keyPath string) (res Transfer
 	if err != nil {
 		return
 	}
+
+	return parseTransferResponse(XMLCodec, resData, t.AppID, t.MchID)
+}
+
+func parseTransferResponse(cd Codec, resData []byte, appID, mchID string) (res TransferResponse, err error) {
 	var tres transferResponse
-	if err = xml.Unmarshal(resData, &tres); err != nil {
+	if err = cd.Unmarshal(resData, &tres); err != nil {
 		return
 	}
 
@@ -138,6 +104,10 @@ func (t Transferer) Transfer(key string, certPath, keyPath string) (res Transfer
 		return
 	}
 
+	if err = checkEcho(appID, mchID, tres.AppID, tres.MchID); err != nil {
+		return
+	}
+
 	res.transferResponse = tres
 	res.Datetime, err = time.Parse(transferTimeFormat, tres.Datetime)
 
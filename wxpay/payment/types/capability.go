@@ -0,0 +1,73 @@
+package types
+
+import "errors"
+
+// Capability 商户开通的产品
+type Capability string
+
+// 商户产品
+const (
+	CapabilityRefund        Capability = "refund"        // 使用证书退款
+	CapabilityTransfer      Capability = "transfer"      // 企业付款到零钱
+	CapabilityRedpack       Capability = "redpack"       // 现金红包
+	CapabilityFundFlow      Capability = "fundflow"      // 资金账单
+	CapabilityProfitSharing Capability = "profitsharing" // 分账
+	CapabilityEcommerce     Capability = "ecommerce"     // 电商收付通
+	CapabilityV3            Capability = "v3"            // APIv3
+)
+
+// Capabilities 商户已开通的产品, 为空表示不限制
+type Capabilities []Capability
+
+// Enabled 是否已开通
+func (cs Capabilities) Enabled(c Capability) bool {
+	if len(cs) == 0 {
+		return true
+	}
+
+	for _, v := range cs {
+		if v == c {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Require 未开通时返回 *CapabilityError
+func (cs Capabilities) Require(c Capability) error {
+	if cs.Enabled(c) {
+		return nil
+	}
+
+	return &CapabilityError{Capability: c}
+}
+
+// ErrCapabilityDisabled 商户未开通对应产品
+// 实际返回的是 *CapabilityError, 使用 IsCapabilityDisabled 判断
+var ErrCapabilityDisabled = errors.New("商户未开通该产品")
+
+// CapabilityError 调用了配置中未开通的产品
+// 在发起请求前返回, 避免收到含义不明确的 NOAUTH 错误
+type CapabilityError struct {
+	Capability Capability
+}
+
+func (e *CapabilityError) Error() string {
+	return ErrCapabilityDisabled.Error() + ": " + string(e.Capability)
+}
+
+// Unwrap 返回 ErrCapabilityDisabled
+func (e *CapabilityError) Unwrap() error {
+	return ErrCapabilityDisabled
+}
+
+// IsCapabilityDisabled 是否为未开通产品错误
+func IsCapabilityDisabled(err error) bool {
+	if err == ErrCapabilityDisabled {
+		return true
+	}
+
+	_, ok := err.(*CapabilityError)
+	return ok
+}
@@ -0,0 +1,191 @@
+// Package types 微信支付 v2/v3 共用的枚举值
+// 接口结构体中的对应字段仍为 string, 可以转换为这里的类型后 switch
+package types
+
+import "strings"
+
+// TradeType 交易类型
+type TradeType string
+
+// 交易类型
+const (
+	TradeTypeJSAPI    TradeType = "JSAPI"    // 小程序/公众号支付
+	TradeTypeNative   TradeType = "NATIVE"   // 扫码支付
+	TradeTypeMWEB     TradeType = "MWEB"     // H5 支付
+	TradeTypeMicropay TradeType = "MICROPAY" // 付款码支付
+	TradeTypeApp      TradeType = "APP"      // APP 支付
+	TradeTypeFacePay  TradeType = "FACEPAY"  // 刷脸支付
+)
+
+// TradeState 交易状态
+type TradeState string
+
+// 交易状态
+const (
+	TradeStateSuccess    TradeState = "SUCCESS"    // 支付成功
+	TradeStateRefund     TradeState = "REFUND"     // 转入退款
+	TradeStateNotPay     TradeState = "NOTPAY"     // 未支付
+	TradeStateClosed     TradeState = "CLOSED"     // 已关闭
+	TradeStateRevoked    TradeState = "REVOKED"    // 已撤销(付款码支付)
+	TradeStateUserPaying TradeState = "USERPAYING" // 用户支付中(付款码支付)
+	TradeStatePayError   TradeState = "PAYERROR"   // 支付失败
+)
+
+// Paid 是否已支付, 转入退款的订单也曾支付成功
+func (s TradeState) Paid() bool {
+	return s == TradeStateSuccess || s == TradeStateRefund
+}
+
+// Final 是否为终态, 终态订单不会再变化(退款除外)
+func (s TradeState) Final() bool {
+	switch s {
+	case TradeStateSuccess, TradeStateRefund, TradeStateClosed, TradeStateRevoked, TradeStatePayError:
+		return true
+	default:
+		return false
+	}
+}
+
+// BankType 付款银行, 即通知中的 bank_type
+type BankType string
+
+// 常用付款银行
+const (
+	BankTypeBalance     BankType = "OTHERS" // 零钱或其他银行
+	BankTypeICBCDebit   BankType = "ICBC_DEBIT"
+	BankTypeICBCCredit  BankType = "ICBC_CREDIT"
+	BankTypeABCDebit    BankType = "ABC_DEBIT"
+	BankTypeABCCredit   BankType = "ABC_CREDIT"
+	BankTypeBOCDebit    BankType = "BOC_DEBIT"
+	BankTypeBOCCredit   BankType = "BOC_CREDIT"
+	BankTypeCCBDebit    BankType = "CCB_DEBIT"
+	BankTypeCCBCredit   BankType = "CCB_CREDIT"
+	BankTypeCMBDebit    BankType = "CMB_DEBIT"
+	BankTypeCMBCredit   BankType = "CMB_CREDIT"
+	BankTypeCOMMDebit   BankType = "COMM_DEBIT"
+	BankTypeCOMMCredit  BankType = "COMM_CREDIT"
+	BankTypePSBCDebit   BankType = "PSBC_DEBIT"
+	BankTypePSBCCredit  BankType = "PSBC_CREDIT"
+	BankTypeCITICDebit  BankType = "CITIC_DEBIT"
+	BankTypeCITICCredit BankType = "CITIC_CREDIT"
+	BankTypeCEBDebit    BankType = "CEB_DEBIT"
+	BankTypeCEBCredit   BankType = "CEB_CREDIT"
+	BankTypeCIBDebit    BankType = "CIB_DEBIT"
+	BankTypeCIBCredit   BankType = "CIB_CREDIT"
+	BankTypeCMBCDebit   BankType = "CMBC_DEBIT"
+	BankTypeCMBCCredit  BankType = "CMBC_CREDIT"
+	BankTypeSPDBDebit   BankType = "SPDB_DEBIT"
+	BankTypeSPDBCredit  BankType = "SPDB_CREDIT"
+	BankTypeGDBDebit    BankType = "GDB_DEBIT"
+	BankTypeGDBCredit   BankType = "GDB_CREDIT"
+	BankTypePABDebit    BankType = "PAB_DEBIT"
+	BankTypePABCredit   BankType = "PAB_CREDIT"
+	BankTypeHXBDebit    BankType = "HXB_DEBIT"
+	BankTypeHXBCredit   BankType = "HXB_CREDIT"
+)
+
+// IsCredit 是否为信用卡
+func (b BankType) IsCredit() bool {
+	return strings.HasSuffix(string(b), "_CREDIT")
+}
+
+// IsDebit 是否为借记卡
+func (b BankType) IsDebit() bool {
+	return strings.HasSuffix(string(b), "_DEBIT")
+}
+
+// Bank 银行简称, 如 ICBC_DEBIT 返回 ICBC
+func (b BankType) Bank() string {
+	if i := strings.LastIndex(string(b), "_"); i > 0 {
+		return string(b)[:i]
+	}
+
+	return string(b)
+}
+
+// BankCode 付款到银行卡的收款银行编号(bank_code)
+type BankCode string
+
+// 付款到银行卡支持的银行
+const (
+	BankCodeCMB   BankCode = "1001" // 招商银行
+	BankCodeICBC  BankCode = "1002" // 工商银行
+	BankCodeCCB   BankCode = "1003" // 建设银行
+	BankCodeSPDB  BankCode = "1004" // 浦发银行
+	BankCodeABC   BankCode = "1005" // 农业银行
+	BankCodeCMBC  BankCode = "1006" // 民生银行
+	BankCodeCIB   BankCode = "1009" // 兴业银行
+	BankCodePAB   BankCode = "1010" // 平安银行
+	BankCodeCOMM  BankCode = "1020" // 交通银行
+	BankCodeCITIC BankCode = "1021" // 中信银行
+	BankCodeCEB   BankCode = "1022" // 光大银行
+	BankCodeHXB   BankCode = "1025" // 华夏银行
+	BankCodeBOC   BankCode = "1026" // 中国银行
+	BankCodeGDB   BankCode = "1027" // 广发银行
+	BankCodeBOB   BankCode = "1032" // 北京银行
+	BankCodeNBCB  BankCode = "1056" // 宁波银行
+	BankCodePSBC  BankCode = "1066" // 邮储银行
+)
+
+// RefundChannel 退款入账渠道
+type RefundChannel string
+
+// 退款入账渠道
+const (
+	RefundChannelOriginal      RefundChannel = "ORIGINAL"       // 原路退款
+	RefundChannelBalance       RefundChannel = "BALANCE"        // 退回到余额
+	RefundChannelOtherBalance  RefundChannel = "OTHER_BALANCE"  // 原账户异常退到其他余额账户
+	RefundChannelOtherBankcard RefundChannel = "OTHER_BANKCARD" // 原银行卡异常退到其他银行卡
+)
+
+// CouponType 代金券类型
+type CouponType string
+
+// 代金券类型
+const (
+	CouponTypeCash   CouponType = "CASH"    // 充值代金券
+	CouponTypeNoCash CouponType = "NO_CASH" // 免充值代金券
+)
+
+// AccountType 资金账户类型
+type AccountType string
+
+// 资金账户类型
+const (
+	AccountTypeBasic     AccountType = "BASIC"     // 基本账户
+	AccountTypeOperation AccountType = "OPERATION" // 运营账户
+	AccountTypeFees      AccountType = "FEES"      // 手续费账户
+)
+
+// Currency 货币类型, ISO 4217 三位字母代码
+type Currency string
+
+// 常用货币类型
+const (
+	CurrencyCNY Currency = "CNY" // 人民币
+	CurrencyHKD Currency = "HKD" // 港币
+	CurrencyUSD Currency = "USD" // 美元
+	CurrencyEUR Currency = "EUR" // 欧元
+	CurrencyGBP Currency = "GBP" // 英镑
+	CurrencyJPY Currency = "JPY" // 日元
+	CurrencyKRW Currency = "KRW" // 韩元
+)
+
+// OrDefault 为空时返回人民币, 接口中未返回货币类型即为人民币
+func (c Currency) OrDefault() Currency {
+	if c == "" {
+		return CurrencyCNY
+	}
+
+	return c
+}
+
+// MinorUnit 最小货币单位的小数位数, 日元和韩元为0, 其余为2
+func (c Currency) MinorUnit() int {
+	switch c {
+	case CurrencyJPY, CurrencyKRW:
+		return 0
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,157 @@
+package v3
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	applymentsAPI = "/v3/ecommerce/applyments/"
+
+	// ApplymentStateChange 进件状态变更通知类型
+	ApplymentStateChange = "APPLYMENT_STATE.CHANGE"
+)
+
+// 进件申请状态
+const (
+	ApplymentChecking          = "CHECKING"            // 资料校验中
+	ApplymentAccountNeedVerify = "ACCOUNT_NEED_VERIFY" // 待账户验证
+	ApplymentAuditing          = "AUDITING"            // 审核中
+	ApplymentRejected          = "REJECTED"            // 已驳回
+	ApplymentNeedSign          = "NEED_SIGN"           // 待签约
+	ApplymentFinish            = "FINISH"              // 完成
+	ApplymentFrozen            = "FROZEN"              // 已冻结
+	ApplymentCanceled          = "CANCELED"            // 已作废
+)
+
+// AuditDetail 驳回原因
+type AuditDetail struct {
+	ParamName    string `json:"param_name"`
+	RejectReason string `json:"reject_reason"`
+}
+
+// Applyment 进件申请状态
+type Applyment struct {
+	ApplymentID        int64         `json:"applyment_id"`
+	OutRequestNo       string        `json:"out_request_no"`
+	ApplymentState     string        `json:"applyment_state"`
+	ApplymentStateDesc string        `json:"applyment_state_desc"`
+	SignState          string        `json:"sign_state"` // 签约状态: UNSIGNED/SIGNED/NOT_SIGNABLE
+	SignURL            string        `json:"sign_url"`   // 签约链接, NEED_SIGN 状态返回
+	SubMchID           string        `json:"sub_mchid"`  // 电商平台二级商户号, FINISH 状态返回
+	LegalValidationURL string        `json:"legal_validation_url"`
+	AuditDetail        []AuditDetail `json:"audit_detail"`
+	AccountValidation  *struct {
+		AccountName              string `json:"account_name"`
+		AccountNo                string `json:"account_no"`
+		PayAmount                int    `json:"pay_amount"`
+		DestinationAccountNumber string `json:"destination_account_number"`
+		DestinationAccountName   string `json:"destination_account_name"`
+		DestinationAccountBank   string `json:"destination_account_bank"`
+		City                     string `json:"city"`
+		Remark                   string `json:"remark"`
+		Deadline                 string `json:"deadline"`
+	} `json:"account_validation,omitempty"`
+}
+
+// Terminal 申请单是否已处于终态
+func (a Applyment) Terminal() bool {
+	switch a.ApplymentState {
+	case ApplymentFinish, ApplymentRejected, ApplymentFrozen, ApplymentCanceled:
+		return true
+	}
+
+	return false
+}
+
+// QueryApplyment 通过申请单ID查询申请状态
+func (c *Client) QueryApplyment(ctx context.Context, applymentID int64) (res Applyment, err error) {
+	err = c.request(ctx, http.MethodGet, applymentsAPI+strconv.FormatInt(applymentID, 10), "", nil, &res)
+	return
+}
+
+// QueryApplymentByOutRequestNo 通过业务申请编号查询申请状态
+func (c *Client) QueryApplymentByOutRequestNo(ctx context.Context, outRequestNo string) (res Applyment, err error) {
+	err = c.request(ctx, http.MethodGet, applymentsAPI+"out-request-no/"+url.PathEscape(outRequestNo), "", nil, &res)
+	return
+}
+
+// HandleApplymentNotify 处理进件状态变更通知
+func (c *Client) HandleApplymentNotify(res http.ResponseWriter, req *http.Request, fn func(Applyment) (bool, string)) error {
+	var a Applyment
+	return c.handleNotify(res, req, &a, func(Notification) (bool, string) {
+		return fn(a)
+	})
+}
+
+// ApplymentPoller 进件状态轮询
+// 按固定间隔查询申请单, 状态变化时回调对应的处理函数, 直到申请单进入终态
+type ApplymentPoller struct {
+	Client   *Client
+	Interval time.Duration // 查询间隔, 默认1分钟
+
+	OnChange   func(prev, cur Applyment) // 任意状态变化
+	OnAuditing func(Applyment)           // 进入审核中
+	OnNeedSign func(Applyment)           // 待签约, 需要把 SignURL 发给商户
+	OnFinish   func(Applyment)           // 进件完成
+	OnRejected func(Applyment)           // 已驳回
+	OnError    func(error)               // 查询出错, 不会中断轮询
+}
+
+// Poll 轮询申请单直到终态或 ctx 取消
+func (p *ApplymentPoller) Poll(ctx context.Context, applymentID int64) (Applyment, error) {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var prev Applyment
+	for {
+		cur, err := p.Client.QueryApplyment(ctx, applymentID)
+		if err != nil {
+			if p.OnError != nil {
+				p.OnError(err)
+			}
+		} else if cur.ApplymentState != prev.ApplymentState {
+			p.transition(prev, cur)
+			prev = cur
+			if cur.Terminal() {
+				return cur, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return prev, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *ApplymentPoller) transition(prev, cur Applyment) {
+	if p.OnChange != nil {
+		p.OnChange(prev, cur)
+	}
+
+	var fn func(Applyment)
+	switch cur.ApplymentState {
+	case ApplymentAuditing:
+		fn = p.OnAuditing
+	case ApplymentNeedSign:
+		fn = p.OnNeedSign
+	case ApplymentFinish:
+		fn = p.OnFinish
+	case ApplymentRejected:
+		fn = p.OnRejected
+	}
+
+	if fn != nil {
+		fn(cur)
+	}
+}
@@ -0,0 +1,129 @@
+package v3
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	bankSearchAPI    = "/v3/capital/capitallhh/banks/search-banks-by-bank-account"
+	personalBankAPI  = "/v3/capital/capitallhh/banks/personal-banking"
+	corporateBankAPI = "/v3/capital/capitallhh/banks/corporate-banking"
+	bankBranchesAPI  = "/v3/capital/capitallhh/banks/%s/branches"
+	provincesAPI     = "/v3/capital/capitallhh/areas/provinces"
+	citiesAPI        = "/v3/capital/capitallhh/areas/provinces/%d/cities"
+)
+
+// Bank 银行信息
+type Bank struct {
+	BankAlias       string `json:"bank_alias"`        // 银行别名
+	BankAliasCode   string `json:"bank_alias_code"`   // 银行别名编码, 用于查询支行
+	AccountBank     string `json:"account_bank"`      // 开户银行, 进件时填写
+	AccountBankCode int    `json:"account_bank_code"` // 开户银行编码
+	NeedBankBranch  bool   `json:"need_bank_branch"`  // 是否需要填写支行
+}
+
+// BankList 银行列表
+type BankList struct {
+	TotalCount int    `json:"total_count"`
+	Count      int    `json:"count"`
+	Offset     int    `json:"offset"`
+	Data       []Bank `json:"data"`
+}
+
+// SearchBanksByAccount 获取对私银行卡号开户银行
+// 卡号使用平台证书加密后传输
+func (c *Client) SearchBanksByAccount(ctx context.Context, accountNumber string) (res BankList, err error) {
+	serial, err := c.encrypt(&accountNumber)
+	if err != nil {
+		return
+	}
+
+	uri := bankSearchAPI + "?account_number=" + url.QueryEscape(accountNumber)
+	err = c.request(ctx, http.MethodGet, uri, serial, nil, &res)
+	return
+}
+
+// PersonalBanks 查询支持个人业务的银行列表
+//
+// @offset 本次查询偏移量
+// @limit 本次请求最大查询条数, 最大值为200
+func (c *Client) PersonalBanks(ctx context.Context, offset, limit int) (res BankList, err error) {
+	err = c.request(ctx, http.MethodGet, pageURI(personalBankAPI, offset, limit), "", nil, &res)
+	return
+}
+
+// CorporateBanks 查询支持对公业务的银行列表
+func (c *Client) CorporateBanks(ctx context.Context, offset, limit int) (res BankList, err error) {
+	err = c.request(ctx, http.MethodGet, pageURI(corporateBankAPI, offset, limit), "", nil, &res)
+	return
+}
+
+// Province 省份
+type Province struct {
+	ProvinceName string `json:"province_name"`
+	ProvinceCode int    `json:"province_code"`
+}
+
+// Provinces 查询省份列表
+func (c *Client) Provinces(ctx context.Context) (res []Province, err error) {
+	var data struct {
+		Data []Province `json:"data"`
+	}
+
+	err = c.request(ctx, http.MethodGet, provincesAPI, "", nil, &data)
+	res = data.Data
+	return
+}
+
+// City 城市
+type City struct {
+	CityName string `json:"city_name"`
+	CityCode int    `json:"city_code"`
+}
+
+// Cities 查询城市列表
+func (c *Client) Cities(ctx context.Context, provinceCode int) (res []City, err error) {
+	var data struct {
+		Data []City `json:"data"`
+	}
+
+	err = c.request(ctx, http.MethodGet, fmt.Sprintf(citiesAPI, provinceCode), "", nil, &data)
+	res = data.Data
+	return
+}
+
+// BankBranch 支行
+type BankBranch struct {
+	BankBranchName string `json:"bank_branch_name"` // 开户银行支行名称
+	BankBranchID   string `json:"bank_branch_id"`   // 开户银行支行联行号
+}
+
+// BankBranchList 支行列表
+type BankBranchList struct {
+	TotalCount      int          `json:"total_count"`
+	Count           int          `json:"count"`
+	Offset          int          `json:"offset"`
+	Data            []BankBranch `json:"data"`
+	AccountBank     string       `json:"account_bank"`
+	AccountBankCode int          `json:"account_bank_code"`
+	BankAlias       string       `json:"bank_alias"`
+	BankAliasCode   string       `json:"bank_alias_code"`
+}
+
+// BankBranches 查询支行列表
+//
+// @bankAliasCode 银行别名编码
+// @cityCode 城市编码
+func (c *Client) BankBranches(ctx context.Context, bankAliasCode string, cityCode, offset, limit int) (res BankBranchList, err error) {
+	uri := pageURI(fmt.Sprintf(bankBranchesAPI, url.PathEscape(bankAliasCode)), offset, limit) + "&city_code=" + strconv.Itoa(cityCode)
+	err = c.request(ctx, http.MethodGet, uri, "", nil, &res)
+	return
+}
+
+func pageURI(uri string, offset, limit int) string {
+	return uri + "?offset=" + strconv.Itoa(offset) + "&limit=" + strconv.Itoa(limit)
+}
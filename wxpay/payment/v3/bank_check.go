@@ -0,0 +1,133 @@
+package v3
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// 分页查询支行时每页条数
+const bankBranchPageSize = 200
+
+// ErrBankNotFound 无法识别卡号所属银行
+var ErrBankNotFound = errors.New("无法识别银行卡号所属银行")
+
+// BankMismatchError 卡号与填写的开户银行不一致
+type BankMismatchError struct {
+	AccountBankCode int    // 填写的开户银行编码
+	Candidates      []Bank // 卡号可能所属的银行
+}
+
+func (e *BankMismatchError) Error() string {
+	names := make([]string, 0, len(e.Candidates))
+	for _, b := range e.Candidates {
+		names = append(names, b.AccountBank)
+	}
+
+	return "银行卡号与开户银行不一致, 卡号可能属于: " + strings.Join(names, ", ")
+}
+
+// BankBranchError 联行号不属于指定银行和城市
+type BankBranchError struct {
+	BankBranchID string
+	Candidates   []BankBranch // 名称匹配的支行, 可能为空
+}
+
+func (e *BankBranchError) Error() string {
+	return "找不到联行号为 " + e.BankBranchID + " 的支行"
+}
+
+// ResolveBank 识别对私银行卡号所属银行
+// 可能返回多个候选, 没有结果时返回 ErrBankNotFound
+func (c *Client) ResolveBank(ctx context.Context, accountNumber string) (candidates []Bank, err error) {
+	list, err := c.SearchBanksByAccount(ctx, accountNumber)
+	if err != nil {
+		return
+	}
+
+	if len(list.Data) == 0 {
+		err = ErrBankNotFound
+		return
+	}
+
+	candidates = list.Data
+	return
+}
+
+// CheckBankAccount 付款到银行卡前校验卡号与开户银行编码是否一致
+// 不一致时返回 *BankMismatchError, 包含卡号可能所属的银行
+func (c *Client) CheckBankAccount(ctx context.Context, accountNumber string, accountBankCode int) (bank Bank, err error) {
+	candidates, err := c.ResolveBank(ctx, accountNumber)
+	if err != nil {
+		return
+	}
+
+	for _, b := range candidates {
+		if b.AccountBankCode == accountBankCode {
+			return b, nil
+		}
+	}
+
+	err = &BankMismatchError{AccountBankCode: accountBankCode, Candidates: candidates}
+	return
+}
+
+// CheckBankBranch 校验联行号属于指定银行和城市
+// 找不到时返回 *BankBranchError, name 不为空时候选为名称包含 name 的支行
+//
+// @bankAliasCode 银行别名编码
+// @cityCode 城市编码
+// @bankBranchID 联行号
+// @name 支行名称关键字, 用于给出候选
+func (c *Client) CheckBankBranch(ctx context.Context, bankAliasCode string, cityCode int, bankBranchID, name string) (branch BankBranch, err error) {
+	var candidates []BankBranch
+
+	err = c.eachBankBranch(ctx, bankAliasCode, cityCode, func(b BankBranch) bool {
+		if b.BankBranchID == bankBranchID {
+			branch = b
+			return false
+		}
+		if name != "" && strings.Contains(b.BankBranchName, name) {
+			candidates = append(candidates, b)
+		}
+		return true
+	})
+	if err != nil || branch.BankBranchID != "" {
+		return
+	}
+
+	err = &BankBranchError{BankBranchID: bankBranchID, Candidates: candidates}
+	return
+}
+
+// SearchBankBranches 查询名称包含关键字的支行
+func (c *Client) SearchBankBranches(ctx context.Context, bankAliasCode string, cityCode int, name string) (res []BankBranch, err error) {
+	err = c.eachBankBranch(ctx, bankAliasCode, cityCode, func(b BankBranch) bool {
+		if strings.Contains(b.BankBranchName, name) {
+			res = append(res, b)
+		}
+		return true
+	})
+
+	return
+}
+
+// 分页遍历支行, fn 返回 false 时停止
+func (c *Client) eachBankBranch(ctx context.Context, bankAliasCode string, cityCode int, fn func(BankBranch) bool) error {
+	for offset := 0; ; offset += bankBranchPageSize {
+		list, err := c.BankBranches(ctx, bankAliasCode, cityCode, offset, bankBranchPageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, b := range list.Data {
+			if !fn(b) {
+				return nil
+			}
+		}
+
+		if len(list.Data) == 0 || offset+len(list.Data) >= list.TotalCount {
+			return nil
+		}
+	}
+}
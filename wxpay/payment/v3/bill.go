@@ -0,0 +1,95 @@
+package v3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/wanghuobo/weapp/payment/bill"
+)
+
+const (
+	tradeBillAPI    = "/v3/bill/tradebill"
+	fundFlowBillAPI = "/v3/bill/fundflowbill"
+)
+
+// BillURL 申请账单结果
+type BillURL struct {
+	HashType    string `json:"hash_type"`    // 摘要算法, SHA1
+	HashValue   string `json:"hash_value"`   // 原始账单的摘要值
+	DownloadURL string `json:"download_url"` // 账单下载地址, 30秒内有效
+}
+
+// TradeBillURL 申请交易账单
+//
+// @date 账单日期, 只使用年月日
+// @billType 账单类型: ALL | SUCCESS | REFUND
+func (c *Client) TradeBillURL(ctx context.Context, date time.Time, billType string) (res BillURL, err error) {
+	q := url.Values{}
+	q.Set("bill_date", date.Format("2006-01-02"))
+	q.Set("tar_type", "GZIP")
+	if billType != "" {
+		q.Set("bill_type", billType)
+	}
+
+	err = c.request(ctx, http.MethodGet, tradeBillAPI+"?"+q.Encode(), "", nil, &res)
+	return
+}
+
+// FundFlowBillURL 申请资金账单
+//
+// @accountType 资金账户类型, 见 Account 常量, 为空时为基本账户
+func (c *Client) FundFlowBillURL(ctx context.Context, date time.Time, accountType string) (res BillURL, err error) {
+	q := url.Values{}
+	q.Set("bill_date", date.Format("2006-01-02"))
+	q.Set("tar_type", "GZIP")
+	if accountType != "" {
+		q.Set("account_type", accountType)
+	}
+
+	err = c.request(ctx, http.MethodGet, fundFlowBillAPI+"?"+q.Encode(), "", nil, &res)
+	return
+}
+
+// DownloadBill 下载并解析账单
+// 下载的文件先校验摘要, 再解压并校验数据行数, 不一致时返回 *bill.CorruptError
+// 下载应答没有签名, 不做签名校验
+func (c *Client) DownloadBill(ctx context.Context, u BillURL) (*bill.Bill, error) {
+	if !strings.HasPrefix(u.DownloadURL, baseURL+"/") {
+		return nil, errors.New("账单下载地址不是微信支付域名: " + u.DownloadURL)
+	}
+
+	_, data, err := c.do(ctx, http.MethodGet, strings.TrimPrefix(u.DownloadURL, baseURL), "", "", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = bill.VerifyHash(data, u.HashType, u.HashValue); err != nil {
+		return nil, err
+	}
+
+	return bill.Parse(data)
+}
+
+// DownloadTradeBill 申请并下载交易账单
+func (c *Client) DownloadTradeBill(ctx context.Context, date time.Time, billType string) (*bill.Bill, error) {
+	u, err := c.TradeBillURL(ctx, date, billType)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.DownloadBill(ctx, u)
+}
+
+// DownloadFundFlowBill 申请并下载资金账单
+func (c *Client) DownloadFundFlowBill(ctx context.Context, date time.Time, accountType string) (*bill.Bill, error) {
+	u, err := c.FundFlowBillURL(ctx, date, accountType)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.DownloadBill(ctx, u)
+}
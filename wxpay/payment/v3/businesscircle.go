@@ -0,0 +1,149 @@
+package v3
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+const (
+	businessCirclePointsAPI   = "/v3/businesscircle/points/notify"
+	businessCircleAuthAPI     = "/v3/businesscircle/user-authorizations/"
+	businessCircleParkingsAPI = "/v3/businesscircle/parkings"
+)
+
+// 商圈通知类型
+const (
+	mallTransactionSuccess     = "MALL_TRANSACTION.SUCCESS"
+	mallRefundSuccess          = "MALL_REFUND.SUCCESS"
+	mallPointsAuthorizeSuccess = "MALL_AUTH.ACTIVATE_CARD"
+)
+
+// MallTransaction 商圈支付结果通知
+type MallTransaction struct {
+	MchID         string `json:"mchid"`         // 商户号
+	MerchantName  string `json:"merchant_name"` // 商圈商户名称
+	ShopName      string `json:"shop_name"`     // 门店名称
+	ShopNumber    string `json:"shop_number"`   // 门店编号
+	AppID         string `json:"appid"`         // 小程序APPID
+	OpenID        string `json:"openid"`        // 用户标识
+	TimeEnd       string `json:"time_end"`      // 交易完成时间, rfc3339 格式
+	Amount        int    `json:"amount"`        // 金额(分)
+	TransactionID string `json:"transaction_id"`
+	CommitTag     string `json:"commit_tag"` // 手动提交积分标记, 自动提交时为空
+}
+
+// MallRefund 商圈退款结果通知
+type MallRefund struct {
+	MchID         string `json:"mchid"`
+	MerchantName  string `json:"merchant_name"`
+	ShopName      string `json:"shop_name"`
+	ShopNumber    string `json:"shop_number"`
+	AppID         string `json:"appid"`
+	OpenID        string `json:"openid"`
+	RefundTime    string `json:"refund_time"` // 退款完成时间
+	PayAmount     int    `json:"pay_amount"`  // 消费金额(分)
+	RefundAmount  int    `json:"refund_amount"`
+	TransactionID string `json:"transaction_id"`
+	RefundID      string `json:"refund_id"` // 微信退款单号
+}
+
+// MallPointsAuthorization 商圈积分授权通知
+type MallPointsAuthorization struct {
+	OpenID     string `json:"openid"`
+	AppID      string `json:"appid"`
+	MchID      string `json:"mchid"`
+	CreateTime string `json:"create_time"` // 授权时间
+}
+
+// HandleMallTransactionNotify 处理商圈支付结果通知(积分通知)
+// 商户需在收到通知后调用 NotifyPoints 同步积分
+func (c *Client) HandleMallTransactionNotify(res http.ResponseWriter, req *http.Request, fn func(MallTransaction) (bool, string)) error {
+	var tx MallTransaction
+	return c.handleNotify(res, req, &tx, func(ntf Notification) (bool, string) {
+		if ntf.EventType != mallTransactionSuccess {
+			return true, ""
+		}
+		return fn(tx)
+	})
+}
+
+// HandleMallRefundNotify 处理商圈退款结果通知
+func (c *Client) HandleMallRefundNotify(res http.ResponseWriter, req *http.Request, fn func(MallRefund) (bool, string)) error {
+	var ref MallRefund
+	return c.handleNotify(res, req, &ref, func(ntf Notification) (bool, string) {
+		if ntf.EventType != mallRefundSuccess {
+			return true, ""
+		}
+		return fn(ref)
+	})
+}
+
+// HandleMallAuthorizationNotify 处理商圈积分授权通知
+func (c *Client) HandleMallAuthorizationNotify(res http.ResponseWriter, req *http.Request, fn func(MallPointsAuthorization) (bool, string)) error {
+	var auth MallPointsAuthorization
+	return c.handleNotify(res, req, &auth, func(ntf Notification) (bool, string) {
+		if ntf.EventType != mallPointsAuthorizeSuccess {
+			return true, ""
+		}
+		return fn(auth)
+	})
+}
+
+// PointsNotify 商圈积分同步
+type PointsNotify struct {
+	SubMchID        string `json:"sub_mchid"`      // 子商户号
+	TransactionID   string `json:"transaction_id"` // 微信订单号
+	AppID           string `json:"appid"`
+	OpenID          string `json:"openid"`
+	EarnPoints      bool   `json:"earn_points"`                 // 是否获得积分
+	IncreasedPoints string `json:"increased_points"`            // 订单新增积分值
+	UpdateTime      string `json:"points_update_time"`          // 积分更新时间, rfc3339 格式
+	NoPointsRemarks string `json:"no_points_remarks,omitempty"` // 未获得积分的原因
+	TotalPoints     string `json:"total_points,omitempty"`      // 顾客积分总额
+}
+
+// NotifyPoints 商圈积分同步
+func (c *Client) NotifyPoints(ctx context.Context, p PointsNotify) error {
+	return c.request(ctx, http.MethodPost, businessCirclePointsAPI, "", p, nil)
+}
+
+// PointsAuthorization 商圈积分授权查询结果
+type PointsAuthorization struct {
+	OpenID string `json:"openid"`
+	// 授权状态: UNAUTHORIZED 未授权 | AUTHORIZED 已授权 | DEAUTHORIZED 已取消授权
+	AuthorizeState string `json:"authorize_state"`
+	AuthorizeTime  string `json:"authorize_time"`
+	// 取消授权时间, 仅 DEAUTHORIZED 状态返回
+	DeauthorizeTime string `json:"deauthorize_time"`
+}
+
+// QueryPointsAuthorization 商圈积分授权查询
+//
+// @openID 顾客在商圈小程序下的 openid
+// @appID 商圈小程序 appid
+func (c *Client) QueryPointsAuthorization(ctx context.Context, openID, appID string) (res PointsAuthorization, err error) {
+	uri := businessCircleAuthAPI + url.PathEscape(openID) + "?appid=" + url.QueryEscape(appID)
+	err = c.request(ctx, http.MethodGet, uri, "", nil, &res)
+	return
+}
+
+// ParkingState 商圈停车状态同步
+type ParkingState struct {
+	SubMchID     string `json:"sub_mchid"`
+	PlateNumber  string `json:"plate_number"` // 车牌号
+	PlateColor   string `json:"plate_color"`  // 车牌颜色: BLUE/GREEN/YELLOW/BLACK/WHITE/LIMEGREEN
+	AppID        string `json:"appid"`
+	OpenID       string `json:"openid"`
+	EnterTime    string `json:"enter_time"`              // 入场时间, rfc3339 格式
+	LeaveTime    string `json:"leave_time,omitempty"`    // 出场时间, 未出场时为空
+	ChargingTime int    `json:"charging_time,omitempty"` // 计费时长(秒)
+	ParkingName  string `json:"parking_name"`            // 停车场名称
+	// 停车状态: 1 入场 | 2 出场
+	ParkingState int `json:"parking_state"`
+}
+
+// SyncParkingState 商圈停车状态同步
+func (c *Client) SyncParkingState(ctx context.Context, p ParkingState) error {
+	return c.request(ctx, http.MethodPost, businessCircleParkingsAPI, "", p, nil)
+}
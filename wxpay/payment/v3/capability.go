@@ -0,0 +1,41 @@
+package v3
+
+import (
+	"strings"
+
+	"github.com/wanghuobo/weapp/payment/types"
+)
+
+// 需要单独开通的产品对应的接口路径前缀
+var uriCapabilities = []struct {
+	prefix     string
+	capability types.Capability
+}{
+	{"/v3/profitsharing/", types.CapabilityProfitSharing},
+	{"/v3/ecommerce/", types.CapabilityEcommerce},
+	{fundFlowBillAPI, types.CapabilityFundFlow},
+}
+
+// ErrCapabilityDisabled 商户未开通对应产品, 见 types.IsCapabilityDisabled
+var ErrCapabilityDisabled = types.ErrCapabilityDisabled
+
+// Enabled 商户是否开通了指定产品, 未配置 Capabilities 时总是返回 true
+func (c *Client) Enabled(capability types.Capability) bool {
+	return c.Capabilities.Enabled(capability)
+}
+
+// 检查接口对应的产品是否已开通
+// 配置了 Capabilities 时必须包含 types.CapabilityV3
+func (c *Client) requireURI(uri string) error {
+	if err := c.Capabilities.Require(types.CapabilityV3); err != nil {
+		return err
+	}
+
+	for _, v := range uriCapabilities {
+		if strings.HasPrefix(uri, v.prefix) {
+			return c.Capabilities.Require(v.capability)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package v3
+
+import (
+	"context"
+	"time"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+// CertRefresher 定期下载平台证书
+// 微信会在证书过期前启用新证书, 需要定期更新才能校验新证书签名的应答和回调
+type CertRefresher struct {
+	Client   *Client
+	Interval time.Duration // 下载间隔, 默认12小时
+	OnError  func(error)   // 下载失败时调用, 为空则忽略, 下次继续尝试
+
+	loop util.Loop
+}
+
+// Run 立即下载一次平台证书, 之后按间隔下载, 直到 ctx 取消, 实现 util.Runner
+func (r *CertRefresher) Run(ctx context.Context) error {
+	r.loop.Interval = r.Interval
+	if r.loop.Interval <= 0 {
+		r.loop.Interval = 12 * time.Hour
+	}
+
+	return r.loop.Run(ctx, r.Client.DownloadCertificates, r.OnError)
+}
+
+// Report 运行情况, 每次下载计为一次执行
+func (r *CertRefresher) Report() util.RunReport {
+	return r.loop.Report()
+}
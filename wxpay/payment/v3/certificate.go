@@ -0,0 +1,306 @@
+package v3
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+const certificatesAPI = "/v3/certificates"
+
+// 平台证书默认宽限期
+// 证书过期或从平台证书列表中移除后, 在宽限期内仍可用于校验回调签名, 避免证书轮换时丢失回调
+const defaultCertGracePeriod = 24 * time.Hour
+
+// CertStats 平台证书校验统计
+type CertStats struct {
+	Verified map[string]int64 // 各证书序列号校验成功次数
+	Failed   map[string]int64 // 各证书序列号签名校验失败次数
+	Unknown  int64            // 找不到证书的次数
+	Expired  int64            // 证书已超出宽限期的次数
+	Unpinned int64            // 证书序列号未被信任的次数
+}
+
+// 平台证书集合
+type certificates struct {
+	mu      sync.RWMutex
+	certs   map[string]*x509.Certificate
+	retired map[string]time.Time // 已从平台证书列表中移除的证书及移除时间
+	pinned  map[string]bool      // 信任的证书序列号, 为空时信任全部
+
+	stats CertStats
+}
+
+func (cs *certificates) get(serial string) *x509.Certificate {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	return cs.certs[serial]
+}
+
+func (cs *certificates) add(serial string, cert *x509.Certificate) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.certs == nil {
+		cs.certs = make(map[string]*x509.Certificate)
+	}
+	cs.certs[serial] = cert
+	delete(cs.retired, serial)
+}
+
+// 使用下载的证书列表替换现有证书
+// 不在列表中的证书标记为已移除, 超出宽限期后删除
+func (cs *certificates) replace(downloaded map[string]*x509.Certificate, grace time.Duration) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.certs == nil {
+		cs.certs = make(map[string]*x509.Certificate)
+	}
+	if cs.retired == nil {
+		cs.retired = make(map[string]time.Time)
+	}
+
+	now := time.Now()
+	for serial, cert := range downloaded {
+		cs.certs[serial] = cert
+		delete(cs.retired, serial)
+	}
+
+	for serial, cert := range cs.certs {
+		if _, ok := downloaded[serial]; ok {
+			continue
+		}
+
+		at, ok := cs.retired[serial]
+		if !ok {
+			cs.retired[serial] = now
+			at = now
+		}
+
+		if now.Sub(at) > grace || now.Sub(cert.NotAfter) > grace {
+			delete(cs.certs, serial)
+			delete(cs.retired, serial)
+		}
+	}
+}
+
+// 查找用于校验签名的证书
+// 证书必须已被信任, 且未超出过期或移除后的宽限期
+func (cs *certificates) lookup(serial string, grace time.Duration) (*x509.Certificate, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cert := cs.certs[serial]
+	if cert == nil {
+		cs.stats.Unknown++
+		return nil, fmt.Errorf("找不到序列号为 %s 的平台证书", serial)
+	}
+
+	if len(cs.pinned) > 0 && !cs.pinned[serial] {
+		cs.stats.Unpinned++
+		return nil, fmt.Errorf("序列号为 %s 的平台证书未被信任", serial)
+	}
+
+	now := time.Now()
+	at, retired := cs.retired[serial]
+	if now.Sub(cert.NotAfter) > grace || (retired && now.Sub(at) > grace) {
+		cs.stats.Expired++
+		return nil, fmt.Errorf("序列号为 %s 的平台证书已失效", serial)
+	}
+
+	return cert, nil
+}
+
+// 记录签名校验结果
+func (cs *certificates) count(serial string, ok bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	m := &cs.stats.Failed
+	if ok {
+		m = &cs.stats.Verified
+	}
+	if *m == nil {
+		*m = make(map[string]int64)
+	}
+	(*m)[serial]++
+}
+
+func (cs *certificates) pin(serials []string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cs.pinned = make(map[string]bool, len(serials))
+	for _, s := range serials {
+		cs.pinned[s] = true
+	}
+}
+
+func (cs *certificates) snapshot() CertStats {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	st := cs.stats
+	st.Verified = make(map[string]int64, len(cs.stats.Verified))
+	for k, v := range cs.stats.Verified {
+		st.Verified[k] = v
+	}
+	st.Failed = make(map[string]int64, len(cs.stats.Failed))
+	for k, v := range cs.stats.Failed {
+		st.Failed[k] = v
+	}
+
+	return st
+}
+
+// 当前使用的证书: 已生效、未移除且过期时间最晚的证书
+func (cs *certificates) current() (string, *x509.Certificate) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	now := time.Now()
+	var serial string
+	var cert *x509.Certificate
+	for s, c := range cs.certs {
+		if now.Before(c.NotBefore) || now.After(c.NotAfter) {
+			continue
+		}
+		if _, retired := cs.retired[s]; retired {
+			continue
+		}
+		if cert == nil || c.NotAfter.After(cert.NotAfter) {
+			serial, cert = s, c
+		}
+	}
+
+	return serial, cert
+}
+
+// AddCertificate 添加微信支付平台证书
+//
+// @serial 平台证书序列号
+// @cert 平台证书
+func (c *Client) AddCertificate(serial string, cert *x509.Certificate) {
+	c.certs.add(serial, cert)
+}
+
+// PinCertificates 只信任指定序列号的平台证书校验签名
+// 不传参数时取消限制, 信任全部已加载的证书
+func (c *Client) PinCertificates(serials ...string) {
+	c.certs.pin(serials)
+}
+
+// CertStats 返回平台证书校验统计, 用于观察证书轮换期间各证书的使用情况
+func (c *Client) CertStats() CertStats {
+	return c.certs.snapshot()
+}
+
+func (c *Client) certGracePeriod() time.Duration {
+	if c.CertGracePeriod > 0 {
+		return c.CertGracePeriod
+	}
+
+	return defaultCertGracePeriod
+}
+
+// Certificate 获取当前用于加密敏感信息的平台证书
+func (c *Client) Certificate() (serial string, cert *x509.Certificate, err error) {
+	serial, cert = c.certs.current()
+	if cert == nil {
+		err = errors.New("没有可用的平台证书, 请先调用 DownloadCertificates 或 AddCertificate")
+	}
+
+	return
+}
+
+// 加密证书
+type encryptCertificate struct {
+	Algorithm      string `json:"algorithm"`
+	Nonce          string `json:"nonce"`
+	AssociatedData string `json:"associated_data"`
+	Ciphertext     string `json:"ciphertext"`
+}
+
+type certificateList struct {
+	Data []struct {
+		SerialNo           string             `json:"serial_no"`
+		EffectiveTime      string             `json:"effective_time"`
+		ExpireTime         string             `json:"expire_time"`
+		EncryptCertificate encryptCertificate `json:"encrypt_certificate"`
+	} `json:"data"`
+}
+
+// DownloadCertificates 下载并加载微信支付平台证书
+// 下载的证书使用 APIv3 密钥解密, 解密后再用其校验本次应答的签名。
+// 已不在列表中的旧证书在 CertGracePeriod 内仍可用于校验回调签名, 之后被删除
+func (c *Client) DownloadCertificates(ctx context.Context) error {
+	header, data, err := c.do(ctx, http.MethodGet, certificatesAPI, "", "", nil, nil)
+	if err != nil {
+		return err
+	}
+
+	var list certificateList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+
+	downloaded := make(map[string]*x509.Certificate)
+	for _, item := range list.Data {
+		ec := item.EncryptCertificate
+		pem, err := util.AesGCMDecrypt(c.APIKey, ec.Nonce, ec.Ciphertext, ec.AssociatedData)
+		if err != nil {
+			return err
+		}
+
+		cert, err := util.ParseCertificate(pem)
+		if err != nil {
+			return err
+		}
+		downloaded[item.SerialNo] = cert
+	}
+
+	cert := downloaded[header.Get(headerSerial)]
+	if cert == nil {
+		cert = c.certs.get(header.Get(headerSerial))
+	}
+	if cert == nil {
+		return errors.New("找不到应答签名使用的平台证书")
+	}
+
+	if err := c.verifyWith(header, data, cert); err != nil {
+		return err
+	}
+
+	c.certs.replace(downloaded, c.certGracePeriod())
+
+	return nil
+}
+
+// 使用当前平台证书加密敏感字段, 返回所用证书序列号
+func (c *Client) encrypt(field *string) (string, error) {
+	if *field == "" {
+		return "", nil
+	}
+
+	serial, cert, err := c.Certificate()
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := util.EncryptOAEP(*field, cert)
+	if err != nil {
+		return "", err
+	}
+	*field = ciphertext
+
+	return serial, nil
+}
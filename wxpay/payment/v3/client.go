@@ -0,0 +1,320 @@
+// Package v3 微信支付 APIv3
+package v3
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/wanghuobo/weapp/payment/types"
+	"github.com/wanghuobo/weapp/util"
+)
+
+const (
+	baseURL = "https://api.mch.weixin.qq.com"
+
+	authorizationSchema = "WECHATPAY2-SHA256-RSA2048"
+)
+
+// 应答及回调中的签名相关头
+const (
+	headerTimestamp = "Wechatpay-Timestamp"
+	headerNonce     = "Wechatpay-Nonce"
+	headerSignature = "Wechatpay-Signature"
+	headerSerial    = "Wechatpay-Serial"
+)
+
+// Client APIv3 客户端
+type Client struct {
+	MchID      string          // 商户号
+	SerialNo   string          // 商户 API 证书序列号
+	PrivateKey *rsa.PrivateKey // 商户 API 私钥
+	APIKey     string          // APIv3 密钥, 用于解密回调通知和平台证书
+
+	// HTTPClient 默认要求 TLS1.2 及以上,
+	// 需要限制加密套件或启用 HTTP/2 时使用 util.NewTransport 替换 Transport
+	HTTPClient *http.Client
+
+	UserAgent string      // 请求 User-Agent, APIv3 要求不能为空, 默认 wxpay-go
+	Header    http.Header // 额外请求头, 如内部出口代理需要的认证头, 不会覆盖签名相关的头
+
+	// CertGracePeriod 平台证书过期或被移除后仍可校验回调签名的宽限期, 默认24小时
+	// 证书轮换期间微信可能使用新旧任一证书签名
+	CertGracePeriod time.Duration
+
+	// Marshal 请求体 JSON 编码函数, 默认 json.Marshal
+	// 签名使用编码结果原样发送的字节, 替换编码方式(如 MarshalNoEscape)不会造成签名与请求体不一致
+	Marshal func(v interface{}) ([]byte, error)
+
+	// Unmarshal 应答及解密后的通知数据的 JSON 解码函数, 默认 json.Unmarshal
+	// 可以替换为更快的 JSON 实现, 或在解码时解密个别字段; 签名校验使用原始字节, 不受影响
+	Unmarshal func(data []byte, v interface{}) error
+
+	// OnSign 调试用, 每次请求签名时回调待签名串, 用于排查 SIGN_ERROR
+	OnSign func(message string)
+
+	// TimestampWindow 大于0时拒绝 Wechatpay-Timestamp 与本机时间偏差超过该值的应答及回调,
+	// 防止截获的应答或回调被重放, 微信要求不超过5分钟; 默认不拒绝, 偏差统计见 Health
+	TimestampWindow time.Duration
+
+	// Capabilities 商户已开通的产品, 为空表示不限制
+	// 调用未开通产品的接口时直接返回 *types.CapabilityError, 不发送请求
+	Capabilities types.Capabilities
+
+	certs   certificates // 微信支付平台证书
+	metrics metrics      // 运行统计, 见 Health
+	routes  notifyRoutes // 子商户通知路由, 见 RouteNotify
+}
+
+// NewClient 新建 APIv3 客户端
+//
+// @mchID 商户号
+// @serialNo 商户 API 证书序列号
+// @key 商户 API 私钥
+// @apiKey APIv3 密钥
+func NewClient(mchID, serialNo string, key *rsa.PrivateKey, apiKey string) *Client {
+	return &Client{
+		MchID:      mchID,
+		SerialNo:   serialNo,
+		PrivateKey: key,
+		APIKey:     apiKey,
+		HTTPClient: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: util.NewTransport(util.TransportOptions{}),
+		},
+	}
+}
+
+// Error APIv3 错误应答
+type Error struct {
+	StatusCode int             `json:"-"`       // HTTP 状态码
+	Code       string          `json:"code"`    // 详细错误码
+	Message    string          `json:"message"` // 错误描述
+	Detail     json.RawMessage `json:"detail,omitempty"`
+
+	Canonical  string        `json:"-"` // 请求的待签名串, 用于排查签名错误
+	RequestID  string        `json:"-"` // 微信请求ID
+	RetryAfter time.Duration `json:"-"` // 限频(429)时微信要求的等待时间
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("请求失败: status=%d code=%s message=%s", e.StatusCode, e.Code, e.Message)
+	if e.RequestID != "" {
+		msg += " request_id=" + e.RequestID
+	}
+
+	return msg
+}
+
+// CanonicalMessage 请求待签名串
+// 格式为 HTTP方法\nURL\n时间戳\n随机串\n请求主体\n, 请求主体必须是实际发送的字节
+//
+// @uri 请求的绝对路径, 包含查询参数
+func CanonicalMessage(method, uri, timestamp, nonce string, body []byte) string {
+	return method + "\n" + uri + "\n" + timestamp + "\n" + nonce + "\n" + string(body) + "\n"
+}
+
+// MarshalNoEscape 不转义 HTML 字符的 JSON 编码, 可用于 Client.Marshal
+func MarshalNoEscape(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// 编码请求主体
+func (c *Client) marshal(v interface{}) ([]byte, error) {
+	if c.Marshal != nil {
+		return c.Marshal(v)
+	}
+
+	return json.Marshal(v)
+}
+
+// 解码应答或通知数据
+func (c *Client) unmarshal(data []byte, v interface{}) error {
+	if c.Unmarshal != nil {
+		return c.Unmarshal(data, v)
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// 生成请求签名头, 同时返回待签名串
+func (c *Client) authorization(method, uri string, body []byte) (auth, message string, err error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	return c.authorizationWith(method, uri, timestamp, util.RandomString(32), body)
+}
+
+// 使用指定的时间戳和随机串生成请求签名头
+func (c *Client) authorizationWith(method, uri, timestamp, nonce string, body []byte) (auth, message string, err error) {
+	message = CanonicalMessage(method, uri, timestamp, nonce, body)
+	if c.OnSign != nil {
+		c.OnSign(message)
+	}
+
+	signature, err := util.SignSHA256WithRSA(message, c.PrivateKey)
+	if err != nil {
+		return
+	}
+
+	auth = fmt.Sprintf(`%s mchid="%s",nonce_str="%s",signature="%s",timestamp="%s",serial_no="%s"`,
+		authorizationSchema, c.MchID, nonce, signature, timestamp, c.SerialNo)
+	return
+}
+
+// 发起 JSON 请求并校验应答签名
+//
+// @serial 请求包含加密字段时使用的平台证书序列号, 为空则不设置
+// @in 请求数据, 为 nil 时不发送请求体
+// @out 应答数据, 为 nil 时忽略应答体
+func (c *Client) request(ctx context.Context, method, uri, serial string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = c.marshal(in); err != nil {
+			return err
+		}
+	}
+
+	header, data, err := c.do(ctx, method, uri, serial, "application/json", body, body)
+	if err != nil {
+		return err
+	}
+
+	return c.decode(header, data, out)
+}
+
+// 校验应答签名并解析应答数据
+func (c *Client) decode(header http.Header, data []byte, out interface{}) error {
+	if err := c.verify(header, data); err != nil {
+		return err
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+
+	return c.unmarshal(data, out)
+}
+
+// 发送请求
+//
+// @signBody 参与签名的请求主体, 与 body 为同一份字节, 上传文件时为 meta 信息
+func (c *Client) do(ctx context.Context, method, uri, serial, contentType string, body, signBody []byte) (http.Header, []byte, error) {
+	if err := c.requireURI(uri); err != nil {
+		return nil, nil, err
+	}
+
+	auth, message, err := c.authorization(method, uri, signBody)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, baseURL+uri, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if serial != "" {
+		req.Header.Set(headerSerial, serial)
+	}
+	util.SetHeaders(req, c.UserAgent, c.Header)
+
+	start := time.Now()
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		c.metrics.observeRequest(method, uri, time.Since(start), true)
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	elapsed := time.Since(start)
+	failed := err != nil || res.StatusCode < 200 || res.StatusCode > 299
+	c.metrics.observeRequest(method, uri, elapsed, failed)
+	requestID, retryAfter := c.captureResponse(ctx, method, uri, res, elapsed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		e := &Error{StatusCode: res.StatusCode, Canonical: message, RequestID: requestID, RetryAfter: retryAfter}
+		json.Unmarshal(data, e)
+		return nil, nil, e
+	}
+
+	return res.Header, data, nil
+}
+
+// 校验应答或回调签名
+func (c *Client) verify(header http.Header, body []byte) error {
+	serial := header.Get(headerSerial)
+	cert, err := c.certs.lookup(serial, c.certGracePeriod())
+	if err != nil {
+		c.metrics.observeDrift(header)
+		return err
+	}
+
+	err = c.verifyWith(header, body, cert)
+	c.certs.count(serial, err == nil)
+
+	return err
+}
+
+// 使用指定平台证书校验签名, 同时记录时间戳偏差并按 TimestampWindow 检查时间戳
+// 下载平台证书时证书尚未加载, 直接调用该方法
+func (c *Client) verifyWith(header http.Header, body []byte, cert *x509.Certificate) error {
+	c.metrics.observeDrift(header)
+
+	if c.TimestampWindow > 0 {
+		ts, err := strconv.ParseInt(header.Get(headerTimestamp), 10, 64)
+		if err != nil {
+			return errors.New("应答时间戳格式错误: " + header.Get(headerTimestamp))
+		}
+
+		d := time.Since(time.Unix(ts, 0))
+		if d < 0 {
+			d = -d
+		}
+		if d > c.TimestampWindow {
+			return fmt.Errorf("应答时间戳超出允许范围: 与本机时间相差 %s", d.Truncate(time.Second))
+		}
+	}
+
+	return verifySignature(header, body, cert)
+}
+
+// 使用指定平台证书校验签名
+func verifySignature(header http.Header, body []byte, cert *x509.Certificate) error {
+	message := header.Get(headerTimestamp) + "\n" + header.Get(headerNonce) + "\n" + string(body) + "\n"
+	if err := util.VerifySHA256WithRSA(message, header.Get(headerSignature), cert); err != nil {
+		return errors.New("应答签名校验失败: " + err.Error())
+	}
+
+	return nil
+}
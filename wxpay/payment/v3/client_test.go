@@ -0,0 +1,226 @@
+package v3
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 官方文档中的签名示例: 获取平台证书列表
+const (
+	exampleMchID     = "1900009191"
+	exampleSerialNo  = "1DDE55AD98ED71D6EDD4A4A16996DE7B47773A8C"
+	exampleTimestamp = "1554208460"
+	exampleNonce     = "593BEC0C930BF1AFEB40B4A08C8FB242"
+)
+
+func TestCanonicalMessage(t *testing.T) {
+	got := CanonicalMessage(http.MethodGet, "/v3/certificates", exampleTimestamp, exampleNonce, nil)
+	want := "GET\n/v3/certificates\n1554208460\n593BEC0C930BF1AFEB40B4A08C8FB242\n\n"
+	if got != want {
+		t.Fatalf("CanonicalMessage\n got: %q\nwant: %q", got, want)
+	}
+
+	got = CanonicalMessage(http.MethodPost, "/v3/pay/transactions/jsapi?a=1", exampleTimestamp, exampleNonce, []byte(`{"a":1}`))
+	want = "POST\n/v3/pay/transactions/jsapi?a=1\n1554208460\n593BEC0C930BF1AFEB40B4A08C8FB242\n{\"a\":1}\n"
+	if got != want {
+		t.Fatalf("CanonicalMessage\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(exampleMchID, exampleSerialNo, key, "")
+
+	var signed string
+	c.OnSign = func(message string) { signed = message }
+
+	auth, message, err := c.authorizationWith(http.MethodGet, "/v3/certificates", exampleTimestamp, exampleNonce, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "GET\n/v3/certificates\n1554208460\n593BEC0C930BF1AFEB40B4A08C8FB242\n\n"
+	if message != want || signed != want {
+		t.Fatalf("message = %q, OnSign = %q, want %q", message, signed, want)
+	}
+
+	prefix := `WECHATPAY2-SHA256-RSA2048 mchid="1900009191",nonce_str="593BEC0C930BF1AFEB40B4A08C8FB242",signature="`
+	suffix := `",timestamp="1554208460",serial_no="1DDE55AD98ED71D6EDD4A4A16996DE7B47773A8C"`
+	if !strings.HasPrefix(auth, prefix) || !strings.HasSuffix(auth, suffix) {
+		t.Fatalf("Authorization = %s", auth)
+	}
+
+	signature := strings.TrimSuffix(strings.TrimPrefix(auth, prefix), suffix)
+	if err = verifyRSA(&key.PublicKey, want, signature); err != nil {
+		t.Fatalf("签名校验失败: %v", err)
+	}
+}
+
+// 拦截请求, 不发送到微信支付
+type captureTransport struct {
+	req  *http.Request
+	body []byte
+}
+
+var errCaptured = errors.New("captured")
+
+func (t *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.req = req
+	if req.Body != nil {
+		t.body, _ = ioutil.ReadAll(req.Body)
+	}
+
+	return nil, errCaptured
+}
+
+func TestSignedBodyWithMarshal(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := map[string]string{"description": "<商品>&amp"}
+	tests := []struct {
+		name    string
+		marshal func(v interface{}) ([]byte, error)
+		want    string
+	}{
+		{"default", nil, `{"description":"\u003c商品\u003e\u0026amp"}`},
+		{"no escape", MarshalNoEscape, `{"description":"<商品>&amp"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &captureTransport{}
+			c := NewClient(exampleMchID, exampleSerialNo, key, "")
+			c.HTTPClient = &http.Client{Transport: tr}
+			c.Marshal = tt.marshal
+
+			var signed string
+			c.OnSign = func(message string) { signed = message }
+
+			err := c.request(context.Background(), http.MethodPost, "/v3/pay/transactions/jsapi", "", in, nil)
+			if !errors.Is(err, errCaptured) {
+				t.Fatalf("err = %v", err)
+			}
+
+			if string(tr.body) != tt.want {
+				t.Fatalf("body\n got: %s\nwant: %s", tr.body, tt.want)
+			}
+
+			// 待签名串中的请求主体与实际发送的字节一致
+			if !strings.HasSuffix(signed, "\n"+string(tr.body)+"\n") {
+				t.Fatalf("待签名串与请求主体不一致\nsigned: %q\n  body: %q", signed, tr.body)
+			}
+
+			auth := tr.req.Header.Get("Authorization")
+			i := strings.Index(auth, `signature="`)
+			if i < 0 {
+				t.Fatalf("Authorization = %s", auth)
+			}
+			signature := auth[i+len(`signature="`):]
+			signature = signature[:strings.Index(signature, `"`)]
+			if err = verifyRSA(&key.PublicKey, signed, signature); err != nil {
+				t.Fatalf("签名校验失败: %v", err)
+			}
+		})
+	}
+}
+
+func verifyRSA(pub *rsa.PublicKey, message, signature string) error {
+	sign, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return err
+	}
+
+	hashed := sha256.Sum256([]byte(message))
+	return rsa.VerifyPKCS1v15(pub, crypto.SHA256, hashed[:], sign)
+}
+
+func TestVerifyTimestamp(t *testing.T) {
+	header := func(d time.Duration) http.Header {
+		h := make(http.Header)
+		h.Set(headerTimestamp, strconv.FormatInt(time.Now().Add(d).Unix(), 10))
+		return h
+	}
+
+	tests := []struct {
+		name   string
+		window time.Duration
+		offset time.Duration
+		reject bool
+	}{
+		{"default does not reject", 0, -time.Hour, false},
+		{"within window", 5 * time.Minute, -time.Minute, false},
+		{"past", 5 * time.Minute, -6 * time.Minute, true},
+		{"future", 5 * time.Minute, 6 * time.Minute, true},
+	}
+
+	cert := testCertificate(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{TimestampWindow: tt.window}
+
+			// 时间戳检查在签名校验之前, 通过时返回签名错误
+			err := c.verifyWith(header(tt.offset), []byte("{}"), cert)
+			if err == nil {
+				t.Fatal("verifyWith 应返回错误")
+			}
+			if got := strings.HasPrefix(err.Error(), "应答时间戳超出允许范围"); got != tt.reject {
+				t.Fatalf("err = %v, reject = %v", err, tt.reject)
+			}
+
+			// 下载证书和普通应答一样记录偏差
+			want := tt.offset
+			if want < 0 {
+				want = -want
+			}
+			if drift := c.Health().LastDrift; drift < want-2*time.Second || drift > want+2*time.Second {
+				t.Fatalf("LastDrift = %s, want about %s", drift, want)
+			}
+		})
+	}
+}
+
+// 自签名的平台证书
+func testCertificate(t *testing.T) *x509.Certificate {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
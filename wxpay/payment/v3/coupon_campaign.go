@@ -0,0 +1,218 @@
+package v3
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// 发券额度不足, 不会请求微信
+var (
+	ErrCampaignBudget   = errors.New("代金券活动预算已用完")
+	ErrCampaignDailyCap = errors.New("代金券活动已达到当日发放上限")
+)
+
+// 默认按北京时间日切
+var campaignLocation = time.FixedZone("CST", 8*3600)
+
+// CouponCampaign 代金券活动
+// 管理批次创建、激活和发放, 发放前检查总预算和每日发放上限
+// 已发放数量以批次查询接口为准, 两次查询之间按本地发放成功次数累加
+type CouponCampaign struct {
+	Client  *Client
+	AppID   string // 用户 openid 所属的 APPID
+	StockID string // 批次号, 为空时需要先调用 Create
+
+	Budget   int            // 总预算(分), 为0时使用批次总预算
+	DailyCap int            // 每日发放上限(张), 为0时不限制
+	Location *time.Location // 日切时区, 默认北京时间
+
+	mu          sync.Mutex
+	stock       Stock
+	refreshed   bool
+	distributed int
+	day         string
+	sentToday   int
+}
+
+// CampaignUsage 活动预算使用情况
+type CampaignUsage struct {
+	Status      string // 批次状态
+	Distributed int    // 已发放数量
+	Consumed    int    // 已使用预算(分)
+	Remaining   int    // 剩余预算(分)
+	SentToday   int    // 本进程当日发放数量
+}
+
+// NewCouponCampaign 新建代金券活动
+func NewCouponCampaign(c *Client, appID, stockID string) *CouponCampaign {
+	return &CouponCampaign{Client: c, AppID: appID, StockID: stockID}
+}
+
+// Create 创建批次
+// 总预算必须等于发放总上限乘以面额, 且不能超过活动预算
+func (cc *CouponCampaign) Create(ctx context.Context, s CouponStock) (stockID string, err error) {
+	rule := s.CouponUseRule.FixedNormalCoupon
+	if rule == nil || rule.CouponAmount <= 0 {
+		err = errors.New("仅支持固定面额满减券")
+		return
+	}
+
+	if s.StockUseRule.MaxAmount != s.StockUseRule.MaxCoupons*rule.CouponAmount {
+		err = errors.New("批次总预算必须等于发放总上限乘以面额")
+		return
+	}
+
+	if cc.Budget > 0 && s.StockUseRule.MaxAmount > cc.Budget {
+		err = errors.New("批次总预算超过活动预算")
+		return
+	}
+
+	if s.StockType == "" {
+		s.StockType = "NORMAL"
+	}
+
+	if stockID, err = cc.Client.CreateCouponStock(ctx, s); err != nil {
+		return
+	}
+
+	cc.mu.Lock()
+	cc.StockID = stockID
+	cc.refreshed = false
+	cc.mu.Unlock()
+
+	return
+}
+
+// Activate 激活批次
+func (cc *CouponCampaign) Activate(ctx context.Context) error {
+	if err := cc.Client.StartCouponStock(ctx, cc.StockID); err != nil {
+		return err
+	}
+
+	_, err := cc.Refresh(ctx)
+	return err
+}
+
+// Refresh 查询批次并返回预算使用情况
+func (cc *CouponCampaign) Refresh(ctx context.Context) (CampaignUsage, error) {
+	stock, err := cc.Client.QueryCouponStock(ctx, cc.StockID)
+	if err != nil {
+		return CampaignUsage{}, err
+	}
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	cc.stock = stock
+	cc.refreshed = true
+	cc.distributed = stock.DistributedCoupons
+
+	return cc.usage(), nil
+}
+
+// Usage 返回最近一次查询后的预算使用情况, 不请求微信
+func (cc *CouponCampaign) Usage() CampaignUsage {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	return cc.usage()
+}
+
+// Send 向用户发券
+// 超出预算或当日上限时返回 ErrCampaignBudget 或 ErrCampaignDailyCap
+//
+// @outRequestNo 商户单据号, 重试时使用相同单据号避免重复发券
+func (cc *CouponCampaign) Send(ctx context.Context, openID, outRequestNo string) (couponID string, err error) {
+	cc.mu.Lock()
+	refreshed := cc.refreshed
+	cc.mu.Unlock()
+
+	if !refreshed {
+		if _, err = cc.Refresh(ctx); err != nil {
+			return
+		}
+	}
+
+	if err = cc.reserve(); err != nil {
+		return
+	}
+
+	couponID, err = cc.Client.SendCoupon(ctx, openID, SendCouponRequest{
+		StockID:      cc.StockID,
+		OutRequestNo: outRequestNo,
+		AppID:        cc.AppID,
+	})
+	if err != nil {
+		cc.cancel()
+	}
+
+	return
+}
+
+// 预占一张券的额度
+func (cc *CouponCampaign) reserve() error {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	cc.rollDay()
+	if cc.DailyCap > 0 && cc.sentToday >= cc.DailyCap {
+		return ErrCampaignDailyCap
+	}
+
+	if (cc.distributed+1)*cc.stock.CouponAmount() > cc.budget() {
+		return ErrCampaignBudget
+	}
+
+	cc.distributed++
+	cc.sentToday++
+	return nil
+}
+
+// 发放失败时归还额度
+func (cc *CouponCampaign) cancel() {
+	cc.mu.Lock()
+	cc.distributed--
+	if cc.sentToday > 0 {
+		cc.sentToday--
+	}
+	cc.mu.Unlock()
+}
+
+func (cc *CouponCampaign) rollDay() {
+	loc := cc.Location
+	if loc == nil {
+		loc = campaignLocation
+	}
+
+	if day := time.Now().In(loc).Format("2006-01-02"); day != cc.day {
+		cc.day = day
+		cc.sentToday = 0
+	}
+}
+
+func (cc *CouponCampaign) budget() int {
+	if cc.Budget > 0 {
+		return cc.Budget
+	}
+
+	if cc.stock.StockUseRule != nil {
+		return cc.stock.StockUseRule.MaxAmount
+	}
+
+	return 0
+}
+
+func (cc *CouponCampaign) usage() CampaignUsage {
+	cc.rollDay()
+
+	consumed := cc.distributed * cc.stock.CouponAmount()
+	return CampaignUsage{
+		Status:      cc.stock.Status,
+		Distributed: cc.distributed,
+		Consumed:    consumed,
+		Remaining:   cc.budget() - consumed,
+		SentToday:   cc.sentToday,
+	}
+}
@@ -0,0 +1,149 @@
+package v3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+const ecommerceRefundsAPI = "/v3/ecommerce/refunds/"
+
+// 电商退款通知类型
+const (
+	RefundSuccess  = "REFUND.SUCCESS"  // 退款成功
+	RefundAbnormal = "REFUND.ABNORMAL" // 退款异常, 需调用 ApplyAbnormalRefund 发起异常退款
+	RefundClosed   = "REFUND.CLOSED"   // 退款关闭
+)
+
+// 异常退款方式
+const (
+	AbnormalRefundUserBankCard     = "USER_BANK_CARD"     // 退款到用户银行卡
+	AbnormalRefundMerchantBankCard = "MERCHANT_BANK_CARD" // 退款至交易商户银行账户
+)
+
+// EcommerceRefund 电商退款单
+type EcommerceRefund struct {
+	SubMchID      string `json:"sub_mchid,omitempty"` // 二级商户号, 通知中返回
+	RefundID      string `json:"refund_id"`           // 微信退款单号
+	OutRefundNo   string `json:"out_refund_no"`       // 商户退款单号
+	TransactionID string `json:"transaction_id"`
+	OutTradeNo    string `json:"out_trade_no"`
+	Channel       string `json:"channel"` // 退款渠道: ORIGINAL/BALANCE/OTHER_BALANCE/OTHER_BANKCARD
+	UserReceived  string `json:"user_received_account"`
+	SuccessTime   string `json:"success_time"`
+	CreateTime    string `json:"create_time"`
+	// 退款状态: SUCCESS 退款成功 | CLOSED 退款关闭 | PROCESSING 退款处理中 | ABNORMAL 退款异常
+	Status string `json:"status"`
+	// 退款出资商户: REFUND_SOURCE_PARTNER_ADVANCE 电商平台垫付 | REFUND_SOURCE_SUB_MERCHANT 二级商户
+	RefundAccount string `json:"refund_account"`
+	Amount        struct {
+		Refund         int    `json:"refund"`          // 退款金额(分)
+		PayerRefund    int    `json:"payer_refund"`    // 用户退款金额
+		DiscountRefund int    `json:"discount_refund"` // 优惠退款金额
+		Currency       string `json:"currency"`
+		Advance        int    `json:"advance"` // 垫付金额
+	} `json:"amount"`
+}
+
+// QueryEcommerceRefund 通过微信退款单号查询退款
+func (c *Client) QueryEcommerceRefund(ctx context.Context, subMchID, refundID string) (res EcommerceRefund, err error) {
+	uri := ecommerceRefundsAPI + "id/" + url.PathEscape(refundID) + "?sub_mchid=" + url.QueryEscape(subMchID)
+	err = c.request(ctx, http.MethodGet, uri, "", nil, &res)
+	return
+}
+
+// AbnormalRefund 异常退款申请
+// 退款异常(如用户银行卡已注销)时, 可以将退款改为退至用户或商户的银行卡
+type AbnormalRefund struct {
+	SubMchID    string `json:"sub_mchid"`
+	OutRefundNo string `json:"out_refund_no"`
+	Type        string `json:"type"`                   // 异常退款方式: AbnormalRefundUserBankCard | AbnormalRefundMerchantBankCard
+	BankType    string `json:"bank_type,omitempty"`    // 开户银行, 退款到用户银行卡时必填
+	BankAccount string `json:"bank_account,omitempty"` // 收款银行卡号, 明文传入, 请求时自动加密
+	RealName    string `json:"real_name,omitempty"`    // 收款用户姓名, 明文传入, 请求时自动加密
+}
+
+// ApplyAbnormalRefund 发起异常退款
+// 收到 RefundAbnormal 通知或查询到 ABNORMAL 状态后调用
+func (c *Client) ApplyAbnormalRefund(ctx context.Context, refundID string, a AbnormalRefund) (res EcommerceRefund, err error) {
+	if a.Type == AbnormalRefundUserBankCard && (a.BankType == "" || a.BankAccount == "" || a.RealName == "") {
+		err = errors.New("退款到用户银行卡时 bank_type, bank_account 和 real_name 不能为空")
+		return
+	}
+
+	var serial string
+	for _, field := range []*string{&a.BankAccount, &a.RealName} {
+		var s string
+		if s, err = c.encrypt(field); err != nil {
+			return
+		}
+		if s != "" {
+			serial = s
+		}
+	}
+
+	err = c.request(ctx, http.MethodPost, ecommerceRefundsAPI+url.PathEscape(refundID)+"/apply-abnormal-refund", serial, a, &res)
+	return
+}
+
+// AdvanceReturn 垫付资金回补结果
+// 电商平台垫付退款后, 从二级商户账户回补垫付资金
+type AdvanceReturn struct {
+	RefundID        string `json:"refund_id"`
+	AdvanceReturnID string `json:"advance_return_id"` // 微信回补单号
+	ReturnAmount    int    `json:"return_amount"`     // 回补金额(分)
+	PayerMchID      string `json:"payer_mchid"`       // 出款方商户号
+	PayerAccount    string `json:"payer_account"`     // 出款方账户: BASIC/OPERATION/FEES
+	PayeeMchID      string `json:"payee_mchid"`       // 入账方商户号
+	PayeeAccount    string `json:"payee_account"`
+	Result          string `json:"result"` // 回补结果: SUCCESS/FAILED/PROCESSING
+	SuccessTime     string `json:"success_time"`
+}
+
+// ReturnAdvance 发起垫付资金回补
+// 仅退款成功且由电商平台垫付(REFUND_SOURCE_PARTNER_ADVANCE)的退款单可以回补
+func (c *Client) ReturnAdvance(ctx context.Context, subMchID, refundID string) (res AdvanceReturn, err error) {
+	req := struct {
+		SubMchID string `json:"sub_mchid"`
+	}{subMchID}
+
+	err = c.request(ctx, http.MethodPost, ecommerceRefundsAPI+url.PathEscape(refundID)+"/return-advance", "", req, &res)
+	return
+}
+
+// QueryReturnAdvance 查询垫付资金回补结果
+func (c *Client) QueryReturnAdvance(ctx context.Context, subMchID, refundID string) (res AdvanceReturn, err error) {
+	uri := ecommerceRefundsAPI + url.PathEscape(refundID) + "/return-advance?sub_mchid=" + url.QueryEscape(subMchID)
+	err = c.request(ctx, http.MethodGet, uri, "", nil, &res)
+	return
+}
+
+// EcommerceRefundNotify 电商退款结果通知
+type EcommerceRefundNotify struct {
+	SPMchID       string `json:"sp_mchid"` // 电商平台商户号
+	SubMchID      string `json:"sub_mchid"`
+	OutTradeNo    string `json:"out_trade_no"`
+	TransactionID string `json:"transaction_id"`
+	OutRefundNo   string `json:"out_refund_no"`
+	RefundID      string `json:"refund_id"`
+	RefundStatus  string `json:"refund_status"` // SUCCESS/CLOSED/ABNORMAL
+	SuccessTime   string `json:"success_time"`
+	UserReceived  string `json:"user_received_account"`
+	RefundAccount string `json:"refund_account"` // 退款出资商户
+	Amount        struct {
+		Total       int `json:"total"`
+		Refund      int `json:"refund"`
+		PayerTotal  int `json:"payer_total"`
+		PayerRefund int `json:"payer_refund"`
+	} `json:"amount"`
+}
+
+// HandleEcommerceRefundNotify 处理电商退款结果通知
+// 事件类型为 RefundSuccess、RefundAbnormal 或 RefundClosed
+func (c *Client) HandleEcommerceRefundNotify(res http.ResponseWriter, req *http.Request, fn func(string, EcommerceRefundNotify) (bool, string)) error {
+	var r EcommerceRefundNotify
+	return c.handleNotify(res, req, &r, func(ntf Notification) (bool, string) {
+		return fn(ntf.EventType, r)
+	})
+}
@@ -0,0 +1,151 @@
+package v3
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+const favorAPI = "/v3/marketing/favor/"
+
+// 代金券批次状态
+const (
+	StockUnactivated = "unactivated" // 未激活
+	StockAudit       = "audit"       // 审核中
+	StockRunning     = "running"     // 运行中
+	StockStopped     = "stoped"      // 已停止
+	StockPaused      = "paused"      // 暂停发放
+)
+
+// StockUseRule 批次发放规则
+type StockUseRule struct {
+	MaxCoupons         int  `json:"max_coupons"`                 // 发放总上限(张)
+	MaxAmount          int  `json:"max_amount"`                  // 总预算(分)
+	MaxAmountByDay     int  `json:"max_amount_by_day,omitempty"` // 单天预算发放上限(分)
+	MaxCouponsPerUser  int  `json:"max_coupons_per_user"`        // 单个用户可领个数
+	NaturalPersonLimit bool `json:"natural_person_limit"`        // 是否开启自然人限制
+	PreventAPIAbuse    bool `json:"prevent_api_abuse"`           // 是否开启防刷拦截
+
+	// 固定面额满减券, 查询批次时返回
+	FixedNormalCoupon *FixedNormalCoupon `json:"fixed_normal_coupon,omitempty"`
+}
+
+// FixedNormalCoupon 固定面额满减券
+type FixedNormalCoupon struct {
+	CouponAmount       int `json:"coupon_amount"`       // 面额(分)
+	TransactionMinimum int `json:"transaction_minimum"` // 使用券金额门槛(分)
+}
+
+// CouponUseRule 核销规则
+type CouponUseRule struct {
+	FixedNormalCoupon  *FixedNormalCoupon `json:"fixed_normal_coupon,omitempty"`
+	GoodsTag           []string           `json:"goods_tag,omitempty"` // 订单优惠标记
+	AvailableMerchants []string           `json:"available_merchants"` // 可核销商户号
+}
+
+// CouponStock 创建代金券批次参数
+type CouponStock struct {
+	StockName          string        `json:"stock_name"`
+	Comment            string        `json:"comment,omitempty"`
+	BelongMerchant     string        `json:"belong_merchant"`      // 批次归属商户号
+	AvailableBeginTime string        `json:"available_begin_time"` // 可用开始时间, RFC3339 格式
+	AvailableEndTime   string        `json:"available_end_time"`   // 可用结束时间, RFC3339 格式
+	StockUseRule       StockUseRule  `json:"stock_use_rule"`
+	CouponUseRule      CouponUseRule `json:"coupon_use_rule"`
+	NoCash             bool          `json:"no_cash"`    // 是否为免充值代金券
+	StockType          string        `json:"stock_type"` // 批次类型, 仅支持 NORMAL
+	OutRequestNo       string        `json:"out_request_no"`
+}
+
+// Stock 代金券批次详情
+type Stock struct {
+	StockID            string        `json:"stock_id"`
+	StockCreatorMchID  string        `json:"stock_creator_mchid"`
+	StockName          string        `json:"stock_name"`
+	Status             string        `json:"status"`
+	CreateTime         string        `json:"create_time"`
+	Description        string        `json:"description"`
+	StockUseRule       *StockUseRule `json:"stock_use_rule,omitempty"`
+	AvailableBeginTime string        `json:"available_begin_time"`
+	AvailableEndTime   string        `json:"available_end_time"`
+	DistributedCoupons int           `json:"distributed_coupons"` // 已发券数量
+	NoCash             bool          `json:"no_cash"`
+	StartTime          string        `json:"start_time,omitempty"` // 激活时间
+	StopTime           string        `json:"stop_time,omitempty"`
+	StockType          string        `json:"stock_type"`
+}
+
+// CouponAmount 单张券面额(分), 非固定面额批次返回0
+func (s Stock) CouponAmount() int {
+	if s.StockUseRule == nil || s.StockUseRule.FixedNormalCoupon == nil {
+		return 0
+	}
+
+	return s.StockUseRule.FixedNormalCoupon.CouponAmount
+}
+
+type stockCreator struct {
+	StockCreatorMchID string `json:"stock_creator_mchid"`
+}
+
+// CreateCouponStock 创建代金券批次
+// 批次创建后处于未激活状态, 需要调用 StartCouponStock 激活后才能发券
+func (c *Client) CreateCouponStock(ctx context.Context, s CouponStock) (stockID string, err error) {
+	var res struct {
+		StockID string `json:"stock_id"`
+	}
+
+	err = c.request(ctx, http.MethodPost, favorAPI+"coupon-stocks", "", s, &res)
+	stockID = res.StockID
+	return
+}
+
+// StartCouponStock 激活代金券批次
+func (c *Client) StartCouponStock(ctx context.Context, stockID string) error {
+	return c.changeCouponStock(ctx, stockID, "start")
+}
+
+// PauseCouponStock 暂停代金券批次发放
+func (c *Client) PauseCouponStock(ctx context.Context, stockID string) error {
+	return c.changeCouponStock(ctx, stockID, "pause")
+}
+
+// RestartCouponStock 重启暂停的代金券批次
+func (c *Client) RestartCouponStock(ctx context.Context, stockID string) error {
+	return c.changeCouponStock(ctx, stockID, "restart")
+}
+
+func (c *Client) changeCouponStock(ctx context.Context, stockID, action string) error {
+	uri := favorAPI + "stocks/" + url.PathEscape(stockID) + "/" + action
+	return c.request(ctx, http.MethodPost, uri, "", stockCreator{StockCreatorMchID: c.MchID}, nil)
+}
+
+// QueryCouponStock 查询代金券批次详情
+func (c *Client) QueryCouponStock(ctx context.Context, stockID string) (res Stock, err error) {
+	uri := favorAPI + "stocks/" + url.PathEscape(stockID) + "?stock_creator_mchid=" + url.QueryEscape(c.MchID)
+	err = c.request(ctx, http.MethodGet, uri, "", nil, &res)
+	return
+}
+
+// SendCouponRequest 发放代金券参数
+type SendCouponRequest struct {
+	StockID           string `json:"stock_id"`
+	OutRequestNo      string `json:"out_request_no"` // 商户单据号, 同一单据号重复请求只发一张券
+	AppID             string `json:"appid"`          // 用户 openid 所属的 APPID
+	StockCreatorMchID string `json:"stock_creator_mchid"`
+}
+
+// SendCoupon 向用户发放代金券
+func (c *Client) SendCoupon(ctx context.Context, openID string, s SendCouponRequest) (couponID string, err error) {
+	if s.StockCreatorMchID == "" {
+		s.StockCreatorMchID = c.MchID
+	}
+
+	var res struct {
+		CouponID string `json:"coupon_id"`
+	}
+
+	err = c.request(ctx, http.MethodPost, favorAPI+"users/"+url.PathEscape(openID)+"/coupons", "", s, &res)
+	couponID = res.CouponID
+	return
+}
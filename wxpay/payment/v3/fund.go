@@ -0,0 +1,145 @@
+package v3
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/wanghuobo/weapp/payment/types"
+)
+
+const (
+	ecommerceFundAPI = "/v3/ecommerce/fund/"
+	merchantFundAPI  = "/v3/merchant/fund/"
+)
+
+// 账户类型
+const (
+	AccountBasic     = string(types.AccountTypeBasic)     // 基本账户
+	AccountOperation = string(types.AccountTypeOperation) // 运营账户
+	AccountFees      = string(types.AccountTypeFees)      // 手续费账户
+)
+
+// Balance 账户余额
+type Balance struct {
+	SubMchID        string `json:"sub_mchid,omitempty"`    // 二级商户号, 查询二级商户余额时返回
+	AccountType     string `json:"account_type,omitempty"` // 账户类型
+	AvailableAmount int    `json:"available_amount"`       // 可用余额(分)
+	PendingAmount   int    `json:"pending_amount"`         // 不可用余额(分)
+}
+
+// SubMerchantBalance 查询二级商户实时余额
+//
+// @accountType 账户类型, 为空时查询基本账户
+func (c *Client) SubMerchantBalance(ctx context.Context, subMchID, accountType string) (res Balance, err error) {
+	uri := ecommerceFundAPI + "balance/" + url.PathEscape(subMchID)
+	if accountType != "" {
+		uri += "?account_type=" + url.QueryEscape(accountType)
+	}
+
+	err = c.request(ctx, http.MethodGet, uri, "", nil, &res)
+	return
+}
+
+// SubMerchantDayEndBalance 查询二级商户日终余额
+//
+// @date 日期, 只使用年月日
+func (c *Client) SubMerchantDayEndBalance(ctx context.Context, subMchID string, date time.Time) (res Balance, err error) {
+	uri := ecommerceFundAPI + "enddaybalance/" + url.PathEscape(subMchID) + "?date=" + date.Format("2006-01-02")
+	err = c.request(ctx, http.MethodGet, uri, "", nil, &res)
+	return
+}
+
+// MerchantBalance 查询电商平台或服务商账户实时余额
+func (c *Client) MerchantBalance(ctx context.Context, accountType string) (res Balance, err error) {
+	err = c.request(ctx, http.MethodGet, merchantFundAPI+"balance/"+url.PathEscape(accountType), "", nil, &res)
+	return
+}
+
+// MerchantDayEndBalance 查询电商平台或服务商账户日终余额
+func (c *Client) MerchantDayEndBalance(ctx context.Context, accountType string, date time.Time) (res Balance, err error) {
+	uri := merchantFundAPI + "dayendbalance/" + url.PathEscape(accountType) + "?date=" + date.Format("2006-01-02")
+	err = c.request(ctx, http.MethodGet, uri, "", nil, &res)
+	return
+}
+
+// Withdraw 提现申请
+type Withdraw struct {
+	SubMchID     string `json:"sub_mchid,omitempty"` // 二级商户号, 电商平台提现时为空
+	OutRequestNo string `json:"out_request_no"`      // 商户提现单号
+	Amount       int    `json:"amount"`              // 提现金额(分)
+	Remark       string `json:"remark,omitempty"`    // 提现备注
+	BankMemo     string `json:"bank_memo,omitempty"` // 银行附言
+	AccountType  string `json:"account_type,omitempty"`
+}
+
+// WithdrawResult 提现单
+type WithdrawResult struct {
+	SubMchID     string `json:"sub_mchid,omitempty"`
+	SPMchID      string `json:"sp_mchid,omitempty"`
+	WithdrawID   string `json:"withdraw_id"` // 微信支付提现单号
+	OutRequestNo string `json:"out_request_no"`
+	// 提现状态: CREATE_SUCCESS 受理成功 | SUCCESS 提现成功 | FAIL 提现失败 | REFUND 提现退票 | CLOSE 关单 | INIT 业务单已创建
+	Status       string `json:"status"`
+	Amount       int    `json:"amount"`
+	CreateTime   string `json:"create_time"`
+	UpdateTime   string `json:"update_time"`
+	Reason       string `json:"reason"` // 失败原因
+	Remark       string `json:"remark"`
+	BankMemo     string `json:"bank_memo"`
+	AccountType  string `json:"account_type"`
+	AccountNo    string `json:"account_number"` // 入账银行账号后四位
+	AccountBank  string `json:"account_bank"`
+	BankName     string `json:"bank_name"`
+	SolutionDesc string `json:"solution"` // 失败时的解决方案
+}
+
+// Done 提现是否已处于终态
+func (w WithdrawResult) Done() bool {
+	switch w.Status {
+	case "SUCCESS", "FAIL", "REFUND", "CLOSE":
+		return true
+	}
+
+	return false
+}
+
+// SubMerchantWithdraw 二级商户余额提现
+func (c *Client) SubMerchantWithdraw(ctx context.Context, w Withdraw) (res WithdrawResult, err error) {
+	err = c.request(ctx, http.MethodPost, ecommerceFundAPI+"withdraw", "", w, &res)
+	return
+}
+
+// QuerySubMerchantWithdraw 通过微信支付提现单号查询二级商户提现状态
+func (c *Client) QuerySubMerchantWithdraw(ctx context.Context, subMchID, withdrawID string) (res WithdrawResult, err error) {
+	uri := ecommerceFundAPI + "withdraw/" + url.PathEscape(withdrawID) + "?sub_mchid=" + url.QueryEscape(subMchID)
+	err = c.request(ctx, http.MethodGet, uri, "", nil, &res)
+	return
+}
+
+// QuerySubMerchantWithdrawByOutRequestNo 通过商户提现单号查询二级商户提现状态
+func (c *Client) QuerySubMerchantWithdrawByOutRequestNo(ctx context.Context, subMchID, outRequestNo string) (res WithdrawResult, err error) {
+	uri := ecommerceFundAPI + "withdraw/out-request-no/" + url.PathEscape(outRequestNo) + "?sub_mchid=" + url.QueryEscape(subMchID)
+	err = c.request(ctx, http.MethodGet, uri, "", nil, &res)
+	return
+}
+
+// MerchantWithdraw 电商平台余额提现
+func (c *Client) MerchantWithdraw(ctx context.Context, w Withdraw) (res WithdrawResult, err error) {
+	w.SubMchID = ""
+	err = c.request(ctx, http.MethodPost, merchantFundAPI+"withdraw", "", w, &res)
+	return
+}
+
+// QueryMerchantWithdraw 通过微信支付提现单号查询电商平台提现状态
+func (c *Client) QueryMerchantWithdraw(ctx context.Context, withdrawID string) (res WithdrawResult, err error) {
+	err = c.request(ctx, http.MethodGet, merchantFundAPI+"withdraw/withdraw-id/"+url.PathEscape(withdrawID), "", nil, &res)
+	return
+}
+
+// QueryMerchantWithdrawByOutRequestNo 通过商户提现单号查询电商平台提现状态
+func (c *Client) QueryMerchantWithdrawByOutRequestNo(ctx context.Context, outRequestNo string) (res WithdrawResult, err error) {
+	err = c.request(ctx, http.MethodGet, merchantFundAPI+"withdraw/out-request-no/"+url.PathEscape(outRequestNo), "", nil, &res)
+	return
+}
@@ -0,0 +1,123 @@
+package v3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+const (
+	goldPlanStatusAPI       = "/v3/goldplan/merchants/changegoldplanstatus"
+	goldPlanCustomPageAPI   = "/v3/goldplan/merchants/changecustompagestatus"
+	goldPlanIndustryAPI     = "/v3/goldplan/merchants/set-advertising-industry-filter"
+	goldPlanOpenAdvertAPI   = "/v3/goldplan/merchants/open-advertising-show"
+	goldPlanCloseAdvertAPI  = "/v3/goldplan/merchants/close-advertising-show"
+	goldPlanOperationOpen   = "OPEN"
+	goldPlanOperationClose  = "CLOSE"
+	goldPlanMaxIndustryList = 5
+)
+
+// 广告屏蔽行业
+const (
+	IndustryECommerce         = "E_COMMERCE"             // 电商
+	IndustryLoveMarriage      = "LOVE_MARRIAGE"          // 婚恋
+	IndustryPotteryEducation  = "POTTERY_EDUCATION"      // 陶艺教育
+	IndustryFinance           = "FINANCE"                // 金融
+	IndustryFoodDelivery      = "FOOD_DELIVERY"          // 外卖
+	IndustryInternetService   = "INTERNET_SERVICE"       // 互联网服务
+	IndustryGame              = "GAME"                   // 游戏
+	IndustryKnowledgePayment  = "KNOWLEDGE_PAYMENT"      // 知识付费
+	IndustryRealEstate        = "REAL_ESTATE"            // 房地产
+	IndustryLifeService       = "LIFE_SERVICE"           // 生活服务
+	IndustryMedicalHealthcare = "MEDICAL_AND_HEALTHCARE" // 医疗健康
+)
+
+var errAdvertisingIndustries = errors.New("同业过滤标签最多选择5个")
+
+// GoldPlanStatus 点金计划状态
+type GoldPlanStatus struct {
+	SubMchID         string `json:"sub_mchid"`
+	GoldPlanStatus   string `json:"gold_plan_status,omitempty"`   // 点金计划状态: OPEN/CLOSE
+	CustomPageStatus string `json:"custom_page_status,omitempty"` // 商家小票状态: OPEN/CLOSE
+}
+
+type goldPlanOperation struct {
+	SubMchID      string `json:"sub_mchid"`
+	OperationType string `json:"operation_type"`
+}
+
+// OpenGoldPlan 为特约商户开通点金计划
+func (c *Client) OpenGoldPlan(ctx context.Context, subMchID string) (GoldPlanStatus, error) {
+	return c.changeGoldPlan(ctx, goldPlanStatusAPI, subMchID, goldPlanOperationOpen)
+}
+
+// CloseGoldPlan 为特约商户关闭点金计划
+func (c *Client) CloseGoldPlan(ctx context.Context, subMchID string) (GoldPlanStatus, error) {
+	return c.changeGoldPlan(ctx, goldPlanStatusAPI, subMchID, goldPlanOperationClose)
+}
+
+// OpenCustomPage 开通商家小票
+// 开通后支付成功页将跳转到服务商配置的商家小票页面
+func (c *Client) OpenCustomPage(ctx context.Context, subMchID string) (GoldPlanStatus, error) {
+	return c.changeGoldPlan(ctx, goldPlanCustomPageAPI, subMchID, goldPlanOperationOpen)
+}
+
+// CloseCustomPage 关闭商家小票
+func (c *Client) CloseCustomPage(ctx context.Context, subMchID string) (GoldPlanStatus, error) {
+	return c.changeGoldPlan(ctx, goldPlanCustomPageAPI, subMchID, goldPlanOperationClose)
+}
+
+func (c *Client) changeGoldPlan(ctx context.Context, uri, subMchID, operation string) (res GoldPlanStatus, err error) {
+	req := goldPlanOperation{
+		SubMchID:      subMchID,
+		OperationType: operation,
+	}
+
+	err = c.request(ctx, http.MethodPost, uri, "", req, &res)
+	return
+}
+
+type advertisingFilter struct {
+	SubMchID   string   `json:"sub_mchid"`
+	Industries []string `json:"advertising_industry_filters,omitempty"`
+}
+
+// SetAdvertisingIndustryFilter 设置同业过滤标签
+// 支付成功页不展示所选行业的广告, 最多选择5个行业
+func (c *Client) SetAdvertisingIndustryFilter(ctx context.Context, subMchID string, industries ...string) error {
+	if len(industries) == 0 {
+		return errors.New("同业过滤标签不能为空")
+	}
+	if len(industries) > goldPlanMaxIndustryList {
+		return errAdvertisingIndustries
+	}
+
+	req := advertisingFilter{
+		SubMchID:   subMchID,
+		Industries: industries,
+	}
+
+	return c.request(ctx, http.MethodPost, goldPlanIndustryAPI, "", req, nil)
+}
+
+// OpenAdvertisingShow 开通广告展示
+//
+// @industries 同业过滤标签, 可为空
+func (c *Client) OpenAdvertisingShow(ctx context.Context, subMchID string, industries ...string) error {
+	if len(industries) > goldPlanMaxIndustryList {
+		return errAdvertisingIndustries
+	}
+
+	req := advertisingFilter{
+		SubMchID:   subMchID,
+		Industries: industries,
+	}
+
+	return c.request(ctx, http.MethodPost, goldPlanOpenAdvertAPI, "", req, nil)
+}
+
+// CloseAdvertisingShow 关闭广告展示
+func (c *Client) CloseAdvertisingShow(ctx context.Context, subMchID string) error {
+	req := advertisingFilter{SubMchID: subMchID}
+	return c.request(ctx, http.MethodPost, goldPlanCloseAdvertAPI, "", req, nil)
+}
@@ -0,0 +1,104 @@
+package v3
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// GoodsTagError 订单优惠标记不可用
+type GoodsTagError struct {
+	Tag    string
+	Reason string
+}
+
+func (e *GoodsTagError) Error() string {
+	return "优惠标记 " + e.Tag + " 不可用: " + e.Reason
+}
+
+// GoodsTagValidator 按代金券批次状态校验订单优惠标记
+// 实现 payment.GoodsTagValidator, 可设置到 payment.Config.GoodsTags。
+// 优惠标记对应的批次中至少有一个处于运行中且在可用时间内时校验通过
+type GoodsTagValidator struct {
+	Client *Client
+	Stocks map[string][]string // 优惠标记对应的代金券批次号, 即创建批次时 CouponUseRule.GoodsTag 的取值
+	TTL    time.Duration       // 批次状态缓存时间, 默认5分钟
+
+	mu    sync.Mutex
+	cache map[string]goodsTagResult
+}
+
+type goodsTagResult struct {
+	err error
+	at  time.Time
+}
+
+// ValidateGoodsTag 校验优惠标记
+// 未配置的优惠标记返回 *GoodsTagError, 避免标记拼写错误; 查询批次失败时返回查询错误, 不缓存
+func (v *GoodsTagValidator) ValidateGoodsTag(ctx context.Context, tag string) error {
+	ttl := v.TTL
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+
+	v.mu.Lock()
+	r, ok := v.cache[tag]
+	v.mu.Unlock()
+	if ok && time.Since(r.at) < ttl {
+		return r.err
+	}
+
+	err := v.validate(ctx, tag)
+	if _, ok := err.(*GoodsTagError); err != nil && !ok {
+		return err
+	}
+
+	v.mu.Lock()
+	if v.cache == nil {
+		v.cache = make(map[string]goodsTagResult)
+	}
+	v.cache[tag] = goodsTagResult{err: err, at: time.Now()}
+	v.mu.Unlock()
+
+	return err
+}
+
+func (v *GoodsTagValidator) validate(ctx context.Context, tag string) error {
+	ids := v.Stocks[tag]
+	if len(ids) == 0 {
+		return &GoodsTagError{Tag: tag, Reason: "没有对应的代金券批次"}
+	}
+
+	now := time.Now()
+	reason := ""
+	for _, id := range ids {
+		s, err := v.Client.QueryCouponStock(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case s.Status != StockRunning:
+			reason = "批次 " + id + " 状态为 " + s.Status
+		case !inPeriod(now, s.AvailableBeginTime, s.AvailableEndTime):
+			reason = "批次 " + id + " 不在可用时间内"
+		default:
+			return nil
+		}
+	}
+
+	return &GoodsTagError{Tag: tag, Reason: reason}
+}
+
+// 是否在 RFC3339 格式的时间范围内, 无法解析的时间不做限制
+func inPeriod(now time.Time, begin, end string) bool {
+	if t, err := time.Parse(time.RFC3339, begin); err == nil && now.Before(t) {
+		return false
+	}
+
+	if t, err := time.Parse(time.RFC3339, end); err == nil && now.After(t) {
+		return false
+	}
+
+	return true
+}
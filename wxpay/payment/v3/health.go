@@ -0,0 +1,249 @@
+package v3
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 健康检查阈值
+const (
+	healthCertExpiry = 7 * 24 * time.Hour // 平台证书剩余有效期低于该值时报告
+	healthDrift      = 5 * time.Minute    // 时间戳偏差超过该值时报告, 微信要求回调时间戳与本机相差不超过5分钟
+	healthErrorRate  = 0.5                // 最近请求错误率超过该值时报告
+	recentSize       = 100                // 计算错误率的最近请求数
+)
+
+// 直方图桶上限, 最后一个桶之外的记录计入溢出桶
+var histogramBuckets = []time.Duration{
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	5 * time.Minute,
+}
+
+// Histogram 时长分布
+type Histogram struct {
+	Buckets []time.Duration // 各桶上限
+	Counts  []int64         // 各桶记录数, 比 Buckets 多一个溢出桶
+	Count   int64
+	Sum     time.Duration
+	Max     time.Duration
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	if h.Counts == nil {
+		h.Buckets = histogramBuckets
+		h.Counts = make([]int64, len(histogramBuckets)+1)
+	}
+
+	i := 0
+	for i < len(h.Buckets) && d > h.Buckets[i] {
+		i++
+	}
+	h.Counts[i]++
+	h.Count++
+	h.Sum += d
+	if d > h.Max {
+		h.Max = d
+	}
+}
+
+func (h Histogram) clone() Histogram {
+	h.Counts = append([]int64(nil), h.Counts...)
+	return h
+}
+
+// Mean 平均时长
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+
+	return h.Sum / time.Duration(h.Count)
+}
+
+// EndpointStats 单个接口的请求统计
+type EndpointStats struct {
+	Requests  int64
+	Errors    int64
+	Throttled int64 // 被限频(429)的次数
+	Latency   Histogram
+}
+
+// Health 客户端健康状态快照
+type Health struct {
+	Healthy  bool
+	Problems []string // 不健康的原因
+
+	// Drift 应答及回调中 Wechatpay-Timestamp 与本机时间的偏差(绝对值)
+	Drift     Histogram
+	LastDrift time.Duration
+
+	Endpoints map[string]EndpointStats // 按 "方法 路径" 统计, 路径中的ID替换为 :id
+	ErrorRate float64                  // 最近请求的错误率
+
+	CertSerial string    // 当前平台证书序列号
+	CertExpiry time.Time // 当前平台证书过期时间
+
+	ThrottledUntil time.Time // 微信限频要求的最早重试时间
+}
+
+// 客户端运行统计
+type metrics struct {
+	mu        sync.Mutex
+	drift     Histogram
+	lastDrift time.Duration
+	endpoints map[string]*EndpointStats
+	recent    [recentSize]bool // 最近请求是否失败
+	recentN   int
+
+	throttledUntil time.Time
+}
+
+// 记录应答或回调的时间戳偏差
+func (m *metrics) observeDrift(header http.Header) {
+	ts, err := strconv.ParseInt(header.Get(headerTimestamp), 10, 64)
+	if err != nil {
+		return
+	}
+
+	d := time.Since(time.Unix(ts, 0))
+	if d < 0 {
+		d = -d
+	}
+
+	m.mu.Lock()
+	m.drift.observe(d)
+	m.lastDrift = d
+	m.mu.Unlock()
+}
+
+// 记录请求耗时和结果
+func (m *metrics) observeRequest(method, uri string, d time.Duration, failed bool) {
+	key := method + " " + endpoint(uri)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st := m.endpoint(key)
+	st.Requests++
+	if failed {
+		st.Errors++
+	}
+	st.Latency.observe(d)
+
+	m.recent[m.recentN%recentSize] = failed
+	m.recentN++
+}
+
+// 记录限频
+func (m *metrics) observeThrottle(method, uri string, retryAfter time.Duration) {
+	key := method + " " + endpoint(uri)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.endpoint(key).Throttled++
+	if until := time.Now().Add(retryAfter); until.After(m.throttledUntil) {
+		m.throttledUntil = until
+	}
+}
+
+// 读取接口统计, 不存在时创建, 调用方需持有锁
+func (m *metrics) endpoint(key string) *EndpointStats {
+	if m.endpoints == nil {
+		m.endpoints = make(map[string]*EndpointStats)
+	}
+
+	st := m.endpoints[key]
+	if st == nil {
+		st = new(EndpointStats)
+		m.endpoints[key] = st
+	}
+
+	return st
+}
+
+// 接口路径, 去掉查询参数并把包含数字的路径段替换为 :id, 避免按订单号等分别统计
+func endpoint(uri string) string {
+	if i := strings.IndexByte(uri, '?'); i >= 0 {
+		uri = uri[:i]
+	}
+
+	segs := strings.Split(uri, "/")
+	for i, seg := range segs {
+		if i > 1 && strings.ContainsAny(seg, "0123456789") {
+			segs[i] = ":id"
+		}
+	}
+
+	return strings.Join(segs, "/")
+}
+
+// Health 返回健康状态快照, 可用于就绪探针
+// 综合时间戳偏差、平台证书有效期及最近请求错误率
+func (c *Client) Health() Health {
+	m := &c.metrics
+	m.mu.Lock()
+	h := Health{
+		Drift:     m.drift.clone(),
+		LastDrift: m.lastDrift,
+		Endpoints: make(map[string]EndpointStats, len(m.endpoints)),
+	}
+	for k, st := range m.endpoints {
+		s := *st
+		s.Latency = st.Latency.clone()
+		h.Endpoints[k] = s
+	}
+
+	n := m.recentN
+	if n > recentSize {
+		n = recentSize
+	}
+	var failed int
+	for i := 0; i < n; i++ {
+		if m.recent[i] {
+			failed++
+		}
+	}
+	if n > 0 {
+		h.ErrorRate = float64(failed) / float64(n)
+	}
+	if time.Now().Before(m.throttledUntil) {
+		h.ThrottledUntil = m.throttledUntil
+	}
+	m.mu.Unlock()
+
+	serial, cert := c.certs.current()
+	if cert == nil {
+		h.Problems = append(h.Problems, "没有可用的平台证书")
+	} else {
+		h.CertSerial = serial
+		h.CertExpiry = cert.NotAfter
+		if time.Until(cert.NotAfter) < healthCertExpiry {
+			h.Problems = append(h.Problems, fmt.Sprintf("平台证书 %s 将于 %s 过期", serial, cert.NotAfter.Format(time.RFC3339)))
+		}
+	}
+
+	if h.LastDrift > healthDrift {
+		h.Problems = append(h.Problems, "时间戳偏差过大: "+h.LastDrift.String())
+	}
+
+	if h.ErrorRate > healthErrorRate {
+		h.Problems = append(h.Problems, fmt.Sprintf("最近请求错误率过高: %.0f%%", h.ErrorRate*100))
+	}
+
+	if !h.ThrottledUntil.IsZero() {
+		h.Problems = append(h.Problems, "请求被限频, 直到 "+h.ThrottledUntil.Format(time.RFC3339))
+	}
+
+	h.Healthy = len(h.Problems) == 0
+	return h
+}
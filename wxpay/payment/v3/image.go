@@ -0,0 +1,172 @@
+package v3
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/jpeg" // 注册 jpeg 解码
+	_ "image/png"  // 注册 png 解码
+	"path/filepath"
+	"strings"
+)
+
+const marketingImageUploadAPI = "/v3/marketing/favor/media/image-upload"
+
+// ImageRule 图片上传限制, 为0的项不检查
+type ImageRule struct {
+	MaxSize   int      // 文件大小上限(字节)
+	Formats   []string // 允许的格式: jpg/png/bmp, 为空时允许全部
+	Width     int      // 宽度必须等于
+	Height    int      // 高度必须等于
+	MaxWidth  int      // 宽度上限
+	MaxHeight int      // 高度上限
+}
+
+// 微信文档中的图片限制
+var (
+	// MediaImageRule 通用图片上传: jpg/png/bmp, 不超过2M
+	MediaImageRule = ImageRule{MaxSize: 2 << 20, Formats: []string{"jpg", "png", "bmp"}}
+	// MarketingImageRule 营销图片上传: jpg/png/bmp, 不超过2M
+	MarketingImageRule = ImageRule{MaxSize: 2 << 20, Formats: []string{"jpg", "png", "bmp"}}
+	// MerchantLogoRule 代金券商户 logo: 120*120 像素
+	MerchantLogoRule = ImageRule{MaxSize: 2 << 20, Formats: []string{"jpg", "png", "bmp"}, Width: 120, Height: 120}
+)
+
+// ImageError 图片不符合上传要求
+type ImageError struct {
+	Filename string
+	Reason   string
+}
+
+func (e *ImageError) Error() string {
+	return "图片 " + e.Filename + " 不符合要求: " + e.Reason
+}
+
+// ValidateImage 按规则检查图片格式、大小和尺寸
+// 格式按文件内容识别, 并且必须与扩展名一致
+func ValidateImage(filename string, data []byte, rule ImageRule) error {
+	fail := func(format string, a ...interface{}) error {
+		return &ImageError{Filename: filename, Reason: fmt.Sprintf(format, a...)}
+	}
+
+	if len(data) == 0 {
+		return fail("文件为空")
+	}
+
+	if rule.MaxSize > 0 && len(data) > rule.MaxSize {
+		return fail("文件大小 %d 字节超过上限 %d 字节", len(data), rule.MaxSize)
+	}
+
+	format := imageFormat(data)
+	if format == "" {
+		return fail("无法识别的图片格式, 仅支持 jpg/png/bmp")
+	}
+
+	if len(rule.Formats) > 0 && !containsString(rule.Formats, format) {
+		return fail("格式 %s 不在允许范围 %s 内", format, strings.Join(rule.Formats, "/"))
+	}
+
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	if ext == "jpeg" {
+		ext = "jpg"
+	}
+	if ext != format {
+		return fail("扩展名 .%s 与实际格式 %s 不一致", ext, format)
+	}
+
+	if rule.Width == 0 && rule.Height == 0 && rule.MaxWidth == 0 && rule.MaxHeight == 0 {
+		return nil
+	}
+
+	width, height, err := imageSize(format, data)
+	if err != nil {
+		return fail("读取图片尺寸失败: %v", err)
+	}
+
+	switch {
+	case rule.Width > 0 && width != rule.Width, rule.Height > 0 && height != rule.Height:
+		return fail("尺寸 %d*%d 不等于要求的 %d*%d", width, height, rule.Width, rule.Height)
+	case rule.MaxWidth > 0 && width > rule.MaxWidth:
+		return fail("宽度 %d 超过上限 %d", width, rule.MaxWidth)
+	case rule.MaxHeight > 0 && height > rule.MaxHeight:
+		return fail("高度 %d 超过上限 %d", height, rule.MaxHeight)
+	}
+
+	return nil
+}
+
+// MarketingImage 营销图片上传结果
+type MarketingImage struct {
+	MediaURL string `json:"media_url"` // 图片链接, 用于创建批次等营销接口
+}
+
+// UploadMarketingImage 上传营销图片
+// 上传前按 rule 在本地检查, 不传时使用 MarketingImageRule
+func (c *Client) UploadMarketingImage(ctx context.Context, filename string, data []byte, rule ...ImageRule) (res MarketingImage, err error) {
+	r := MarketingImageRule
+	if len(rule) > 0 {
+		r = rule[0]
+	}
+
+	if err = ValidateImage(filename, data, r); err != nil {
+		return
+	}
+
+	meta := mediaMeta{
+		Filename: filename,
+		SHA256:   sha256Hex(data),
+	}
+
+	err = c.upload(ctx, marketingImageUploadAPI, filename, data, meta, &res)
+	return
+}
+
+// 按文件头识别图片格式
+func imageFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return "jpg"
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return "png"
+	case bytes.HasPrefix(data, []byte("BM")):
+		return "bmp"
+	default:
+		return ""
+	}
+}
+
+// 读取图片宽高, bmp 直接读取文件头
+func imageSize(format string, data []byte) (width, height int, err error) {
+	if format == "bmp" {
+		if len(data) < 26 {
+			err = fmt.Errorf("bmp 文件头不完整")
+			return
+		}
+
+		width = int(int32(binary.LittleEndian.Uint32(data[18:22])))
+		height = int(int32(binary.LittleEndian.Uint32(data[22:26])))
+		if height < 0 {
+			height = -height
+		}
+		return
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+
+	return cfg.Width, cfg.Height, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
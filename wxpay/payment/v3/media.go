@@ -0,0 +1,108 @@
+package v3
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+)
+
+const mediaUploadAPI = "/v3/merchant/media/upload"
+
+// 图片上传的 meta 信息
+type mediaMeta struct {
+	Filename string `json:"filename"`
+	SHA256   string `json:"sha256"`
+}
+
+// MediaResult 图片上传结果
+type MediaResult struct {
+	MediaID string `json:"media_id"` // 媒体文件标识
+}
+
+// UploadImage 上传图片
+// 返回的 media_id 可用于进件、电子小票等接口
+// 上传前按 MediaImageRule 在本地检查格式和大小
+//
+// @filename 文件名, 需包含 jpg/png/bmp 扩展名
+// @data 图片内容
+func (c *Client) UploadImage(ctx context.Context, filename string, data []byte) (res MediaResult, err error) {
+	if err = ValidateImage(filename, data, MediaImageRule); err != nil {
+		return
+	}
+
+	meta := mediaMeta{
+		Filename: filename,
+		SHA256:   sha256Hex(data),
+	}
+
+	err = c.upload(ctx, mediaUploadAPI, filename, data, meta, &res)
+	return
+}
+
+// 以 multipart/form-data 上传文件
+// 签名主体为 meta 信息的 JSON 串
+func (c *Client) upload(ctx context.Context, uri, filename string, data []byte, meta, out interface{}) error {
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="meta"`)
+	h.Set("Content-Type", "application/json")
+	part, err := writer.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	if _, err = part.Write(metaJSON); err != nil {
+		return err
+	}
+
+	h = make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="file"; filename="`+filename+`"`)
+	h.Set("Content-Type", imageContentType(filename))
+	part, err = writer.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	if _, err = part.Write(data); err != nil {
+		return err
+	}
+
+	if err = writer.Close(); err != nil {
+		return err
+	}
+
+	header, resData, err := c.do(ctx, http.MethodPost, uri, "", writer.FormDataContentType(), body.Bytes(), metaJSON)
+	if err != nil {
+		return err
+	}
+
+	return c.decode(header, resData, out)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func imageContentType(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".png":
+		return "image/png"
+	case ".bmp":
+		return "image/bmp"
+	default:
+		return "image/jpg"
+	}
+}
@@ -0,0 +1,233 @@
+package v3
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+// Notification 回调通知
+type Notification struct {
+	ID           string   `json:"id"`            // 通知ID
+	CreateTime   string   `json:"create_time"`   // 通知创建时间
+	EventType    string   `json:"event_type"`    // 通知类型
+	ResourceType string   `json:"resource_type"` // 通知数据类型: encrypt-resource
+	Summary      string   `json:"summary"`       // 回调摘要
+	Resource     resource `json:"resource"`      // 通知数据
+
+	// 通知原文的 SHA-256 摘要(十六进制), 按解析前的原始字节计算, 可作为防篡改凭证保存
+	BodySHA256 string `json:"-"`
+
+	// 解密后的通知数据中, 解析使用的结构体未收录的参数(如微信新增的字段), 由 DecryptExtra 填写
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// 加密的通知数据
+type resource struct {
+	Algorithm      string `json:"algorithm"` // AEAD_AES_256_GCM
+	Ciphertext     string `json:"ciphertext"`
+	AssociatedData string `json:"associated_data"`
+	OriginalType   string `json:"original_type"`
+	Nonce          string `json:"nonce"`
+}
+
+// ParseNotify 校验回调签名并解析通知
+func (c *Client) ParseNotify(req *http.Request) (ntf Notification, err error) {
+	h := sha256.New()
+	body, err := ioutil.ReadAll(io.TeeReader(req.Body, h))
+	if err != nil {
+		return
+	}
+
+	if err = c.verify(req.Header, body); err != nil {
+		err = &NotifyError{Status: http.StatusUnauthorized, Err: err}
+		return
+	}
+
+	if err = json.Unmarshal(body, &ntf); err != nil {
+		err = &NotifyError{Status: http.StatusBadRequest, Err: err}
+		return
+	}
+
+	ntf.BodySHA256 = hex.EncodeToString(h.Sum(nil))
+	return
+}
+
+// Decrypt 使用 APIv3 密钥解密通知数据
+func (c *Client) Decrypt(ntf Notification, out interface{}) error {
+	r := ntf.Resource
+	data, err := util.AesGCMDecrypt(c.APIKey, r.Nonce, r.Ciphertext, r.AssociatedData)
+	if err != nil {
+		return err
+	}
+
+	return c.unmarshal(data, out)
+}
+
+// DecryptExtra 解密通知数据, 同时返回 out 的 json 标签未收录的参数
+// 只比较第一层参数, 嵌套对象中新增的字段不会返回
+func (c *Client) DecryptExtra(ntf Notification, out interface{}) (extra map[string]json.RawMessage, err error) {
+	r := ntf.Resource
+	data, err := util.AesGCMDecrypt(c.APIKey, r.Nonce, r.Ciphertext, r.AssociatedData)
+	if err != nil {
+		return
+	}
+
+	if err = c.unmarshal(data, out); err != nil {
+		return
+	}
+
+	var all map[string]json.RawMessage
+	if json.Unmarshal(data, &all) != nil {
+		return
+	}
+
+	known := make(map[string]bool)
+	jsonNames(reflect.TypeOf(out), known)
+	for name, value := range all {
+		if known[name] {
+			continue
+		}
+
+		if extra == nil {
+			extra = make(map[string]json.RawMessage)
+		}
+		extra[name] = value
+	}
+
+	return
+}
+
+// 读取结构体 json 标签中的参数名, 匿名嵌入的结构体同样读取
+func jsonNames(t reflect.Type, names map[string]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("json")
+		if !ok && sf.Anonymous {
+			jsonNames(sf.Type, names)
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if name == "" {
+			name = sf.Name
+		}
+		if name != "-" {
+			names[name] = true
+		}
+	}
+}
+
+// 回调应答
+type replay struct {
+	Code    string `json:"code"`    // SUCCESS/FAIL
+	Message string `json:"message"` // 返回信息
+}
+
+// NotifyError 回调处理失败, 应答时使用 Status 作为 HTTP 状态码
+// 签名校验失败为 401, 通知格式错误为 400, 其他错误应答 500
+type NotifyError struct {
+	Status int
+	Err    error
+}
+
+func (e *NotifyError) Error() string {
+	return e.Err.Error()
+}
+
+// WriteNotifySuccess 应答回调处理成功
+// APIv3 以 HTTP 状态码表示处理结果, 2XX 即为成功, 这里应答 200 及 SUCCESS
+func WriteNotifySuccess(res http.ResponseWriter) error {
+	return writeJSONReply(res, http.StatusOK, replay{Code: "SUCCESS", Message: "成功"})
+}
+
+// WriteNotifyFail 应答回调处理失败, 微信会按策略重新发送通知
+// 应答体为 {"code":"FAIL","message":"..."}, status 不是 4XX/5XX 时使用 500
+func WriteNotifyFail(res http.ResponseWriter, status int, msg string) error {
+	if status < http.StatusBadRequest || status > 599 {
+		status = http.StatusInternalServerError
+	}
+
+	return writeJSONReply(res, status, replay{Code: "FAIL", Message: msg})
+}
+
+// WriteNotifyError 按错误应答回调处理失败, *NotifyError 使用其中的状态码, 其他错误为 500
+func WriteNotifyError(res http.ResponseWriter, err error) error {
+	status := http.StatusInternalServerError
+	if e, ok := err.(*NotifyError); ok {
+		status = e.Status
+	}
+
+	return WriteNotifyFail(res, status, err.Error())
+}
+
+// 根据处理结果应答回调
+// 处理失败时返回 5XX 状态码, 微信会按策略重新发送通知
+func writeReplay(res http.ResponseWriter, ok bool, msg string) error {
+	if !ok {
+		return WriteNotifyFail(res, http.StatusInternalServerError, msg)
+	}
+
+	return writeJSONReply(res, http.StatusOK, replay{Code: "SUCCESS", Message: msg})
+}
+
+func writeJSONReply(res http.ResponseWriter, status int, rep replay) error {
+	b, err := json.Marshal(rep)
+	if err != nil {
+		return err
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	_, err = res.Write(b)
+
+	return err
+}
+
+// 解析并解密通知后交给处理函数
+func (c *Client) handleNotify(res http.ResponseWriter, req *http.Request, out interface{}, fn func(Notification) (bool, string)) error {
+	// 解析或解密失败时同样应答错误状态码, 否则默认的 200 空应答会被微信视为成功
+	ntf, err := c.ParseNotify(req)
+	if err != nil {
+		WriteNotifyError(res, err)
+		return err
+	}
+
+	if ntf.Extra, err = c.DecryptExtra(ntf, out); err != nil {
+		err = &NotifyError{Status: http.StatusBadRequest, Err: err}
+		WriteNotifyError(res, err)
+		return err
+	}
+
+	ok, msg := fn(ntf)
+	return writeReplay(res, ok, msg)
+}
+
+// ParseNotifyBytes 校验回调签名并解析通知, 用于 API 网关、云函数等转交的回调
+// 请求头名称不区分大小写, body 需要是原始字节, base64 编码的请求体先用 util.GatewayBody 解码
+func (c *Client) ParseNotifyBytes(headers map[string]string, body []byte) (Notification, error) {
+	req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	if err != nil {
+		return Notification{}, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	return c.ParseNotify(req)
+}
@@ -0,0 +1,104 @@
+package v3
+
+import (
+	"crypto/rsa"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+// Signer SHA256withRSA 签名
+// Client 实现了该接口, 私钥托管在 KMS 等外部服务时可以自行实现
+type Signer interface {
+	Sign(message string) (string, error)
+}
+
+// RSASigner 使用商户 API 私钥签名
+type RSASigner struct {
+	Key *rsa.PrivateKey
+}
+
+// Sign 签名并返回 base64 编码结果
+func (s RSASigner) Sign(message string) (string, error) {
+	return util.SignSHA256WithRSA(message, s.Key)
+}
+
+// Sign 使用商户 API 私钥签名
+func (c *Client) Sign(message string) (string, error) {
+	return util.SignSHA256WithRSA(message, c.PrivateKey)
+}
+
+// Params 小程序调起支付参数, 字段名与 wx.requestPayment 一致
+type Params struct {
+	TimeStamp string `json:"timeStamp"`
+	NonceStr  string `json:"nonceStr"`
+	Package   string `json:"package"`
+	SignType  string `json:"signType"` // 固定为 RSA
+	PaySign   string `json:"paySign"`
+}
+
+// GetMiniProgramParams 生成 APIv3 小程序调起支付参数
+// APIv3 下单得到的 prepay_id 需要使用商户 API 私钥签名, 不能使用 payment.GetParams 的 MD5 签名
+//
+// @appID 小程序 APPID, 服务商模式为子商户的 APPID
+// @prepayID JSAPI/小程序下单得到的 prepay_id
+// @signer 签名器, 一般为 Client
+func GetMiniProgramParams(appID, prepayID string, signer Signer) (p Params, err error) {
+	if appID == "" || prepayID == "" {
+		err = errors.New("appid 和 prepay_id 不能为空")
+		return
+	}
+
+	p.TimeStamp = strconv.FormatInt(time.Now().Unix(), 10)
+	p.NonceStr = util.RandomString(32)
+	p.Package = "prepay_id=" + prepayID
+	p.SignType = "RSA"
+
+	message := appID + "\n" + p.TimeStamp + "\n" + p.NonceStr + "\n" + p.Package + "\n"
+	p.PaySign, err = signer.Sign(message)
+
+	return
+}
+
+// PartnerPayer 服务商模式下单使用的 APPID 和用户标识
+// 前端调起支付时签名使用的 APPID 必须与用户标识所属的 APPID 一致, 否则前端会报签名错误
+type PartnerPayer struct {
+	SpAppID   string `json:"-"`                    // 服务商 APPID (sp_appid)
+	SubAppID  string `json:"-"`                    // 子商户 APPID (sub_appid)
+	SpOpenID  string `json:"sp_openid,omitempty"`  // 用户在服务商 APPID 下的 openid
+	SubOpenID string `json:"sub_openid,omitempty"` // 用户在子商户 APPID 下的 openid
+}
+
+// SignAppID 返回前端调起支付时签名使用的 APPID
+// 使用 sub_openid 下单时为 sub_appid, 使用 sp_openid 下单时为 sp_appid
+func (p PartnerPayer) SignAppID() (string, error) {
+	switch {
+	case p.SpOpenID != "" && p.SubOpenID != "":
+		return "", errors.New("sp_openid 和 sub_openid 只能填写一个")
+	case p.SubOpenID != "":
+		if p.SubAppID == "" {
+			return "", errors.New("使用 sub_openid 下单时必须填写 sub_appid")
+		}
+		return p.SubAppID, nil
+	case p.SpOpenID != "":
+		if p.SpAppID == "" {
+			return "", errors.New("使用 sp_openid 下单时必须填写 sp_appid")
+		}
+		return p.SpAppID, nil
+	default:
+		return "", errors.New("sp_openid 和 sub_openid 必须填写一个")
+	}
+}
+
+// GetPartnerMiniProgramParams 服务商模式生成小程序调起支付参数
+// 按下单时的用户标识选择签名 APPID, 签名使用服务商 API 私钥
+func GetPartnerMiniProgramParams(p PartnerPayer, prepayID string, signer Signer) (Params, error) {
+	appID, err := p.SignAppID()
+	if err != nil {
+		return Params{}, err
+	}
+
+	return GetMiniProgramParams(appID, prepayID, signer)
+}
@@ -0,0 +1,183 @@
+package v3
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+const (
+	parkingServicesFindAPI = "/v3/vehicle/parking/services/find"
+	parkingParkingsAPI     = "/v3/vehicle/parking/parkings"
+	parkingTransactionsAPI = "/v3/vehicle/transactions/parking"
+	parkingTradeScene      = "PARKING"
+)
+
+// ParkingService 车牌服务开通信息
+type ParkingService struct {
+	PlateNumber     string `json:"plate_number"`
+	PlateColor      string `json:"plate_color"`
+	ServiceOpenTime string `json:"service_open_time"` // 车牌服务开通时间
+	OpenID          string `json:"openid"`
+	// 车牌服务开通状态: NORMAL 正常服务 | PAUSE 暂停服务 | OUT_SERVICE 未开通
+	ServiceState string `json:"service_state"`
+}
+
+// FindParkingService 查询车牌服务开通信息
+//
+// @subMchID 子商户号
+// @appID 应用ID
+// @plateNumber 车牌号
+// @plateColor 车牌颜色
+// @openID 用户标识
+func (c *Client) FindParkingService(ctx context.Context, subMchID, appID, plateNumber, plateColor, openID string) (res ParkingService, err error) {
+	query := url.Values{}
+	query.Set("sub_mchid", subMchID)
+	query.Set("appid", appID)
+	query.Set("plate_number", plateNumber)
+	query.Set("plate_color", plateColor)
+	query.Set("openid", openID)
+
+	err = c.request(ctx, http.MethodGet, parkingServicesFindAPI+"?"+query.Encode(), "", nil, &res)
+	return
+}
+
+// Parking 停车入场
+type Parking struct {
+	SubMchID     string `json:"sub_mchid"`
+	OutParkingNo string `json:"out_parking_no"` // 商户入场id
+	PlateNumber  string `json:"plate_number"`
+	PlateColor   string `json:"plate_color"`
+	NotifyURL    string `json:"notify_url"` // 入场状态变更通知地址
+	StartTime    string `json:"start_time"` // 入场时间, rfc3339 格式
+	ParkingName  string `json:"parking_name"`
+	FreeDuration int    `json:"free_duration"` // 免费时长(秒)
+}
+
+// ParkingEntry 停车入场结果
+type ParkingEntry struct {
+	ID           string `json:"id"` // 停车入场id
+	OutParkingNo string `json:"out_parking_no"`
+	PlateNumber  string `json:"plate_number"`
+	PlateColor   string `json:"plate_color"`
+	StartTime    string `json:"start_time"`
+	ParkingName  string `json:"parking_name"`
+	FreeDuration int    `json:"free_duration"`
+	// 停车入场状态: NORMAL 正常 | BLOCKED 不可用
+	State string `json:"state"`
+	// 不可用状态描述, 仅 BLOCKED 返回: PAUSE/OVERDUE/REMOVE/OUT_SERVICE
+	BlockReason string `json:"block_reason"`
+}
+
+// CreateParking 创建停车入场
+func (c *Client) CreateParking(ctx context.Context, p Parking) (res ParkingEntry, err error) {
+	err = c.request(ctx, http.MethodPost, parkingParkingsAPI, "", p, &res)
+	return
+}
+
+// Amount 订单金额
+type Amount struct {
+	Total    int    `json:"total"`              // 总金额(分)
+	Currency string `json:"currency,omitempty"` // 货币类型, 默认 CNY
+}
+
+// ParkingInfo 停车场景信息
+type ParkingInfo struct {
+	ParkingID        string `json:"parking_id"` // 停车入场id
+	PlateNumber      string `json:"plate_number"`
+	PlateColor       string `json:"plate_color"`
+	StartTime        string `json:"start_time"`
+	EndTime          string `json:"end_time"`
+	ParkingName      string `json:"parking_name"`
+	ChargingDuration int    `json:"charging_duration"` // 计费时长(秒)
+	DeviceID         string `json:"device_id"`         // 停车场设备id
+}
+
+// ParkingTransaction 停车扣费
+type ParkingTransaction struct {
+	AppID         string      `json:"appid"`
+	SubAppID      string      `json:"sub_appid,omitempty"`
+	SubMchID      string      `json:"sub_mchid"`
+	Description   string      `json:"description"`
+	Attach        string      `json:"attach,omitempty"`
+	OutTradeNo    string      `json:"out_trade_no"`
+	TradeScene    string      `json:"trade_scene"` // 交易场景, 固定为 PARKING
+	GoodsTag      string      `json:"goods_tag,omitempty"`
+	NotifyURL     string      `json:"notify_url"`
+	ProfitSharing string      `json:"profit_sharing,omitempty"` // 是否分账: Y/N
+	Amount        Amount      `json:"amount"`
+	ParkingInfo   ParkingInfo `json:"parking_info"`
+}
+
+// ParkingTransactionResult 停车扣费结果
+type ParkingTransactionResult struct {
+	AppID         string `json:"appid"`
+	SubAppID      string `json:"sub_appid"`
+	SpMchID       string `json:"sp_mchid"`
+	SubMchID      string `json:"sub_mchid"`
+	Description   string `json:"description"`
+	CreateTime    string `json:"create_time"`
+	OutTradeNo    string `json:"out_trade_no"`
+	TransactionID string `json:"transaction_id"`
+	// 交易状态: SUCCESS 支付成功 | ACCEPTED 已接收, 等待扣款 | PAY_FAIL 支付失败 | REFUND 转入退款
+	TradeState     string      `json:"trade_state"`
+	TradeStateDesc string      `json:"trade_state_description"`
+	SuccessTime    string      `json:"success_time"`
+	BankType       string      `json:"bank_type"`
+	UserRepaid     string      `json:"user_repaid"` // 用户是否已还款: Y/N
+	Attach         string      `json:"attach"`
+	TradeScene     string      `json:"trade_scene"`
+	ParkingInfo    ParkingInfo `json:"parking_info"`
+	Amount         struct {
+		Total         int    `json:"total"`
+		Currency      string `json:"currency"`
+		PayerTotal    int    `json:"payer_total"`
+		DiscountTotal int    `json:"discount_total"`
+	} `json:"amount"`
+}
+
+// CreateParkingTransaction 扣费受理
+func (c *Client) CreateParkingTransaction(ctx context.Context, t ParkingTransaction) (res ParkingTransactionResult, err error) {
+	t.TradeScene = parkingTradeScene
+	err = c.request(ctx, http.MethodPost, parkingTransactionsAPI, "", t, &res)
+	return
+}
+
+// QueryParkingTransaction 查询停车扣费订单
+func (c *Client) QueryParkingTransaction(ctx context.Context, subMchID, outTradeNo string) (res ParkingTransactionResult, err error) {
+	uri := parkingTransactionsAPI + "/out-trade-no/" + url.PathEscape(outTradeNo) + "?sub_mchid=" + url.QueryEscape(subMchID)
+	err = c.request(ctx, http.MethodGet, uri, "", nil, &res)
+	return
+}
+
+// HandleParkingTransactionNotify 处理停车扣费结果通知
+func (c *Client) HandleParkingTransactionNotify(res http.ResponseWriter, req *http.Request, fn func(ParkingTransactionResult) (bool, string)) error {
+	var tx ParkingTransactionResult
+	return c.handleNotify(res, req, &tx, func(Notification) (bool, string) {
+		return fn(tx)
+	})
+}
+
+// ParkingStateChange 停车入场状态变更通知
+type ParkingStateChange struct {
+	SpMchID      string `json:"sp_mchid"`
+	SubMchID     string `json:"sub_mchid"`
+	ParkingID    string `json:"parking_id"`
+	OutParkingNo string `json:"out_parking_no"`
+	PlateNumber  string `json:"plate_number"`
+	PlateColor   string `json:"plate_color"`
+	StartTime    string `json:"start_time"`
+	ParkingName  string `json:"parking_name"`
+	FreeDuration int    `json:"free_duration"`
+	State        string `json:"parking_state"` // NORMAL/BLOCKED
+	BlockedState string `json:"blocked_state_description"`
+	StateTime    string `json:"state_update_time"`
+}
+
+// HandleParkingStateNotify 处理停车入场状态变更通知
+func (c *Client) HandleParkingStateNotify(res http.ResponseWriter, req *http.Request, fn func(ParkingStateChange) (bool, string)) error {
+	var change ParkingStateChange
+	return c.handleNotify(res, req, &change, func(Notification) (bool, string) {
+		return fn(change)
+	})
+}
@@ -0,0 +1,232 @@
+package v3
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+const payscoreServiceOrderAPI = "/v3/payscore/serviceorder"
+
+// 支付分通知类型
+const (
+	PayscoreUserOpenService  = "PAYSCORE.USER_OPEN_SERVICE"  // 用户授权开通服务
+	PayscoreUserCloseService = "PAYSCORE.USER_CLOSE_SERVICE" // 用户解除授权
+	PayscoreUserConfirm      = "PAYSCORE.USER_CONFIRM"       // 用户确认订单
+	PayscoreUserPaid         = "PAYSCORE.USER_PAID"          // 用户支付成功
+)
+
+// 订单风险金类型
+const (
+	RiskFundDeposit           = "DEPOSIT"             // 押金
+	RiskFundAdvance           = "ADVANCE"             // 预付款
+	RiskFundCashDeposit       = "CASH_DEPOSIT"        // 保证金
+	RiskFundEstimateOrderCost = "ESTIMATE_ORDER_COST" // 预估订单费用
+)
+
+// PostPayment 后付费项目
+type PostPayment struct {
+	Name        string `json:"name"`
+	Amount      int    `json:"amount"` // 金额(分)
+	Description string `json:"description,omitempty"`
+	Count       int    `json:"count,omitempty"`
+}
+
+// PostDiscount 后付费商户优惠
+type PostDiscount struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Amount      int    `json:"amount"`
+	Count       int    `json:"count,omitempty"`
+}
+
+// TimeRange 服务时间段
+type TimeRange struct {
+	StartTime       string `json:"start_time"` // 格式 yyyyMMddHHmmss 或 OnAccept
+	StartTimeRemark string `json:"start_time_remark,omitempty"`
+	EndTime         string `json:"end_time,omitempty"`
+	EndTimeRemark   string `json:"end_time_remark,omitempty"`
+}
+
+// Location 服务位置
+type Location struct {
+	StartLocation string `json:"start_location,omitempty"`
+	EndLocation   string `json:"end_location,omitempty"`
+}
+
+// RiskFund 订单风险金
+type RiskFund struct {
+	Name        string `json:"name"`   // 风险金名称
+	Amount      int    `json:"amount"` // 风险金额(分)
+	Description string `json:"description,omitempty"`
+}
+
+// ServiceOrder 创建支付分订单
+type ServiceOrder struct {
+	OutOrderNo          string         `json:"out_order_no"`
+	AppID               string         `json:"appid"`
+	ServiceID           string         `json:"service_id"`
+	ServiceIntroduction string         `json:"service_introduction"`
+	PostPayments        []PostPayment  `json:"post_payments,omitempty"`
+	PostDiscounts       []PostDiscount `json:"post_discounts,omitempty"`
+	TimeRange           TimeRange      `json:"time_range"`
+	Location            *Location      `json:"location,omitempty"`
+	RiskFund            RiskFund       `json:"risk_fund"`
+	Attach              string         `json:"attach,omitempty"`
+	NotifyURL           string         `json:"notify_url"`
+	OpenID              string         `json:"openid,omitempty"`
+	NeedUserConfirm     bool           `json:"need_user_confirm"` // 是否需要用户确认, 先享后付模式为 false
+}
+
+// ServiceOrderResult 支付分订单信息
+type ServiceOrderResult struct {
+	AppID               string `json:"appid"`
+	MchID               string `json:"mchid"`
+	OutOrderNo          string `json:"out_order_no"`
+	ServiceID           string `json:"service_id"`
+	ServiceIntroduction string `json:"service_introduction"`
+	// 服务订单状态: CREATED 商户已创建 | DOING 进行中 | DONE 已完成 | REVOKED 已取消 | EXPIRED 已失效
+	State string `json:"state"`
+	// 订单状态说明: USER_CONFIRM 用户确认 | MCH_COMPLETE 商户完结
+	StateDescription string         `json:"state_description"`
+	PostPayments     []PostPayment  `json:"post_payments"`
+	PostDiscounts    []PostDiscount `json:"post_discounts"`
+	RiskFund         RiskFund       `json:"risk_fund"`
+	TotalAmount      int            `json:"total_amount"`
+	NeedCollection   bool           `json:"need_collection"` // 是否需要收款
+	TimeRange        TimeRange      `json:"time_range"`
+	Location         Location       `json:"location"`
+	Attach           string         `json:"attach"`
+	NotifyURL        string         `json:"notify_url"`
+	OrderID          string         `json:"order_id"` // 微信支付服务订单号
+	OpenID           string         `json:"openid"`
+	Package          string         `json:"package"` // 用于跳转到微信侧小程序订单数据
+	Collection       *Collection    `json:"collection,omitempty"`
+}
+
+// Collection 收款信息
+type Collection struct {
+	// 收款状态: USER_PAYING 待支付 | USER_PAID 已支付
+	State        string `json:"state"`
+	TotalAmount  int    `json:"total_amount"`
+	PayingAmount int    `json:"paying_amount"`
+	PaidAmount   int    `json:"paid_amount"`
+	Details      []struct {
+		Seq           int    `json:"seq"`
+		Amount        int    `json:"amount"`
+		PaidType      string `json:"paid_type"` // NEWTON 微信支付分 | MCH 商户渠道
+		PaidTime      string `json:"paid_time"`
+		TransactionID string `json:"transaction_id"`
+	} `json:"details"`
+}
+
+// CreateServiceOrder 创建支付分订单
+// 先享后付模式需要指定风险金, 用户无需确认即可开始服务
+func (c *Client) CreateServiceOrder(ctx context.Context, o ServiceOrder) (res ServiceOrderResult, err error) {
+	err = c.request(ctx, http.MethodPost, payscoreServiceOrderAPI, "", o, &res)
+	return
+}
+
+// QueryServiceOrder 查询支付分订单
+func (c *Client) QueryServiceOrder(ctx context.Context, appID, serviceID, outOrderNo string) (res ServiceOrderResult, err error) {
+	query := url.Values{}
+	query.Set("out_order_no", outOrderNo)
+	query.Set("service_id", serviceID)
+	query.Set("appid", appID)
+
+	err = c.request(ctx, http.MethodGet, payscoreServiceOrderAPI+"?"+query.Encode(), "", nil, &res)
+	return
+}
+
+// CancelServiceOrder 取消支付分订单
+func (c *Client) CancelServiceOrder(ctx context.Context, appID, serviceID, outOrderNo, reason string) error {
+	req := map[string]string{
+		"appid":      appID,
+		"service_id": serviceID,
+		"reason":     reason,
+	}
+
+	return c.request(ctx, http.MethodPost, serviceOrderURI(outOrderNo, "cancel"), "", req, nil)
+}
+
+// CompleteServiceOrder 完结支付分订单
+type CompleteServiceOrder struct {
+	AppID         string         `json:"appid"`
+	ServiceID     string         `json:"service_id"`
+	PostPayments  []PostPayment  `json:"post_payments"`
+	PostDiscounts []PostDiscount `json:"post_discounts,omitempty"`
+	TotalAmount   int            `json:"total_amount"` // 总金额, 需等于后付费项目总额减去优惠总额
+	TimeRange     *TimeRange     `json:"time_range,omitempty"`
+	Location      *Location      `json:"location,omitempty"`
+	ProfitSharing bool           `json:"profit_sharing"`
+	GoodsTag      string         `json:"goods_tag,omitempty"`
+}
+
+// CompleteServiceOrder 完结支付分订单
+// 完结后微信将按照订单金额自动扣款
+func (c *Client) CompleteServiceOrder(ctx context.Context, outOrderNo string, o CompleteServiceOrder) (res ServiceOrderResult, err error) {
+	err = c.request(ctx, http.MethodPost, serviceOrderURI(outOrderNo, "complete"), "", o, &res)
+	return
+}
+
+// PayServiceOrder 商户发起催收扣款
+// 完结后自动扣款失败时可调用, 每个订单每天最多调用一次
+func (c *Client) PayServiceOrder(ctx context.Context, appID, serviceID, outOrderNo string) error {
+	req := map[string]string{
+		"appid":      appID,
+		"service_id": serviceID,
+	}
+
+	return c.request(ctx, http.MethodPost, serviceOrderURI(outOrderNo, "pay"), "", req, nil)
+}
+
+// SyncServiceOrderPaid 同步服务订单信息
+// 用户通过其他渠道完成支付后, 需同步订单状态避免重复扣款
+//
+// @paidTime 用户在其他渠道的支付时间, 格式 yyyyMMddHHmmss
+func (c *Client) SyncServiceOrderPaid(ctx context.Context, appID, serviceID, outOrderNo, paidTime string) (res ServiceOrderResult, err error) {
+	req := map[string]interface{}{
+		"appid":      appID,
+		"service_id": serviceID,
+		"type":       "Order_Paid",
+		"detail": map[string]string{
+			"paid_time": paidTime,
+		},
+	}
+
+	err = c.request(ctx, http.MethodPost, serviceOrderURI(outOrderNo, "sync"), "", req, &res)
+	return
+}
+
+func serviceOrderURI(outOrderNo, action string) string {
+	return payscoreServiceOrderAPI + "/" + url.PathEscape(outOrderNo) + "/" + action
+}
+
+// PayscorePermission 支付分授权/解除授权通知
+type PayscorePermission struct {
+	AppID             string `json:"appid"`
+	MchID             string `json:"mchid"`
+	OutRequestNo      string `json:"out_request_no"` // 商户签约单号
+	ServiceID         string `json:"service_id"`
+	OpenID            string `json:"openid"`
+	UserServiceStatus string `json:"user_service_status"` // 回调状态: USER_OPEN_SERVICE/USER_CLOSE_SERVICE
+	OpenOrCloseTime   string `json:"openorclose_time"`    // 服务开启/解除授权时间
+	AuthorizationCode string `json:"authorization_code"`  // 授权协议号
+}
+
+// HandlePayscorePermissionNotify 处理支付分开启/解除授权通知
+func (c *Client) HandlePayscorePermissionNotify(res http.ResponseWriter, req *http.Request, fn func(string, PayscorePermission) (bool, string)) error {
+	var p PayscorePermission
+	return c.handleNotify(res, req, &p, func(ntf Notification) (bool, string) {
+		return fn(ntf.EventType, p)
+	})
+}
+
+// HandleServiceOrderNotify 处理支付分订单确认及支付成功通知
+// 事件类型为 PayscoreUserConfirm 或 PayscoreUserPaid
+func (c *Client) HandleServiceOrderNotify(res http.ResponseWriter, req *http.Request, fn func(string, ServiceOrderResult) (bool, string)) error {
+	var o ServiceOrderResult
+	return c.handleNotify(res, req, &o, func(ntf Notification) (bool, string) {
+		return fn(ntf.EventType, o)
+	})
+}
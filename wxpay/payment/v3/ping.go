@@ -0,0 +1,66 @@
+package v3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+// 本机时间与微信服务器时间允许的最大偏差, 与微信校验请求时间戳的范围一致
+const maxClockSkew = 5 * time.Minute
+
+// PingResult 连通性检查结果
+type PingResult struct {
+	Latency   time.Duration // 请求耗时
+	ClockSkew time.Duration // 本机时间减去应答 Wechatpay-Timestamp
+}
+
+// Ping 检查网络、商户证书、APIv3 密钥和本机时间, 可用于就绪探针
+// 请求平台证书列表: 请求成功说明商户私钥和证书序列号正确,
+// 能解密证书说明 APIv3 密钥正确, 已加载平台证书时同时校验应答签名。
+// 不会替换已加载的平台证书
+func (c *Client) Ping(ctx context.Context) (res PingResult, err error) {
+	start := time.Now()
+	header, data, err := c.do(ctx, http.MethodGet, certificatesAPI, "", "", nil, nil)
+	res.Latency = time.Since(start)
+	if err != nil {
+		return
+	}
+
+	if ts, e := strconv.ParseInt(header.Get(headerTimestamp), 10, 64); e == nil {
+		res.ClockSkew = time.Now().Add(-res.Latency / 2).Sub(time.Unix(ts, 0)).Truncate(time.Second)
+	}
+
+	var list certificateList
+	if err = json.Unmarshal(data, &list); err != nil {
+		return
+	}
+	if len(list.Data) == 0 {
+		err = errors.New("ping: 平台证书列表为空")
+		return
+	}
+
+	ec := list.Data[0].EncryptCertificate
+	if _, err = util.AesGCMDecrypt(c.APIKey, ec.Nonce, ec.Ciphertext, ec.AssociatedData); err != nil {
+		err = errors.New("ping: 无法解密平台证书, 请检查 APIv3 密钥: " + err.Error())
+		return
+	}
+
+	if c.certs.get(header.Get(headerSerial)) != nil {
+		if err = c.verify(header, data); err != nil {
+			return
+		}
+	}
+
+	if res.ClockSkew > maxClockSkew || res.ClockSkew < -maxClockSkew {
+		err = fmt.Errorf("ping: 本机时间与微信服务器相差 %v", res.ClockSkew)
+	}
+
+	return
+}
@@ -0,0 +1,144 @@
+package v3
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+const (
+	profitSharingOrdersAPI         = "/v3/profitsharing/orders"
+	profitSharingReceiversAddAPI   = "/v3/profitsharing/receivers/add"
+	profitSharingReceiversDelAPI   = "/v3/profitsharing/receivers/delete"
+	profitSharingMerchantConfigAPI = "/v3/profitsharing/merchant-configs/"
+)
+
+// 分账接收方类型
+const (
+	ReceiverMerchantID     = "MERCHANT_ID"         // 商户号
+	ReceiverPersonalOpenID = "PERSONAL_OPENID"     // 个人openid(由父商户APPID转换得到)
+	ReceiverPersonalSubID  = "PERSONAL_SUB_OPENID" // 个人sub_openid(由子商户APPID转换得到)
+)
+
+// Receiver 分账接收方
+type Receiver struct {
+	SubMchID string `json:"sub_mchid,omitempty"` // 子商户号, 服务商模式必填
+	AppID    string `json:"appid"`               // 应用ID
+	SubAppID string `json:"sub_appid,omitempty"` // 子商户应用ID
+	Type     string `json:"type"`                // 分账接收方类型
+	Account  string `json:"account"`             // 分账接收方账号
+	// 分账个人接收方姓名或商户全称, 明文传入, 请求时自动使用平台证书加密
+	Name string `json:"name,omitempty"`
+	// 与分账方的关系类型: STORE/STAFF/STORE_OWNER/PARTNER/HEADQUARTER/BRAND/DISTRIBUTOR/USER/SUPPLIER/CUSTOM
+	RelationType   string `json:"relation_type"`
+	CustomRelation string `json:"custom_relation,omitempty"` // 自定义的分账关系
+}
+
+// AddReceiver 添加分账接收方
+func (c *Client) AddReceiver(ctx context.Context, r Receiver) error {
+	serial, err := c.encrypt(&r.Name)
+	if err != nil {
+		return err
+	}
+
+	return c.request(ctx, http.MethodPost, profitSharingReceiversAddAPI, serial, r, nil)
+}
+
+// DeleteReceiver 删除分账接收方
+func (c *Client) DeleteReceiver(ctx context.Context, r Receiver) error {
+	req := Receiver{
+		SubMchID: r.SubMchID,
+		AppID:    r.AppID,
+		SubAppID: r.SubAppID,
+		Type:     r.Type,
+		Account:  r.Account,
+	}
+
+	return c.request(ctx, http.MethodPost, profitSharingReceiversDelAPI, "", req, nil)
+}
+
+// ProfitSharingReceiver 分账订单中的接收方
+type ProfitSharingReceiver struct {
+	Type        string `json:"type"`           // 分账接收方类型
+	Account     string `json:"account"`        // 分账接收方账号
+	Name        string `json:"name,omitempty"` // 分账接收方姓名, 明文传入, 请求时自动加密
+	Amount      int    `json:"amount"`         // 分账金额(分)
+	Description string `json:"description"`    // 分账描述
+
+	// 以下为应答字段
+	Result     string `json:"result,omitempty"`      // 分账结果: PENDING/SUCCESS/CLOSED
+	FailReason string `json:"fail_reason,omitempty"` // 分账失败原因
+	DetailID   string `json:"detail_id,omitempty"`   // 分账明细单号
+	CreateTime string `json:"create_time,omitempty"`
+	FinishTime string `json:"finish_time,omitempty"`
+}
+
+// ProfitSharingOrder 请求分账
+type ProfitSharingOrder struct {
+	SubMchID        string                  `json:"sub_mchid,omitempty"` // 子商户号
+	AppID           string                  `json:"appid"`
+	SubAppID        string                  `json:"sub_appid,omitempty"`
+	TransactionID   string                  `json:"transaction_id"` // 微信订单号
+	OutOrderNo      string                  `json:"out_order_no"`   // 商户分账单号
+	Receivers       []ProfitSharingReceiver `json:"receivers"`
+	UnfreezeUnsplit bool                    `json:"unfreeze_unsplit"` // 是否解冻剩余未分资金
+}
+
+// ProfitSharingResult 分账结果
+type ProfitSharingResult struct {
+	SubMchID      string                  `json:"sub_mchid"`
+	TransactionID string                  `json:"transaction_id"`
+	OutOrderNo    string                  `json:"out_order_no"`
+	OrderID       string                  `json:"order_id"` // 微信分账单号
+	State         string                  `json:"state"`    // 分账单状态: PROCESSING/FINISHED
+	Receivers     []ProfitSharingReceiver `json:"receivers"`
+}
+
+// ProfitSharing 请求分账
+func (c *Client) ProfitSharing(ctx context.Context, o ProfitSharingOrder) (res ProfitSharingResult, err error) {
+	var serial string
+	receivers := make([]ProfitSharingReceiver, len(o.Receivers))
+	copy(receivers, o.Receivers)
+	for i := range receivers {
+		if receivers[i].Name == "" {
+			continue
+		}
+		if serial, err = c.encrypt(&receivers[i].Name); err != nil {
+			return
+		}
+	}
+	o.Receivers = receivers
+
+	err = c.request(ctx, http.MethodPost, profitSharingOrdersAPI, serial, o, &res)
+	return
+}
+
+// QueryProfitSharing 查询分账结果
+//
+// @subMchID 子商户号, 直连商户传空
+// @transactionID 微信订单号
+// @outOrderNo 商户分账单号
+func (c *Client) QueryProfitSharing(ctx context.Context, subMchID, transactionID, outOrderNo string) (res ProfitSharingResult, err error) {
+	query := url.Values{}
+	query.Set("transaction_id", transactionID)
+	if subMchID != "" {
+		query.Set("sub_mchid", subMchID)
+	}
+
+	uri := profitSharingOrdersAPI + "/" + url.PathEscape(outOrderNo) + "?" + query.Encode()
+	err = c.request(ctx, http.MethodGet, uri, "", nil, &res)
+	return
+}
+
+// MerchantConfig 子商户分账配置
+type MerchantConfig struct {
+	SubMchID string `json:"sub_mchid"`
+	// 子商户允许服务商分账的最大比例, 单位万分比, 比如 2000 表示 20%
+	MaxRatio int `json:"max_ratio"`
+}
+
+// QueryMaxRatio 查询子商户最大分账比例
+func (c *Client) QueryMaxRatio(ctx context.Context, subMchID string) (res MerchantConfig, err error) {
+	err = c.request(ctx, http.MethodGet, profitSharingMerchantConfigAPI+url.PathEscape(subMchID), "", nil, &res)
+	return
+}
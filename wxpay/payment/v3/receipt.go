@@ -0,0 +1,64 @@
+package v3
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const shoppingReceiptAPI = "/v3/marketing/shopping-receipt/shoppingreceipts"
+
+// Receipt 电子小票
+// 电子小票通过微信订单号与交易绑定, 上传后用户可在支付凭证中查看
+type Receipt struct {
+	TransactionID string // 微信订单号
+	// 交易所属商户号, 服务商模式为子商户号
+	TransactionMchID string
+	OutTradeNo       string // 商户订单号, 可选
+	Filename         string // 小票图片文件名, 仅支持 jpg/png
+	Image            []byte // 小票图片内容
+}
+
+type receiptMeta struct {
+	TransactionID    string `json:"transaction_id"`
+	TransactionMchID string `json:"transaction_mchid"`
+	OutTradeNo       string `json:"out_trade_no,omitempty"`
+	UploadTime       string `json:"upload_time"`
+	SHA256           string `json:"sha256"`
+}
+
+// ReceiptResult 电子小票上传结果
+type ReceiptResult struct {
+	TransactionID    string `json:"transaction_id"`
+	TransactionMchID string `json:"transaction_mchid"`
+	OutTradeNo       string `json:"out_trade_no"`
+	SHA256           string `json:"sha256"`
+	UploadTime       string `json:"upload_time"`
+	// 小票ID, 对同一笔交易重复上传时覆盖之前的小票
+	ReceiptID string `json:"receipt_id"`
+}
+
+// UploadReceipt 上传电子小票图片并绑定到交易
+// 商户需先通过 OpenCustomPage 开通商家小票, 支付成功页才会展示小票入口
+func (c *Client) UploadReceipt(ctx context.Context, r Receipt) (res ReceiptResult, err error) {
+	if r.TransactionID == "" || r.TransactionMchID == "" {
+		err = errors.New("transaction_id 和 transaction_mchid 不能为空")
+		return
+	}
+
+	if len(r.Image) == 0 {
+		err = errors.New("小票图片不能为空")
+		return
+	}
+
+	meta := receiptMeta{
+		TransactionID:    r.TransactionID,
+		TransactionMchID: r.TransactionMchID,
+		OutTradeNo:       r.OutTradeNo,
+		UploadTime:       time.Now().Format(time.RFC3339),
+		SHA256:           sha256Hex(r.Image),
+	}
+
+	err = c.upload(ctx, shoppingReceiptAPI, r.Filename, r.Image, meta, &res)
+	return
+}
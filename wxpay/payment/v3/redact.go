@@ -0,0 +1,33 @@
+package v3
+
+import "github.com/wanghuobo/weapp/util"
+
+// 个人接收方的账号为 openid, 需要遮盖
+func redactAccount(typ, account string) string {
+	if typ == ReceiverMerchantID {
+		return account
+	}
+
+	return util.MaskOpenID(account)
+}
+
+// Redacted 返回遮盖了开户名称和银行账号的副本, 用于打印日志
+func (a SettlementAccount) Redacted() SettlementAccount {
+	a.AccountName = util.MaskName(a.AccountName)
+	a.AccountNumber = util.MaskBankNo(a.AccountNumber)
+	return a
+}
+
+// Redacted 返回遮盖了个人账号和姓名的副本, 用于打印日志
+func (r Receiver) Redacted() Receiver {
+	r.Account = redactAccount(r.Type, r.Account)
+	r.Name = util.MaskName(r.Name)
+	return r
+}
+
+// Redacted 返回遮盖了个人账号和姓名的副本, 用于打印日志
+func (r ProfitSharingReceiver) Redacted() ProfitSharingReceiver {
+	r.Account = redactAccount(r.Type, r.Account)
+	r.Name = util.MaskName(r.Name)
+	return r
+}
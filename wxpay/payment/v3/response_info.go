@@ -0,0 +1,87 @@
+package v3
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 应答中用于排查和限频的头
+const (
+	headerRequestID  = "Request-ID"
+	headerRetryAfter = "Retry-After"
+)
+
+// 限频时默认等待时间, 应答中没有 Retry-After 时使用
+const defaultRetryAfter = time.Second
+
+// ResponseInfo 单次请求的应答信息
+type ResponseInfo struct {
+	StatusCode int           // HTTP 状态码
+	RequestID  string        // 微信请求ID, 联系微信排查问题时提供
+	RetryAfter time.Duration // 限频时微信要求的等待时间
+	Header     http.Header   // 完整应答头
+	Duration   time.Duration // 请求耗时
+}
+
+type responseInfoKey struct{}
+
+// WithResponseInfo 请求结束后把应答信息写入 info
+// 请求失败时 info 中已经获取到的字段同样会被填写
+func WithResponseInfo(ctx context.Context, info *ResponseInfo) context.Context {
+	return context.WithValue(ctx, responseInfoKey{}, info)
+}
+
+// 记录应答信息, 返回限频等待时间
+func (c *Client) captureResponse(ctx context.Context, method, uri string, res *http.Response, d time.Duration) (requestID string, retryAfter time.Duration) {
+	requestID = res.Header.Get(headerRequestID)
+	if res.StatusCode == http.StatusTooManyRequests {
+		if retryAfter = parseRetryAfter(res.Header.Get(headerRetryAfter)); retryAfter <= 0 {
+			retryAfter = defaultRetryAfter
+		}
+		c.metrics.observeThrottle(method, uri, retryAfter)
+	}
+
+	if info, ok := ctx.Value(responseInfoKey{}).(*ResponseInfo); ok && info != nil {
+		*info = ResponseInfo{
+			StatusCode: res.StatusCode,
+			RequestID:  requestID,
+			RetryAfter: retryAfter,
+			Header:     res.Header,
+			Duration:   d,
+		}
+	}
+
+	return
+}
+
+// 解析 Retry-After, 支持秒数和 HTTP 日期两种格式
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+
+	if sec, err := strconv.Atoi(v); err == nil {
+		return time.Duration(sec) * time.Second
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+
+	return 0
+}
+
+// ThrottledUntil 微信限频要求的最早重试时间, 未被限频时返回零值
+// 批量调用时可以据此暂停, 避免持续触发限频
+func (c *Client) ThrottledUntil() time.Time {
+	c.metrics.mu.Lock()
+	defer c.metrics.mu.Unlock()
+
+	if time.Now().After(c.metrics.throttledUntil) {
+		return time.Time{}
+	}
+
+	return c.metrics.throttledUntil
+}
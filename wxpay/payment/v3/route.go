@@ -0,0 +1,85 @@
+package v3
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// NotifyHandler 通知处理函数
+//
+// @ntf 通知
+// @data 解密后的通知数据, 按 ntf.EventType 解析为对应结构
+type NotifyHandler func(ntf Notification, data json.RawMessage) (bool, string)
+
+// 子商户通知路由
+type notifyRoutes struct {
+	mu       sync.RWMutex
+	handlers map[string]NotifyHandler
+	fallback NotifyHandler
+}
+
+// HandleSubMerchant 注册子商户通知处理函数
+// 服务商统一接收回调时, RouteNotify 按通知数据中的 sub_mchid 分发
+//
+// @subMchID 子商户号
+func (c *Client) HandleSubMerchant(subMchID string, fn NotifyHandler) {
+	c.routes.mu.Lock()
+	defer c.routes.mu.Unlock()
+
+	if c.routes.handlers == nil {
+		c.routes.handlers = make(map[string]NotifyHandler)
+	}
+	c.routes.handlers[subMchID] = fn
+}
+
+// HandleDefault 注册未知子商户或不含 sub_mchid 的通知处理函数
+func (c *Client) HandleDefault(fn NotifyHandler) {
+	c.routes.mu.Lock()
+	c.routes.fallback = fn
+	c.routes.mu.Unlock()
+}
+
+// RouteNotify 校验并解密通知后按子商户号分发
+// 校验、解密失败或没有匹配的处理函数时应答错误状态码并返回错误, 微信会重新发送通知
+func (c *Client) RouteNotify(res http.ResponseWriter, req *http.Request) error {
+	var data json.RawMessage
+
+	ntf, err := c.ParseNotify(req)
+	if err != nil {
+		WriteNotifyError(res, err)
+		return err
+	}
+
+	if err := c.Decrypt(ntf, &data); err != nil {
+		err = &NotifyError{Status: http.StatusBadRequest, Err: err}
+		WriteNotifyError(res, err)
+		return err
+	}
+
+	var sub struct {
+		SubMchID string `json:"sub_mchid"`
+	}
+	if err := json.Unmarshal(data, &sub); err != nil {
+		err = &NotifyError{Status: http.StatusBadRequest, Err: err}
+		WriteNotifyError(res, err)
+		return err
+	}
+
+	c.routes.mu.RLock()
+	fn, ok := c.routes.handlers[sub.SubMchID]
+	if !ok {
+		fn = c.routes.fallback
+	}
+	c.routes.mu.RUnlock()
+
+	if fn == nil {
+		err := errors.New("没有子商户 " + sub.SubMchID + " 的通知处理函数")
+		WriteNotifyError(res, err)
+		return err
+	}
+
+	ok, msg := fn(ntf, data)
+	return writeReplay(res, ok, msg)
+}
@@ -0,0 +1,88 @@
+package v3
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+const subMerchantsAPI = "/v3/apply4sub/sub_merchants/"
+
+// 结算账户类型
+const (
+	AccountTypeBusiness = "ACCOUNT_TYPE_BUSINESS" // 对公银行账户
+	AccountTypePrivate  = "ACCOUNT_TYPE_PRIVATE"  // 经营者个人银行卡
+)
+
+// SettlementAccount 修改结算账户
+type SettlementAccount struct {
+	AccountType     string `json:"account_type"`             // 账户类型
+	AccountBank     string `json:"account_bank"`             // 开户银行
+	BankAddressCode string `json:"bank_address_code"`        // 开户银行省市编码
+	BankName        string `json:"bank_name,omitempty"`      // 开户银行全称(含支行)
+	BankBranchID    string `json:"bank_branch_id,omitempty"` // 开户银行联行号
+	AccountName     string `json:"account_name,omitempty"`   // 开户名称, 明文传入, 请求时自动加密
+	AccountNumber   string `json:"account_number"`           // 银行账号, 明文传入, 请求时自动加密
+}
+
+// ModifySettlementResult 修改结算账户结果
+type ModifySettlementResult struct {
+	ApplicationNo string `json:"application_no"` // 修改结算账户申请单号
+}
+
+// ModifySettlement 修改子商户结算账户
+// 银行账号及开户名称使用平台证书加密
+func (c *Client) ModifySettlement(ctx context.Context, subMchID string, a SettlementAccount) (res ModifySettlementResult, err error) {
+	serial, err := c.encrypt(&a.AccountNumber)
+	if err != nil {
+		return
+	}
+
+	if a.AccountName != "" {
+		if serial, err = c.encrypt(&a.AccountName); err != nil {
+			return
+		}
+	}
+
+	err = c.request(ctx, http.MethodPost, subMerchantsAPI+url.PathEscape(subMchID)+"/modify-settlement", serial, a, &res)
+	return
+}
+
+// Settlement 结算账户信息
+type Settlement struct {
+	AccountType   string `json:"account_type"`
+	AccountBank   string `json:"account_bank"`
+	BankName      string `json:"bank_name"`
+	BankBranchID  string `json:"bank_branch_id"`
+	AccountNumber string `json:"account_number"` // 脱敏的银行账号
+	// 汇款验证结果: VERIFY_SUCCESS 验证成功 | VERIFY_FAIL 验证失败 | VERIFYING 验证中
+	VerifyResult     string `json:"verify_result"`
+	VerifyFailReason string `json:"verify_fail_reason"`
+}
+
+// QuerySettlement 查询子商户结算账户
+func (c *Client) QuerySettlement(ctx context.Context, subMchID string) (res Settlement, err error) {
+	err = c.request(ctx, http.MethodGet, subMerchantsAPI+url.PathEscape(subMchID)+"/settlement", "", nil, &res)
+	return
+}
+
+// SettlementApplication 修改结算账户申请状态
+type SettlementApplication struct {
+	AccountName   string `json:"account_name"` // 脱敏的开户名称
+	AccountType   string `json:"account_type"`
+	AccountBank   string `json:"account_bank"`
+	BankName      string `json:"bank_name"`
+	BankBranchID  string `json:"bank_branch_id"`
+	AccountNumber string `json:"account_number"`
+	// 审核状态: AUDIT_SUCCESS 审核成功 | AUDITING 审核中 | AUDIT_FAIL 审核驳回
+	VerifyResult     string `json:"verify_result"`
+	VerifyFailReason string `json:"verify_fail_reason"`
+	VerifyFinishTime string `json:"verify_finish_time"`
+}
+
+// QuerySettlementApplication 查询修改结算账户申请状态
+func (c *Client) QuerySettlementApplication(ctx context.Context, subMchID, applicationNo string) (res SettlementApplication, err error) {
+	uri := subMerchantsAPI + url.PathEscape(subMchID) + "/application/" + url.PathEscape(applicationNo)
+	err = c.request(ctx, http.MethodGet, uri, "", nil, &res)
+	return
+}
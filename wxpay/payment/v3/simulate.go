@@ -0,0 +1,112 @@
+package v3
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/wanghuobo/weapp/util"
+)
+
+// Simulator APIv3 回调通知模拟器
+// 本地开发联调时生成签名和加密方式都与微信支付一致的通知并投递到业务系统,
+// 签名使用模拟的平台证书私钥, 业务系统需要通过 Client.AddCertificate 信任对应的证书
+type Simulator struct {
+	APIKey     string          // APIv3 密钥, 与业务系统配置一致
+	SerialNo   string          // 模拟的平台证书序列号
+	PrivateKey *rsa.PrivateKey // 模拟的平台证书私钥
+	HTTPClient *http.Client    // 为空时使用 http.DefaultClient
+}
+
+// Notify 生成回调通知
+//
+// @eventType 通知类型, 如 TRANSACTION.SUCCESS、REFUND.SUCCESS
+// @originalType 通知数据原始类型, 如 transaction、refund, 同时作为加密附加数据
+// @data 通知数据, 序列化为 JSON 后加密
+func (s Simulator) Notify(eventType, originalType string, data interface{}) (header http.Header, body []byte, err error) {
+	plaintext, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	nonce := util.RandomString(12)
+	ciphertext, err := util.AesGCMEncrypt(s.APIKey, nonce, plaintext, originalType)
+	if err != nil {
+		return
+	}
+
+	ntf := Notification{
+		ID:           util.RandomString(32),
+		CreateTime:   time.Now().Format(time.RFC3339),
+		EventType:    eventType,
+		ResourceType: "encrypt-resource",
+		Summary:      "模拟通知",
+		Resource: resource{
+			Algorithm:      "AEAD_AES_256_GCM",
+			Ciphertext:     ciphertext,
+			AssociatedData: originalType,
+			OriginalType:   originalType,
+			Nonce:          nonce,
+		},
+	}
+
+	if body, err = json.Marshal(ntf); err != nil {
+		return
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonceStr := util.RandomString(32)
+	signature, err := util.SignSHA256WithRSA(timestamp+"\n"+nonceStr+"\n"+string(body)+"\n", s.PrivateKey)
+	if err != nil {
+		return
+	}
+
+	header = make(http.Header)
+	header.Set("Content-Type", "application/json")
+	header.Set(headerTimestamp, timestamp)
+	header.Set(headerNonce, nonceStr)
+	header.Set(headerSignature, signature)
+	header.Set(headerSerial, s.SerialNo)
+
+	return
+}
+
+// Send 生成回调通知并投递到业务系统
+// 业务系统返回非 2XX 状态码时返回错误
+func (s Simulator) Send(ctx context.Context, url, eventType, originalType string, data interface{}) error {
+	header, body, err := s.Notify(eventType, originalType, data)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header = header
+
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("通知处理失败: HTTP %d %s", res.StatusCode, msg)
+	}
+
+	return nil
+}
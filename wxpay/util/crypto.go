@@ -2,15 +2,22 @@ package util
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/md5"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/pem"
 	"errors"
 	"io"
+	"io/ioutil"
 	"sort"
 	"strings"
 
@@ -203,3 +210,202 @@ func AesECBDecrypt(ciphertext, key []byte) (plaintext []byte, err error) {
 
 	return PKCS5UnPadding(ciphertext)
 }
+
+// AesECBEncrypt ECB 加密数据, 与 AesECBDecrypt 对应
+//
+// @plaintext 明文数据
+// @key 商户支付密钥
+func AesECBEncrypt(plaintext, key []byte) (ciphertext []byte, err error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return
+	}
+
+	ciphertext = PKCS5Padding(append([]byte(nil), plaintext...), block.BlockSize())
+	ecb.NewEncrypter(block).CryptBlocks(ciphertext, ciphertext)
+
+	return
+}
+
+// SignSHA256WithRSA SHA256withRSA 签名并返回 base64 编码结果
+//
+// @message 待签名串
+// @key 商户 API 私钥
+func SignSHA256WithRSA(message string, key *rsa.PrivateKey) (string, error) {
+	hashed := sha256.Sum256([]byte(message))
+	sign, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(sign), nil
+}
+
+// VerifySHA256WithRSA 使用证书公钥校验 SHA256withRSA 签名
+//
+// @message 待验签串
+// @signature base64 编码的签名
+// @cert 微信支付平台证书
+func VerifySHA256WithRSA(message, signature string, cert *x509.Certificate) error {
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("证书公钥不是 RSA 类型")
+	}
+
+	sign, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return err
+	}
+
+	hashed := sha256.Sum256([]byte(message))
+	return rsa.VerifyPKCS1v15(pub, crypto.SHA256, hashed[:], sign)
+}
+
+// EncryptOAEP 使用证书公钥加密敏感信息(RSAES-OAEP)并返回 base64 编码结果
+func EncryptOAEP(plaintext string, cert *x509.Certificate) (string, error) {
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return "", errors.New("证书公钥不是 RSA 类型")
+	}
+
+	ciphertext, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, pub, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// DecryptOAEP 使用商户私钥解密敏感信息(RSAES-OAEP)
+func DecryptOAEP(ciphertext string, key *rsa.PrivateKey) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := rsa.DecryptOAEP(sha1.New(), rand.Reader, key, data, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// AesGCMDecrypt AEAD_AES_256_GCM 解密
+//
+// @key APIv3 密钥
+// @nonce 加密使用的随机串
+// @ciphertext base64 编码的密文
+// @additional 附加数据
+func AesGCMDecrypt(key, nonce, ciphertext, additional string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return gcm.Open(nil, []byte(nonce), data, []byte(additional))
+}
+
+// AesGCMEncrypt AEAD_AES_256_GCM 加密, 返回 base64 编码的密文
+//
+// @key APIv3 密钥
+// @nonce 12 位随机串
+// @additional 附加数据
+func AesGCMEncrypt(key, nonce string, plaintext []byte, additional string) (string, error) {
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	data := gcm.Seal(nil, []byte(nonce), plaintext, []byte(additional))
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// ParsePrivateKey 解析 PEM 格式的 RSA 私钥(PKCS#1 或 PKCS#8)
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("私钥格式错误")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rk, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("私钥不是 RSA 类型")
+	}
+
+	return rk, nil
+}
+
+// ParseCertificate 解析 PEM 格式的证书
+func ParseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("证书格式错误")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// ReadPrivateKey 读取并解析 PEM 格式的 RSA 私钥
+func ReadPrivateKey(r io.Reader) (*rsa.PrivateKey, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParsePrivateKey(data)
+}
+
+// ReadCertificate 读取并解析 PEM 格式的证书
+func ReadCertificate(r io.Reader) (*x509.Certificate, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseCertificate(data)
+}
+
+// SignByHMACSHA256 多参数通过HMAC-SHA256签名
+func SignByHMACSHA256(data map[string]string, key string) (string, error) {
+
+	var query []string
+	for k, v := range data {
+		query = append(query, k+"="+v)
+	}
+
+	sort.Strings(query)
+	query = append(query, "key="+key)
+	str := strings.Join(query, "&")
+
+	hs := hmac.New(sha256.New, []byte(key))
+	if _, err := hs.Write([]byte(str)); err != nil {
+		return "", err
+	}
+
+	return strings.ToUpper(hex.EncodeToString(hs.Sum(nil))), nil
+}
@@ -0,0 +1,107 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// DNSCache 带缓存的域名解析
+// 解析失败时使用已过期的缓存结果, 避免 DNS 短暂故障直接导致支付失败
+type DNSCache struct {
+	TTL      time.Duration // 缓存时间, 默认1分钟
+	Timeout  time.Duration // 单次解析超时时间, 默认2秒
+	Resolver *net.Resolver // 为空使用 net.DefaultResolver
+
+	mu      sync.RWMutex
+	entries map[string]dnsEntry
+}
+
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+// NewDNSCache 新建域名解析缓存
+func NewDNSCache(ttl, timeout time.Duration) *DNSCache {
+	return &DNSCache{TTL: ttl, Timeout: timeout}
+}
+
+// LookupHost 解析域名, 优先使用未过期的缓存
+func (c *DNSCache) LookupHost(ctx context.Context, host string) ([]string, error) {
+	c.mu.RLock()
+	entry, ok := c.entries[host]
+	c.mu.RUnlock()
+
+	if ok && time.Now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	resolver := c.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	lctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	addrs, err := resolver.LookupHost(lctx, host)
+	if err != nil || len(addrs) == 0 {
+		if ok {
+			return entry.addrs, nil
+		}
+		if err == nil {
+			err = errors.New("no address found for " + host)
+		}
+		return nil, err
+	}
+
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]dnsEntry)
+	}
+	c.entries[host] = dnsEntry{addrs: addrs, expires: time.Now().Add(ttl)}
+	c.mu.Unlock()
+
+	return addrs, nil
+}
+
+// DialContext 使用缓存解析结果建立连接, 依次尝试解析到的地址
+func (c *DNSCache) DialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		if net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		addrs, err := c.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ip := range addrs {
+			var conn net.Conn
+			if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip, port)); err == nil {
+				return conn, nil
+			}
+		}
+
+		return nil, err
+	}
+}
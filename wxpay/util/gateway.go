@@ -0,0 +1,83 @@
+package util
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+)
+
+// GatewayResponse 交给 API 网关或云函数返回的应答
+type GatewayResponse struct {
+	StatusCode int
+	Header     map[string]string
+	Body       []byte
+}
+
+// GatewayBody 读取网关转交的请求体
+// 网关对二进制或非 UTF-8 内容通常做 base64 编码, 并通过 isBase64Encoded 等字段标明
+func GatewayBody(body string, base64Encoded bool) ([]byte, error) {
+	if !base64Encoded {
+		return []byte(body), nil
+	}
+
+	return base64.StdEncoding.DecodeString(body)
+}
+
+// ServeGateway 使用网关转交的请求头和请求体调用 http.Handler
+// 用于在 API 网关、云函数等非 HTTP 服务中复用通知处理器及签名校验逻辑,
+// 请求头名称不区分大小写
+//
+// @path 请求路径, 通知地址令牌等依赖路径的校验需要传入原始路径, 为空时使用 "/"
+func ServeGateway(h http.Handler, path string, headers map[string]string, body []byte) (res GatewayResponse, err error) {
+	if path == "" {
+		path = "/"
+	}
+
+	req, err := http.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	rec := &gatewayRecorder{header: make(http.Header)}
+	h.ServeHTTP(rec, req)
+
+	res.StatusCode = rec.code
+	if res.StatusCode == 0 {
+		res.StatusCode = http.StatusOK
+	}
+	res.Header = make(map[string]string, len(rec.header))
+	for k := range rec.header {
+		res.Header[k] = rec.header.Get(k)
+	}
+	res.Body = rec.body.Bytes()
+
+	return
+}
+
+// 记录 handler 的应答
+type gatewayRecorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (r *gatewayRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *gatewayRecorder) Write(b []byte) (int, error) {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+
+	return r.body.Write(b)
+}
+
+func (r *gatewayRecorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+}
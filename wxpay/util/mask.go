@@ -0,0 +1,40 @@
+package util
+
+import "strings"
+
+// Mask 使用 * 遮盖字符串中间部分
+//
+// @head 保留开头字符数
+// @tail 保留结尾字符数
+// 字符串长度不足时全部遮盖
+func Mask(s string, head, tail int) string {
+	r := []rune(s)
+	if len(r) == 0 {
+		return s
+	}
+
+	if len(r) <= head+tail {
+		return strings.Repeat("*", len(r))
+	}
+
+	return string(r[:head]) + strings.Repeat("*", len(r)-head-tail) + string(r[len(r)-tail:])
+}
+
+// MaskOpenID 遮盖 openid, 保留前后各 4 位
+func MaskOpenID(openID string) string {
+	return Mask(openID, 4, 4)
+}
+
+// MaskName 遮盖姓名, 仅保留第一个字
+func MaskName(name string) string {
+	if len([]rune(name)) == 1 {
+		return "*"
+	}
+
+	return Mask(name, 1, 0)
+}
+
+// MaskBankNo 遮盖银行账号, 仅保留后 4 位
+func MaskBankNo(no string) string {
+	return Mask(no, 0, 4)
+}
@@ -0,0 +1,37 @@
+package util
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"errors"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// LoadPKCS12 解析 PKCS#12 格式的证书及私钥
+// 微信支付下载的证书包中 apiclient_cert.p12 与 apiclient_cert.pem/apiclient_key.pem 内容相同
+//
+// @password 证书密码, 微信支付商户证书的密码为商户号
+func LoadPKCS12(data []byte, password string) (cert tls.Certificate, err error) {
+	blocks, err := pkcs12.ToPEM(data, password)
+	if err != nil {
+		return
+	}
+
+	var certPEM, keyPEM []byte
+	for _, b := range blocks {
+		switch b.Type {
+		case "CERTIFICATE":
+			certPEM = append(certPEM, pem.EncodeToMemory(b)...)
+		case "PRIVATE KEY":
+			keyPEM = append(keyPEM, pem.EncodeToMemory(b)...)
+		}
+	}
+
+	if len(certPEM) == 0 || len(keyPEM) == 0 {
+		err = errors.New("PKCS#12 证书中缺少证书或私钥")
+		return
+	}
+
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
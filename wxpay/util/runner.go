@@ -0,0 +1,99 @@
+package util
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Runner 后台组件
+// Run 持续运行直到 ctx 取消并返回停止原因, 可以交给 errgroup 等统一启动和停止
+type Runner interface {
+	Run(ctx context.Context) error
+}
+
+// RunReport 后台组件运行情况
+type RunReport struct {
+	Running  bool
+	Started  time.Time // 最近一次启动时间
+	Stopped  time.Time // 最近一次停止时间
+	Runs     int64     // 执行次数
+	Failures int64     // 执行失败次数
+	LastErr  error     // 最近一次执行失败的原因
+	StopErr  error     // 停止原因, ctx 取消时为 context.Canceled 或 context.DeadlineExceeded
+}
+
+// Loop 周期执行任务, 供定时扫描、证书刷新等后台组件使用
+type Loop struct {
+	Interval time.Duration // 执行间隔, 默认1分钟
+	Jitter   time.Duration // 执行间隔的随机抖动, 为0时不抖动, 避免多实例同时执行
+
+	mu     sync.Mutex
+	report RunReport
+}
+
+// Run 立即执行一次 fn, 之后按间隔执行, 直到 ctx 取消
+// fn 返回的错误交给 onError 并记录在 Report 中, 不会中断循环
+func (l *Loop) Run(ctx context.Context, fn func(context.Context) error, onError func(error)) error {
+	interval := l.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	l.mu.Lock()
+	l.report.Running = true
+	l.report.Started = time.Now()
+	l.report.StopErr = nil
+	l.mu.Unlock()
+
+	for {
+		err := fn(ctx)
+		if ctx.Err() != nil {
+			return l.stop(ctx.Err())
+		}
+
+		l.mu.Lock()
+		l.report.Runs++
+		if err != nil {
+			l.report.Failures++
+			l.report.LastErr = err
+		}
+		l.mu.Unlock()
+
+		if err != nil && onError != nil {
+			onError(err)
+		}
+
+		d := interval
+		if l.Jitter > 0 {
+			d += time.Duration(rand.Int63n(int64(l.Jitter)+1)) - l.Jitter/2
+		}
+
+		timer := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return l.stop(ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (l *Loop) stop(err error) error {
+	l.mu.Lock()
+	l.report.Running = false
+	l.report.Stopped = time.Now()
+	l.report.StopErr = err
+	l.mu.Unlock()
+
+	return err
+}
+
+// Report 运行情况
+func (l *Loop) Report() RunReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.report
+}
@@ -0,0 +1,210 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SecretSource 密钥材料来源, 如商户证书、私钥和支付密钥
+// 返回的 key 为密钥名称(如 apiclient_cert.pem), value 为内容
+type SecretSource interface {
+	Load(ctx context.Context) (map[string][]byte, error)
+}
+
+// FileSecret 读取目录下的密钥文件
+// 适用于 Kubernetes Secret 卷和 Vault Agent 渲染的目录, 每个文件为一项密钥。
+// 以 . 开头的文件(如 Kubernetes 的 ..data)和子目录会被忽略
+type FileSecret struct {
+	Dir string
+}
+
+// Load 读取目录下的全部密钥文件
+func (s FileSecret) Load(ctx context.Context) (map[string][]byte, error) {
+	entries, err := ioutil.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string][]byte, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		// Kubernetes Secret 卷中的文件为指向 ..data 目录的符号链接
+		fi, err := os.Stat(filepath.Join(s.Dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if fi.IsDir() {
+			continue
+		}
+
+		if data[name], err = ioutil.ReadFile(filepath.Join(s.Dir, name)); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// VaultSecret 读取 Vault KV 引擎中的密钥
+// KV v2 的路径需要包含 data, 如 secret/data/wxpay
+type VaultSecret struct {
+	Addr   string       // Vault 地址, 如 https://vault.example.com:8200
+	Token  string       // 访问令牌
+	Path   string       // 密钥路径
+	Client *http.Client // 为空时使用 http.DefaultClient
+}
+
+// Load 读取密钥, 值为字符串的字段作为一项密钥
+func (s VaultSecret) Load(ctx context.Context) (map[string][]byte, error) {
+	uri := strings.TrimSuffix(s.Addr, "/") + "/v1/" + strings.TrimPrefix(s.Path, "/")
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("X-Vault-Token", s.Token)
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("读取 Vault 密钥失败: HTTP %d %s", res.StatusCode, body)
+	}
+
+	var ret struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err = json.Unmarshal(body, &ret); err != nil {
+		return nil, err
+	}
+
+	// KV v2 的密钥在 data.data 中
+	fields := ret.Data
+	if inner, ok := fields["data"]; ok {
+		if _, hasMeta := fields["metadata"]; hasMeta {
+			fields = nil
+			if err = json.Unmarshal(inner, &fields); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	data := make(map[string][]byte, len(fields))
+	for k, v := range fields {
+		var str string
+		if json.Unmarshal(v, &str) == nil {
+			data[k] = []byte(str)
+		}
+	}
+
+	if len(data) == 0 {
+		return nil, errors.New("Vault 密钥为空: " + s.Path)
+	}
+
+	return data, nil
+}
+
+// SecretWatcher 定期读取密钥, 内容变化时调用 OnChange, 实现密钥轮换后热加载
+//
+//	w := &util.SecretWatcher{
+//		Source: util.FileSecret{Dir: "/etc/wxpay"},
+//		OnChange: func(s map[string][]byte) error {
+//			return client.SetCertificate(s["apiclient_cert.pem"], s["apiclient_key.pem"])
+//		},
+//	}
+//	go w.Run(ctx)
+type SecretWatcher struct {
+	Source   SecretSource
+	Interval time.Duration // 读取间隔, 默认1分钟
+
+	// OnChange 首次读取及内容变化时调用, 返回错误时下次读取会再次调用
+	OnChange func(map[string][]byte) error
+	OnError  func(error) // 读取或 OnChange 失败时调用, 为空则忽略
+
+	mu   sync.Mutex
+	sum  []byte
+	loop Loop
+}
+
+// Run 立即读取一次密钥, 之后按间隔读取, 直到 ctx 取消, 实现 Runner
+func (w *SecretWatcher) Run(ctx context.Context) error {
+	w.loop.Interval = w.Interval
+	return w.loop.Run(ctx, w.Check, w.OnError)
+}
+
+// Check 读取一次密钥, 内容变化时调用 OnChange
+func (w *SecretWatcher) Check(ctx context.Context) error {
+	data, err := w.Source.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	sum := secretSum(data)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if bytes.Equal(sum, w.sum) {
+		return nil
+	}
+
+	if w.OnChange != nil {
+		if err = w.OnChange(data); err != nil {
+			return err
+		}
+	}
+	w.sum = sum
+
+	return nil
+}
+
+// Report 运行情况, 每次读取计为一次执行
+func (w *SecretWatcher) Report() RunReport {
+	return w.loop.Report()
+}
+
+// 密钥内容摘要, 用于判断是否变化
+func secretSum(data map[string][]byte) []byte {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s:%d:", k, len(data[k]))
+		h.Write(data[k])
+	}
+
+	return h.Sum(nil)
+}
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"net/url"
 	"time"
+	"unicode/utf8"
 )
 
 // TokenAPI 获取带 token 的 API 地址
@@ -111,6 +112,32 @@ func NewTLSClient(certPath, keyPath string) (httpClient *http.Client, err error)
 	return newTLSClient(tlsConfig)
 }
 
+// NewTLSClientFromPEM 使用内存中的 PEM 格式证书及私钥创建支持双向证书认证的 http.Client
+func NewTLSClientFromPEM(certPEM, keyPEM []byte) (*http.Client, error) {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+	return newTLSClient(tlsConfig)
+}
+
+// NewTLSClientFromPKCS12 使用 PKCS#12 格式的证书(apiclient_cert.p12)创建支持双向证书认证的 http.Client
+//
+// @password 证书密码, 微信支付商户证书的密码为商户号
+func NewTLSClientFromPKCS12(data []byte, password string) (*http.Client, error) {
+	cert, err := LoadPKCS12(data, password)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+	return newTLSClient(tlsConfig)
+}
+
 func newTLSClient(tlsConfig *tls.Config) (*http.Client, error) {
 
 	dialTLS := func(network, addr string) (net.Conn, error) {
@@ -156,3 +183,108 @@ func FetchIP() (net.IP, error) {
 
 	return nil, errors.New("failed to found IP address")
 }
+
+// DefaultUserAgent 默认 User-Agent
+const DefaultUserAgent = "wxpay-go"
+
+// SetHeaders 设置 User-Agent 及额外请求头
+// 额外请求头不会覆盖请求中已经设置的头(签名、内容类型等), 也不允许设置 Authorization 和 Host
+//
+// @userAgent 为空时使用 DefaultUserAgent
+func SetHeaders(req *http.Request, userAgent string, extra http.Header) {
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	for key, values := range extra {
+		key = http.CanonicalHeaderKey(key)
+		if key == "Authorization" || key == "Host" || key == "User-Agent" || req.Header.Get(key) != "" {
+			continue
+		}
+
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+}
+
+// TransportOptions 传输层配置
+type TransportOptions struct {
+	MinVersion   uint16   // 最低 TLS 版本, 默认 tls.VersionTLS12
+	CipherSuites []uint16 // 允许的加密套件, 为空使用 Go 默认值, 仅对 TLS1.2 及以下生效
+	EnableHTTP2  bool     // 是否启用 HTTP/2
+	DialTimeout  time.Duration
+	DNSCache     *DNSCache // 域名解析缓存, 为空则每次连接时解析
+
+	// 连接池, 高并发下单时应调大 MaxIdleConnsPerHost 以复用连接, 避免频繁握手
+	MaxIdleConns        int           // 最大空闲连接数, 默认100
+	MaxIdleConnsPerHost int           // 每个域名的最大空闲连接数, 默认100(请求集中在同一个域名)
+	MaxConnsPerHost     int           // 每个域名的最大连接数, 默认不限制
+	IdleConnTimeout     time.Duration // 空闲连接超时时间, 默认90秒
+}
+
+// NewTransport 按配置创建 http.Transport
+//
+// @certs 双向认证使用的商户证书
+func NewTransport(opts TransportOptions, certs ...tls.Certificate) *http.Transport {
+	if opts.MinVersion == 0 {
+		opts.MinVersion = tls.VersionTLS12
+	}
+
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = 5 * time.Second
+	}
+
+	if opts.MaxIdleConns <= 0 {
+		opts.MaxIdleConns = 100
+	}
+
+	if opts.MaxIdleConnsPerHost <= 0 {
+		opts.MaxIdleConnsPerHost = 100
+	}
+
+	if opts.IdleConnTimeout <= 0 {
+		opts.IdleConnTimeout = 90 * time.Second
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   opts.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	dial := dialer.DialContext
+	if opts.DNSCache != nil {
+		dial = opts.DNSCache.DialContext(dialer)
+	}
+
+	return &http.Transport{
+		Proxy:       http.ProxyFromEnvironment,
+		DialContext: dial,
+		TLSClientConfig: &tls.Config{
+			MinVersion:   opts.MinVersion,
+			CipherSuites: opts.CipherSuites,
+			Certificates: certs,
+		},
+		ForceAttemptHTTP2:     opts.EnableHTTP2,
+		MaxIdleConns:          opts.MaxIdleConns,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       opts.MaxConnsPerHost,
+		IdleConnTimeout:       opts.IdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// TruncateBytes 按字节数截断字符串, 不会截断多字节字符
+func TruncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}